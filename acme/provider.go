@@ -0,0 +1,126 @@
+// Package acme solves ACME DNS-01 challenges using Cloud DNS. Provider
+// satisfies lego's challenge.Provider and challenge.ProviderTimeout
+// interfaces, so it can be handed straight to a lego client.
+package acme
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gophercloud/gophercloud/v2"
+
+	"github.com/rackerlabs/goclouddns/domains"
+	"github.com/rackerlabs/goclouddns/records"
+)
+
+const (
+	// DefaultTTL is the lowest TTL Cloud DNS accepts.
+	DefaultTTL = 300
+
+	// DefaultAPITimeout bounds each Present or CleanUp call, including the
+	// wait for its async job.
+	DefaultAPITimeout = 2 * time.Minute
+
+	// DefaultPropagationTimeout is how long lego should wait for the TXT
+	// record to be visible.
+	DefaultPropagationTimeout = 10 * time.Minute
+
+	// DefaultPollingInterval is how often lego should check propagation.
+	DefaultPollingInterval = 10 * time.Second
+)
+
+// Provider creates and removes _acme-challenge TXT records.
+type Provider struct {
+	client *gophercloud.ServiceClient
+
+	// TTL is used for the challenge records.
+	TTL uint
+
+	// APITimeout bounds each Present or CleanUp call.
+	APITimeout time.Duration
+
+	// PropagationTimeout and PollingInterval are reported to lego through
+	// Timeout.
+	PropagationTimeout time.Duration
+	PollingInterval    time.Duration
+}
+
+// NewProvider returns a Provider using client with the default timings.
+func NewProvider(client *gophercloud.ServiceClient) *Provider {
+	return &Provider{
+		client:             client,
+		TTL:                DefaultTTL,
+		APITimeout:         DefaultAPITimeout,
+		PropagationTimeout: DefaultPropagationTimeout,
+		PollingInterval:    DefaultPollingInterval,
+	}
+}
+
+// Present creates the TXT record proving control of domain and returns once
+// Cloud DNS has finished the job.
+func (p *Provider) Present(domain, token, keyAuth string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), p.APITimeout)
+	defer cancel()
+
+	fqdn, value := ChallengeRecord(domain, keyAuth)
+	zone, err := domains.FindZone(ctx, p.client, fqdn)
+	if err != nil {
+		return err
+	}
+
+	opts := records.CreateOpts{
+		Name:    fqdn,
+		Type:    "TXT",
+		Data:    value,
+		TTL:     p.TTL,
+		Comment: "ACME challenge",
+	}
+	if err := records.Create(ctx, p.client, zone.ID, opts).Err; err != nil {
+		return fmt.Errorf("creating TXT record %s: %w", fqdn, err)
+	}
+	return nil
+}
+
+// CleanUp removes the TXT record created by Present. Other challenge values
+// for the same name, such as those for a wildcard and its base domain, are
+// left in place.
+func (p *Provider) CleanUp(domain, token, keyAuth string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), p.APITimeout)
+	defer cancel()
+
+	fqdn, value := ChallengeRecord(domain, keyAuth)
+	zone, err := domains.FindZone(ctx, p.client, fqdn)
+	if err != nil {
+		return err
+	}
+
+	recordList, err := records.FindByOpts(ctx, p.client, zone.ID, records.ListOpts{Name: fqdn, Type: "TXT", Data: value})
+	if err != nil {
+		return err
+	}
+
+	for _, record := range recordList {
+		if err := records.Delete(ctx, p.client, zone.ID, record.ID).ExtractErr(); err != nil {
+			return fmt.Errorf("deleting TXT record %s: %w", fqdn, err)
+		}
+	}
+	return nil
+}
+
+// Timeout reports how long lego should wait for the challenge record to
+// propagate and how often to check.
+func (p *Provider) Timeout() (timeout, interval time.Duration) {
+	return p.PropagationTimeout, p.PollingInterval
+}
+
+// ChallengeRecord returns the record name and TXT value for a DNS-01
+// challenge on domain.
+func ChallengeRecord(domain string, keyAuth string) (fqdn string, value string) {
+	domain = strings.TrimSuffix(strings.TrimPrefix(domain, "*."), ".")
+	sum := sha256.Sum256([]byte(keyAuth))
+	return "_acme-challenge." + domain, base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,78 @@
+package acme
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rackerlabs/goclouddns/domains"
+	"github.com/rackerlabs/goclouddns/fakedns"
+	"github.com/rackerlabs/goclouddns/records"
+)
+
+func TestChallengeRecord(t *testing.T) {
+	fqdn, value := ChallengeRecord("*.example.com", "token.thumbprint")
+
+	if fqdn != "_acme-challenge.example.com" {
+		t.Errorf("unexpected fqdn %q", fqdn)
+	}
+	if value != "61rBZ_4knHblO0MNoxFsXZ_eTFUHum0B6IVRbhvUn5I" {
+		t.Errorf("unexpected value %q", value)
+	}
+}
+
+func TestPresentAndCleanUp(t *testing.T) {
+	server := fakedns.NewServer()
+	defer server.Close()
+
+	server.AddDomain(domains.CreateOpts{Name: "example.com", Email: "admin@example.com"})
+	prodID := server.AddDomain(domains.CreateOpts{Name: "prod.example.com", Email: "admin@example.com"})
+
+	provider := NewProvider(server.ServiceClient())
+	if err := provider.Present("www.prod.example.com", "token", "token.thumbprint"); err != nil {
+		t.Fatalf("Present() returned error: %v", err)
+	}
+	if err := provider.Present("www.prod.example.com", "token", "other.thumbprint"); err != nil {
+		t.Fatalf("Present() returned error: %v", err)
+	}
+
+	if got := txtRecords(t, server, prodID); len(got) != 2 {
+		t.Fatalf("expected 2 TXT records in prod.example.com, got %+v", got)
+	}
+
+	if err := provider.CleanUp("www.prod.example.com", "token", "token.thumbprint"); err != nil {
+		t.Fatalf("CleanUp() returned error: %v", err)
+	}
+
+	got := txtRecords(t, server, prodID)
+	if len(got) != 1 {
+		t.Fatalf("expected 1 TXT record left, got %+v", got)
+	}
+	if _, value := ChallengeRecord("www.prod.example.com", "other.thumbprint"); got[0].Data != value {
+		t.Errorf("expected the other challenge to remain, got %q", got[0].Data)
+	}
+}
+
+func TestPresentWithoutZoneFails(t *testing.T) {
+	server := fakedns.NewServer()
+	defer server.Close()
+
+	provider := NewProvider(server.ServiceClient())
+	if err := provider.Present("example.net", "token", "token.thumbprint"); err == nil {
+		t.Fatal("expected missing zone error")
+	}
+}
+
+func txtRecords(t *testing.T, server *fakedns.Server, domID string) []records.RecordList {
+	t.Helper()
+
+	ctx := context.Background()
+	page, err := records.List(ctx, server.ServiceClient(), domID, records.ListOpts{Type: "TXT"}).AllPages(ctx)
+	if err != nil {
+		t.Fatalf("List() returned error: %v", err)
+	}
+	recordList, err := records.ExtractRecords(page)
+	if err != nil {
+		t.Fatalf("ExtractRecords() returned error: %v", err)
+	}
+	return recordList
+}
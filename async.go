@@ -3,8 +3,12 @@ package goclouddns
 import (
 	"context"
 	"fmt"
+	"net/url"
+	"strings"
+	"time"
 
 	"github.com/gophercloud/gophercloud/v2"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // AsyncResult is the result of any async operation
@@ -30,6 +34,32 @@ func (r AsyncResult) Extract() (*AsyncMessage, error) {
 	return &s, err
 }
 
+// JobObserver is told about each job WaitForStatus waits on. See
+// SetJobObserver.
+type JobObserver interface {
+	ObserveJob(job JobResult)
+}
+
+// JobResult describes one async job once WaitForStatus is done with it.
+type JobResult struct {
+	// Operation names the call that started the job, such as
+	// "records.Create".
+	Operation string
+
+	// JobID is the Cloud DNS job ID.
+	JobID string
+
+	// Status is the last status seen: COMPLETED, ERROR, TIMEOUT if the
+	// context ended first, or UNKNOWN if the job could not be polled.
+	Status string
+
+	// Polls is how many times the callback URL was fetched.
+	Polls int
+
+	// Duration is how long WaitForStatus waited.
+	Duration time.Duration
+}
+
 func WaitForStatus(ctx context.Context, client *gophercloud.ServiceClient, ret *AsyncResult, status string) error {
 	req, err := ret.Extract()
 	if err != nil {
@@ -38,7 +68,34 @@ func WaitForStatus(ctx context.Context, client *gophercloud.ServiceClient, ret *
 
 	url := req.CallbackURL + "?showDetails=true"
 
-	return gophercloud.WaitFor(ctx, func(ctx context.Context) (bool, error) {
+	job := JobResult{Operation: jobOperation(req.Verb, req.RequestURL), JobID: req.JobID, Status: "UNKNOWN"}
+	start := time.Now()
+	logger := Logger(client)
+	observer := optionsFor(client).jobObserver
+	defer func() {
+		job.Duration = time.Since(start)
+		logger.DebugContext(ctx, "job done",
+			"operation", job.Operation, "job_id", job.JobID, "status", job.Status,
+			"polls", job.Polls, "duration", job.Duration)
+		if observer != nil {
+			observer.ObserveJob(job)
+		}
+	}()
+
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(AttrJobID.String(req.JobID))
+	defer func() { span.SetAttributes(AttrJobStatus.String(job.Status)) }()
+
+	err = gophercloud.WaitFor(ctx, func(ctx context.Context) (done bool, err error) {
+		job.Polls++
+
+		ctx, pollSpan := tracer(ctx, client).Start(ctx, "WaitForStatus poll",
+			trace.WithAttributes(AttrJobID.String(req.JobID), AttrJobPoll.Int(job.Polls)))
+		defer func() {
+			pollSpan.SetAttributes(AttrJobStatus.String(job.Status))
+			EndSpan(pollSpan, err)
+		}()
+
 		var resp gophercloud.Result
 		if _, err := client.Get(ctx, url, &resp.Body, nil); err != nil {
 			return false, err
@@ -48,6 +105,8 @@ func WaitForStatus(ctx context.Context, client *gophercloud.ServiceClient, ret *
 		if err := resp.ExtractInto(&latest); err != nil {
 			return false, err
 		}
+		job.Status = latest.Status
+		logger.DebugContext(ctx, "job poll", "job_id", job.JobID, "poll", job.Polls, "status", job.Status)
 
 		if latest.Status == status {
 			// success case
@@ -70,4 +129,28 @@ func WaitForStatus(ctx context.Context, client *gophercloud.ServiceClient, ret *
 
 		return false, nil
 	})
+	if err != nil && ctx.Err() != nil {
+		job.Status = "TIMEOUT"
+	}
+	return err
+}
+
+// jobOperation names the call that started a job from the verb and URL of
+// its request, such as "domains.Update" for a PUT to a domain.
+func jobOperation(verb string, requestURL string) string {
+	resource := "domains"
+	if u, err := url.Parse(requestURL); err == nil && strings.Contains(u.Path+"/", "/records/") {
+		resource = "records"
+	}
+
+	switch strings.ToUpper(verb) {
+	case "POST":
+		return resource + ".Create"
+	case "PUT":
+		return resource + ".Update"
+	case "DELETE":
+		return resource + ".Delete"
+	default:
+		return resource + "." + strings.ToUpper(verb)
+	}
 }
@@ -102,3 +102,24 @@ func TestNewCloudDNS_EndpointError(t *testing.T) {
 		t.Errorf("expected endpoint error but got none")
 	}
 }
+
+func TestJobOperation(t *testing.T) {
+	tests := []struct {
+		verb string
+		url  string
+		want string
+	}{
+		{"POST", "https://dns.api.rackspacecloud.com/v1.0/123456/domains", "domains.Create"},
+		{"PUT", "https://dns.api.rackspacecloud.com/v1.0/123456/domains/1000001", "domains.Update"},
+		{"DELETE", "https://dns.api.rackspacecloud.com/v1.0/123456/domains/1000001", "domains.Delete"},
+		{"POST", "https://dns.api.rackspacecloud.com/v1.0/123456/domains/1000001/records", "records.Create"},
+		{"PUT", "https://dns.api.rackspacecloud.com/v1.0/123456/domains/1000001/records/A-2000001", "records.Update"},
+		{"DELETE", "https://dns.api.rackspacecloud.com/v1.0/123456/domains/1000001/records/A-2000001", "records.Delete"},
+	}
+
+	for _, tt := range tests {
+		if got := jobOperation(tt.verb, tt.url); got != tt.want {
+			t.Errorf("jobOperation(%s, %s) = %q, want %q", tt.verb, tt.url, got, tt.want)
+		}
+	}
+}
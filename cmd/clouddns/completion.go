@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gophercloud/gophercloud/v2"
+	"github.com/spf13/cobra"
+)
+
+// completionCacheTTL is how long completed IDs are reused before the API
+// is asked again. It only needs to cover the few seconds of a user
+// pressing tab repeatedly.
+const completionCacheTTL = time.Minute
+
+// completer lists the candidates for one positional argument, given the
+// arguments before it. Candidates are "ID\tdescription".
+type completer func(ctx context.Context, service *gophercloud.ServiceClient, args []string) ([]string, error)
+
+// completeArgs completes each positional argument with the completer at
+// its position, caching the candidates under the cache key of each
+// completer. A nil completer leaves the argument alone.
+func (app *cliApp) completeArgs(completers ...cachedCompleter) cobra.CompletionFunc {
+	return func(_ *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) >= len(completers) || completers[len(args)].complete == nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		c := completers[len(args)]
+
+		candidates, err := app.cachedCandidates(c, args)
+		if err != nil {
+			cobra.CompErrorln(err.Error())
+			return nil, cobra.ShellCompDirectiveError
+		}
+
+		var matches []string
+		for _, candidate := range candidates {
+			if strings.HasPrefix(candidate, toComplete) {
+				matches = append(matches, candidate)
+			}
+		}
+		return matches, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// cachedCompleter is a completer with the cache key for its candidates.
+type cachedCompleter struct {
+	key      func(args []string) string
+	complete completer
+}
+
+var (
+	domainIDCompleter = cachedCompleter{
+		key:      func([]string) string { return "domains" },
+		complete: completeDomainIDs,
+	}
+	recordIDCompleter = cachedCompleter{
+		key:      func(args []string) string { return "records/" + args[0] },
+		complete: completeRecordIDs,
+	}
+)
+
+func completeDomainIDs(ctx context.Context, service *gophercloud.ServiceClient, _ []string) ([]string, error) {
+	domainList, err := listAllDomains(ctx, service, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make([]string, 0, len(domainList))
+	for _, domain := range domainList {
+		candidates = append(candidates, domain.ID+"\t"+domain.Name)
+	}
+	return candidates, nil
+}
+
+func completeRecordIDs(ctx context.Context, service *gophercloud.ServiceClient, args []string) ([]string, error) {
+	domID, err := resolveDomainID(ctx, service, args[0])
+	if err != nil {
+		return nil, err
+	}
+	recordList, err := listAllRecords(ctx, service, domID, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make([]string, 0, len(recordList))
+	for _, record := range recordList {
+		candidates = append(candidates, fmt.Sprintf("%s\t%s %s %s", record.ID, record.Name, record.Type, record.Data))
+	}
+	return candidates, nil
+}
+
+// completionCacheEntry is what is stored in a completion cache file.
+type completionCacheEntry struct {
+	Taken      time.Time `json:"taken"`
+	Candidates []string  `json:"candidates"`
+}
+
+// cachedCandidates returns the candidates of c from the cache when they
+// are fresh, and otherwise from the API, refreshing the cache.
+func (app *cliApp) cachedCandidates(c cachedCompleter, args []string) ([]string, error) {
+	path := app.completionCachePath(c.key(args))
+	if path != "" {
+		if candidates, ok := readCompletionCache(path); ok {
+			return candidates, nil
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), app.operationTimeout())
+	defer cancel()
+
+	service, err := app.connectService(ctx)
+	if err != nil {
+		return nil, err
+	}
+	candidates, err := c.complete(ctx, service, args)
+	if err != nil {
+		return nil, err
+	}
+
+	if path != "" {
+		// a cache that cannot be written only makes the next tab slower
+		_ = writeCompletionCache(path, candidates)
+	}
+	return candidates, nil
+}
+
+// completionCachePath names the cache file for key, kept apart per config
+// file, profile and account so switching credentials never completes
+// another account's IDs. It returns "" when there is no user cache
+// directory.
+func (app *cliApp) completionCachePath(key string) string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+
+	// profiles of the same name in two config files are not the same
+	configPath, _ := app.configFile()
+	if abs, err := filepath.Abs(configPath); err == nil {
+		configPath = abs
+	}
+	profileName, _, _ := app.profile()
+	account := strings.Join([]string{
+		configPath,
+		profileName,
+		os.Getenv("OS_AUTH_URL"),
+		os.Getenv("OS_USERNAME"),
+		os.Getenv("OS_PROJECT_ID"),
+		os.Getenv("OS_TENANT_ID"),
+		key,
+	}, "\x00")
+	sum := sha256.Sum256([]byte(account))
+	return filepath.Join(dir, "clouddns", "completion", hex.EncodeToString(sum[:16])+".json")
+}
+
+func readCompletionCache(path string) ([]string, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+
+	var entry completionCacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, false
+	}
+	if time.Since(entry.Taken) > completionCacheTTL {
+		return nil, false
+	}
+	return entry.Candidates, true
+}
+
+func writeCompletionCache(path string, candidates []string) error {
+	data, err := json.Marshal(completionCacheEntry{Taken: time.Now(), Candidates: candidates})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
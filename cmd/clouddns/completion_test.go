@@ -0,0 +1,91 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rackerlabs/goclouddns/domains"
+	"github.com/rackerlabs/goclouddns/fakedns"
+	"github.com/rackerlabs/goclouddns/records"
+)
+
+func TestCompleteDomainAndRecordIDs(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	server := fakedns.NewServer()
+	defer server.Close()
+	domID := server.AddDomain(domains.CreateOpts{Name: "example.com", Email: "admin@example.com"})
+	recID, err := server.AddRecord(domID, records.CreateOpts{Name: "app.example.com", Type: "A", Data: "10.5.19.11"})
+	if err != nil {
+		t.Fatalf("AddRecord() returned error: %v", err)
+	}
+
+	output := captureStdout(t, func() {
+		if err := runAgainst(server, "__complete", "record", "show", ""); err != nil {
+			t.Fatalf("Execute() returned error: %v", err)
+		}
+	})
+	if !strings.Contains(output, domID+"\texample.com\n") {
+		t.Fatalf("expected domain ID with name, got %q", output)
+	}
+
+	output = captureStdout(t, func() {
+		if err := runAgainst(server, "__complete", "record", "delete", domID, ""); err != nil {
+			t.Fatalf("Execute() returned error: %v", err)
+		}
+	})
+	if !strings.Contains(output, recID+"\tapp.example.com A 10.5.19.11\n") {
+		t.Fatalf("expected record ID with its name, type and data, got %q", output)
+	}
+}
+
+func TestCompleteDomainIDsUsesCache(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	server := fakedns.NewServer()
+	defer server.Close()
+	domID := server.AddDomain(domains.CreateOpts{Name: "example.com", Email: "admin@example.com"})
+
+	complete := func() string {
+		return captureStdout(t, func() {
+			if err := runAgainst(server, "__complete", "domain", "show", ""); err != nil {
+				t.Fatalf("Execute() returned error: %v", err)
+			}
+		})
+	}
+
+	if output := complete(); !strings.Contains(output, domID) {
+		t.Fatalf("expected domain ID, got %q", output)
+	}
+
+	// a domain added within the cache lifetime is not seen yet
+	otherID := server.AddDomain(domains.CreateOpts{Name: "example.net", Email: "admin@example.net"})
+	if output := complete(); !strings.Contains(output, domID) || strings.Contains(output, otherID) {
+		t.Fatalf("expected cached candidates, got %q", output)
+	}
+}
+
+func TestCompletionCacheIsKeptPerConfigFile(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	dir := t.TempDir()
+
+	first := &cliApp{configPath: filepath.Join(dir, "first.yaml")}
+	second := &cliApp{configPath: filepath.Join(dir, "second.yaml")}
+	if first.completionCachePath("domains") == second.completionCachePath("domains") {
+		t.Fatal("expected different config files to have different completion caches")
+	}
+}
+
+func TestCompletionCommandIsEnabled(t *testing.T) {
+	output := captureStdout(t, func() {
+		cmd := newRootCmd()
+		cmd.SetArgs([]string{"completion", "bash"})
+		if err := cmd.Execute(); err != nil {
+			t.Fatalf("Execute() returned error: %v", err)
+		}
+	})
+	if !strings.Contains(output, "__start_clouddns") {
+		t.Fatalf("expected a bash completion script, got %.200q", output)
+	}
+}
@@ -0,0 +1,470 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/gophercloud/gophercloud/v2"
+	tokens2 "github.com/gophercloud/gophercloud/v2/openstack/identity/v2/tokens"
+	"github.com/spf13/cobra"
+	"go.yaml.in/yaml/v3"
+
+	"github.com/rackerlabs/goclouddns"
+	"github.com/rackerlabs/goraxauth"
+)
+
+// defaultAuthURL is the Rackspace identity endpoint, as used by
+// goraxauth.AuthOptionsFromEnv.
+const defaultAuthURL = "https://identity.api.rackspacecloud.com/v2.0/"
+
+// config is the clouddns config file, by default
+// ~/.config/clouddns/config.yaml.
+type config struct {
+	// CurrentProfile is used when --profile is not given.
+	CurrentProfile string              `yaml:"current-profile,omitempty"`
+	Profiles       map[string]*profile `yaml:"profiles,omitempty"`
+
+	// ProtectedDomains are never deleted without --override-protection,
+	// whichever profile is in use. Names are lower case without the
+	// trailing dot.
+	ProtectedDomains []string `yaml:"protected-domains,omitempty"`
+}
+
+// profile holds the credentials and defaults for one account.
+type profile struct {
+	Username string `yaml:"username,omitempty"`
+
+	// Only one of these is used, in this order. The commands are run with
+	// sh -c and their trimmed output is the secret, so secrets can stay in
+	// a password manager.
+	APIKey          string `yaml:"api-key,omitempty"`
+	APIKeyCommand   string `yaml:"api-key-command,omitempty"`
+	PasswordCommand string `yaml:"password-command,omitempty"`
+
+	Tenant  string `yaml:"tenant,omitempty"`
+	Region  string `yaml:"region,omitempty"`
+	AuthURL string `yaml:"auth-url,omitempty"`
+
+	// Endpoint replaces the Cloud DNS endpoint from the service catalog.
+	Endpoint string `yaml:"endpoint,omitempty"`
+
+	// Format is the default --format.
+	Format string `yaml:"format,omitempty"`
+}
+
+// defaultConfigPath follows XDG_CONFIG_HOME, falling back to ~/.config on
+// every platform so the path in the docs holds.
+func defaultConfigPath() (string, error) {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "clouddns", "config.yaml"), nil
+}
+
+func (app *cliApp) configFile() (string, error) {
+	if app.configPath != "" {
+		return app.configPath, nil
+	}
+	return defaultConfigPath()
+}
+
+// loadConfig reads the config file. A missing file is an empty config.
+func loadConfig(path string) (*config, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &config{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+// save writes cfg to path, readable only by the user since profiles may
+// hold API keys.
+func (cfg *config) save(path string) error {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(cfg); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o600)
+}
+
+// profile returns the profile chosen by --profile or the config's
+// current-profile, and nil when neither names one, in which case
+// credentials come from the environment as before.
+func (app *cliApp) profile() (string, *profile, error) {
+	if app.profileLoaded {
+		return app.profileName, app.activeProfile, nil
+	}
+
+	path, err := app.configFile()
+	if err != nil {
+		return "", nil, err
+	}
+	cfg, err := loadConfig(path)
+	if err != nil {
+		return "", nil, err
+	}
+
+	name := app.profileName
+	if name == "" {
+		name = cfg.CurrentProfile
+	}
+	if name != "" {
+		p, ok := cfg.Profiles[name]
+		if !ok {
+			return "", nil, fmt.Errorf("profile %q not found in %s", name, path)
+		}
+		app.activeProfile = p
+	}
+
+	app.profileName = name
+	app.profileLoaded = true
+	return app.profileName, app.activeProfile, nil
+}
+
+// applyProfile makes the profile's format the default for --format.
+func (app *cliApp) applyProfile(cmd *cobra.Command) error {
+	_, p, err := app.profile()
+	if err != nil {
+		return err
+	}
+	if p != nil && p.Format != "" && !cmd.Flags().Changed("format") {
+		app.format = p.Format
+	}
+	return nil
+}
+
+// connectFromConfig authenticates with the active profile, or from the
+// environment when there is none.
+func (app *cliApp) connectFromConfig(ctx context.Context) (*gophercloud.ServiceClient, error) {
+	_, p, err := app.profile()
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return connectFromEnv(ctx)
+	}
+
+	opts, err := p.authOptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	provider, err := goraxauth.AuthenticatedClient(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// an endpoint in the profile takes the place of the catalog's, so the
+	// client is still made by NewCloudDNS like any other
+	if p.Endpoint != "" {
+		endpoint := strings.TrimSuffix(p.Endpoint, "/") + "/"
+		provider.EndpointLocator = func(gophercloud.EndpointOpts) (string, error) {
+			return endpoint, nil
+		}
+	}
+	return goclouddns.NewCloudDNS(provider, gophercloud.EndpointOpts{Region: p.Region})
+}
+
+func (p *profile) authOptions(ctx context.Context) (goraxauth.AuthOptions, error) {
+	if p.Username == "" {
+		return goraxauth.AuthOptions{}, errors.New("profile has no username")
+	}
+
+	opts := goraxauth.AuthOptions{
+		AuthOptions: tokens2.AuthOptions{
+			IdentityEndpoint: defaultAuthURL,
+			Username:         p.Username,
+			TenantID:         p.Tenant,
+		},
+	}
+	if p.AuthURL != "" {
+		opts.IdentityEndpoint = p.AuthURL
+	}
+
+	var err error
+	switch {
+	case p.APIKey != "":
+		opts.ApiKey = p.APIKey
+	case p.APIKeyCommand != "":
+		opts.ApiKey, err = secretFromCommand(ctx, p.APIKeyCommand)
+	case p.PasswordCommand != "":
+		opts.Password, err = secretFromCommand(ctx, p.PasswordCommand)
+	default:
+		err = errors.New("profile has no api-key, api-key-command or password-command")
+	}
+	return opts, err
+}
+
+// secretFromCommand runs command with sh -c and returns its output
+// without surrounding whitespace.
+func secretFromCommand(ctx context.Context, command string) (string, error) {
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("running %q: %w", command, err)
+	}
+
+	secret := strings.TrimSpace(string(out))
+	if secret == "" {
+		return "", fmt.Errorf("running %q: no output", command)
+	}
+	return secret, nil
+}
+
+func newConfigCmd(app *cliApp) *cobra.Command {
+	configCmd := &cobra.Command{
+		Use:   "config",
+		Short: "Manage profiles in the config file",
+		// a broken config or unknown --profile must not stop it being fixed
+		PersistentPreRunE: func(*cobra.Command, []string) error { return nil },
+	}
+
+	pathCmd := &cobra.Command{
+		Use:   "path",
+		Short: "Print the config file path",
+		Args:  noArgsValidator("clouddns config path"),
+		RunE: func(*cobra.Command, []string) error {
+			path, err := app.configFile()
+			if err != nil {
+				return err
+			}
+			fmt.Println(path)
+			return nil
+		},
+	}
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List profiles",
+		Args:  noArgsValidator("clouddns config list"),
+		RunE: func(*cobra.Command, []string) error {
+			return app.withConfig(func(cfg *config, _ string) error {
+				names := make([]string, 0, len(cfg.Profiles))
+				for name := range cfg.Profiles {
+					names = append(names, name)
+				}
+				slices.Sort(names)
+
+				w := newTabWriter()
+				fmt.Fprintln(w, "CURRENT\tNAME\tUSERNAME\tTENANT\tREGION")
+				for _, name := range names {
+					p := cfg.Profiles[name]
+					current := ""
+					if name == cfg.CurrentProfile {
+						current = "*"
+					}
+					fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", current, name, p.Username, p.Tenant, p.Region)
+				}
+				return w.Flush()
+			})
+		},
+	}
+
+	showCmd := &cobra.Command{
+		Use:   "show [NAME]",
+		Short: "Show a profile, the current one by default",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			return app.withConfig(func(cfg *config, path string) error {
+				name := cfg.CurrentProfile
+				if len(args) == 1 {
+					name = args[0]
+				}
+				p, ok := cfg.Profiles[name]
+				if !ok {
+					return fmt.Errorf("profile %q not found in %s", name, path)
+				}
+
+				shown := *p
+				if shown.APIKey != "" {
+					shown.APIKey = "(set)"
+				}
+				data, err := yaml.Marshal(map[string]*profile{name: &shown})
+				if err != nil {
+					return err
+				}
+				fmt.Print(string(data))
+				return nil
+			})
+		},
+	}
+
+	var set profile
+	setCmd := &cobra.Command{
+		Use:   "set-profile NAME",
+		Short: "Create a profile or change its settings",
+		Args:  exactArgsValidator(1, "clouddns config set-profile NAME", "NAME"),
+		Example: strings.Join([]string{
+			"  clouddns config set-profile prod --username alice --api-key-command 'pass show rackspace/prod'",
+			"  clouddns config set-profile prod --region DFW --format json",
+		}, "\n"),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if set.Format != "" {
+				if err := (&cliApp{format: set.Format}).validateOutputFormat(); err != nil {
+					return err
+				}
+			}
+			if set.Endpoint != "" {
+				if u, err := url.Parse(set.Endpoint); err != nil || u.Scheme == "" || u.Host == "" {
+					return fmt.Errorf("invalid --endpoint %q: must be an absolute URL", set.Endpoint)
+				}
+			}
+
+			return app.updateConfig(func(cfg *config) error {
+				if cfg.Profiles == nil {
+					cfg.Profiles = map[string]*profile{}
+				}
+				p, ok := cfg.Profiles[args[0]]
+				if !ok {
+					p = &profile{}
+					cfg.Profiles[args[0]] = p
+				}
+				if cfg.CurrentProfile == "" {
+					cfg.CurrentProfile = args[0]
+				}
+
+				for _, field := range []struct {
+					flag  string
+					dst   *string
+					value string
+				}{
+					{"username", &p.Username, set.Username},
+					{"api-key", &p.APIKey, set.APIKey},
+					{"api-key-command", &p.APIKeyCommand, set.APIKeyCommand},
+					{"password-command", &p.PasswordCommand, set.PasswordCommand},
+					{"tenant", &p.Tenant, set.Tenant},
+					{"region", &p.Region, set.Region},
+					{"auth-url", &p.AuthURL, set.AuthURL},
+					{"endpoint", &p.Endpoint, set.Endpoint},
+					{"format", &p.Format, set.Format},
+				} {
+					if cmd.Flags().Changed(field.flag) {
+						*field.dst = field.value
+					}
+				}
+				return nil
+			})
+		},
+	}
+	setCmd.Flags().StringVar(&set.Username, "username", "", "Rackspace username")
+	setCmd.Flags().StringVar(&set.APIKey, "api-key", "", "API key, stored in the config file")
+	setCmd.Flags().StringVar(&set.APIKeyCommand, "api-key-command", "", "command printing the API key")
+	setCmd.Flags().StringVar(&set.PasswordCommand, "password-command", "", "command printing the password")
+	setCmd.Flags().StringVar(&set.Tenant, "tenant", "", "tenant (account) ID")
+	setCmd.Flags().StringVar(&set.Region, "region", "", "region of the Cloud DNS endpoint")
+	setCmd.Flags().StringVar(&set.AuthURL, "auth-url", "", "identity endpoint (default "+defaultAuthURL+")")
+	setCmd.Flags().StringVar(&set.Endpoint, "endpoint", "", "Cloud DNS endpoint, instead of the service catalog's")
+	// the profile's --format is registered on this command, shadowing the
+	// global one, so it does not change how this command prints
+	setCmd.Flags().StringVar(&set.Format, "format", "", "default output format: "+outputFormatHelp)
+
+	useCmd := &cobra.Command{
+		Use:   "use NAME",
+		Short: "Make a profile the current one",
+		Args:  exactArgsValidator(1, "clouddns config use NAME", "NAME"),
+		RunE: func(_ *cobra.Command, args []string) error {
+			return app.updateConfig(func(cfg *config) error {
+				if _, ok := cfg.Profiles[args[0]]; !ok {
+					return fmt.Errorf("profile %q not found", args[0])
+				}
+				cfg.CurrentProfile = args[0]
+				return nil
+			})
+		},
+	}
+
+	deleteCmd := &cobra.Command{
+		Use:   "delete NAME",
+		Short: "Delete a profile",
+		Args:  exactArgsValidator(1, "clouddns config delete NAME", "NAME"),
+		RunE: func(_ *cobra.Command, args []string) error {
+			return app.updateConfig(func(cfg *config) error {
+				if _, ok := cfg.Profiles[args[0]]; !ok {
+					return fmt.Errorf("profile %q not found", args[0])
+				}
+				delete(cfg.Profiles, args[0])
+				if cfg.CurrentProfile == args[0] {
+					cfg.CurrentProfile = ""
+				}
+				return nil
+			})
+		},
+	}
+
+	for _, cmd := range []*cobra.Command{showCmd, setCmd, useCmd, deleteCmd} {
+		cmd.ValidArgsFunction = app.completeProfiles
+	}
+
+	configCmd.AddCommand(pathCmd, listCmd, showCmd, setCmd, useCmd, deleteCmd,
+		newConfigProtectCmd(app), newConfigUnprotectCmd(app))
+	return configCmd
+}
+
+func (app *cliApp) withConfig(run func(cfg *config, path string) error) error {
+	path, err := app.configFile()
+	if err != nil {
+		return err
+	}
+	cfg, err := loadConfig(path)
+	if err != nil {
+		return err
+	}
+	return run(cfg, path)
+}
+
+func (app *cliApp) updateConfig(update func(cfg *config) error) error {
+	return app.withConfig(func(cfg *config, path string) error {
+		if err := update(cfg); err != nil {
+			return err
+		}
+		return cfg.save(path)
+	})
+}
+
+func (app *cliApp) completeProfiles(_ *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var names []string
+	_ = app.withConfig(func(cfg *config, _ string) error {
+		for name := range cfg.Profiles {
+			names = append(names, name)
+		}
+		return nil
+	})
+	slices.Sort(names)
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
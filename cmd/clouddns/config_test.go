@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gophercloud/gophercloud/v2"
+
+	"github.com/rackerlabs/goclouddns/domains"
+	"github.com/rackerlabs/goclouddns/fakedns"
+)
+
+func runConfig(t *testing.T, path string, args ...string) string {
+	t.Helper()
+
+	return captureStdout(t, func() {
+		cmd := newRootCmdWithApp(&cliApp{})
+		cmd.SetArgs(append([]string{"--config", path}, args...))
+		if err := cmd.Execute(); err != nil {
+			t.Fatalf("Execute(%v) returned error: %v", args, err)
+		}
+	})
+}
+
+func TestConfigProfiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "clouddns", "config.yaml")
+
+	runConfig(t, path, "config", "set-profile", "prod", "--username", "alice", "--api-key", "s3cret", "--format", "json")
+	runConfig(t, path, "config", "set-profile", "lab", "--username", "bob", "--password-command", "echo pw", "--region", "ORD")
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("config not written: %v", err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("expected config mode 0600, got %v", info.Mode().Perm())
+	}
+
+	output := runConfig(t, path, "config", "list")
+	if !strings.Contains(output, "*        prod  alice") || !strings.Contains(output, "         lab   bob") {
+		t.Fatalf("expected prod current and lab listed, got %q", output)
+	}
+
+	output = runConfig(t, path, "config", "show", "prod")
+	if strings.Contains(output, "s3cret") || !strings.Contains(output, "format: json") {
+		t.Fatalf("expected the API key hidden and the format shown, got %q", output)
+	}
+
+	runConfig(t, path, "config", "set-profile", "prod", "--tenant", "123456")
+	cfg, err := loadConfig(path)
+	if err != nil {
+		t.Fatalf("loadConfig() returned error: %v", err)
+	}
+	if p := cfg.Profiles["prod"]; p.Username != "alice" || p.Tenant != "123456" || p.APIKey != "s3cret" {
+		t.Fatalf("expected set-profile to keep unchanged fields, got %+v", p)
+	}
+
+	runConfig(t, path, "config", "use", "lab")
+	runConfig(t, path, "config", "delete", "prod")
+	if cfg, _ = loadConfig(path); cfg.CurrentProfile != "lab" || cfg.Profiles["prod"] != nil {
+		t.Fatalf("expected lab current and prod deleted, got %+v", cfg)
+	}
+}
+
+func TestProfileFormatIsDefault(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	cfg := &config{CurrentProfile: "prod", Profiles: map[string]*profile{"prod": {Username: "alice", Format: "jsonpath={[*].Name}"}}}
+	if err := cfg.save(path); err != nil {
+		t.Fatalf("save() returned error: %v", err)
+	}
+
+	server := fakedns.NewServer()
+	defer server.Close()
+	server.AddDomain(domains.CreateOpts{Name: "example.com", Email: "admin@example.com"})
+
+	run := func(args ...string) string {
+		return captureStdout(t, func() {
+			app := &cliApp{
+				configPath: path,
+				connect: func(context.Context) (*gophercloud.ServiceClient, error) {
+					return server.ServiceClient(), nil
+				},
+			}
+			cmd := newRootCmdWithApp(app)
+			cmd.SetArgs(args)
+			if err := cmd.Execute(); err != nil {
+				t.Fatalf("Execute() returned error: %v", err)
+			}
+		})
+	}
+
+	if output := run("domain", "list"); output != "example.com\n" {
+		t.Fatalf("expected the profile's format, got %q", output)
+	}
+	if output := run("domain", "list", "--format", "json"); !strings.Contains(output, `"Name": "example.com"`) {
+		t.Fatalf("expected --format to override the profile, got %q", output)
+	}
+}
+
+func TestUnknownProfileFails(t *testing.T) {
+	cmd := newRootCmdWithApp(&cliApp{configPath: os.DevNull})
+	cmd.SetArgs([]string{"--profile", "nope", "domain", "list"})
+	err := cmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), `profile "nope" not found`) {
+		t.Fatalf("expected unknown profile error, got %v", err)
+	}
+}
+
+func TestProfileAuthOptions(t *testing.T) {
+	p := &profile{Username: "alice", APIKeyCommand: "echo ' key-from-command '", Tenant: "123456"}
+	opts, err := p.authOptions(t.Context())
+	if err != nil {
+		t.Fatalf("authOptions() returned error: %v", err)
+	}
+	if opts.ApiKey != "key-from-command" || opts.Username != "alice" || opts.TenantID != "123456" || opts.IdentityEndpoint != defaultAuthURL {
+		t.Fatalf("unexpected auth options %+v", opts)
+	}
+
+	p = &profile{Username: "alice", PasswordCommand: "exit 1"}
+	if _, err := p.authOptions(t.Context()); err == nil {
+		t.Fatalf("expected a failing password command to be an error")
+	}
+}
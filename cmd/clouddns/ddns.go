@@ -0,0 +1,379 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gophercloud/gophercloud/v2"
+	"github.com/spf13/cobra"
+
+	"github.com/rackerlabs/goclouddns"
+	"github.com/rackerlabs/goclouddns/records"
+)
+
+func newDDNSCmd(app *cliApp) *cobra.Command {
+	ddnsCmd := &cobra.Command{
+		Use:   "ddns",
+		Short: "Keep records pointed at changing addresses",
+	}
+
+	var source string
+	var sourceURL string
+	var sourceInterface string
+	var sourceCommand string
+	var recordType string
+	var ttl uint
+	var interval time.Duration
+	var statePath string
+	var once bool
+	runCmd := &cobra.Command{
+		Use:   "run DOMID NAME",
+		Short: "Update a record whenever this host's address changes",
+		Long: strings.Join([]string{
+			"Check this host's address on an interval and update the record when it",
+			"changes. The last address written is kept in a state file, so the API is",
+			"only called when the address actually changes.",
+		}, "\n"),
+		Args: exactArgsValidator(2, "clouddns ddns run DOMID NAME", "DOMID and NAME"),
+		Example: strings.Join([]string{
+			"  clouddns ddns run <domain-id> office1.example.com",
+			"  clouddns ddns run <domain-id> office1.example.com --source interface --interface eth0",
+			"  clouddns ddns run <domain-id> office1.example.com --type AAAA --source command --command \"curl -6s https://ifconfig.co\"",
+			"  clouddns ddns run <domain-id> office1.example.com --once",
+		}, "\n"),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !once && interval <= 0 {
+				return friendlyUsageError(cmd, fmt.Sprintf("invalid --interval %s: must be positive", interval), "clouddns ddns run DOMID NAME")
+			}
+
+			src, err := newAddressSource(source, sourceURL, sourceInterface, sourceCommand, recordType)
+			if err != nil {
+				return err
+			}
+
+			if statePath == "" {
+				statePath, err = defaultDDNSStatePath(args[1], recordType)
+				if err != nil {
+					return err
+				}
+			}
+
+			return app.withLongRunningService(func(ctx context.Context, service *gophercloud.ServiceClient) error {
+				domID, err := resolveDomainID(ctx, service, args[0])
+				if err != nil {
+					return err
+				}
+
+				updater := &ddnsUpdater{
+					service:    service,
+					domID:      domID,
+					name:       args[1],
+					recordType: strings.ToUpper(recordType),
+					ttl:        ttl,
+					source:     src,
+					statePath:  statePath,
+					timeout:    app.operationTimeout(),
+				}
+
+				if once {
+					return updater.runOnce(ctx)
+				}
+				return updater.run(ctx, interval)
+			})
+		},
+	}
+	runCmd.Flags().StringVar(&source, "source", "http", "where to read the address from: http, interface or command")
+	runCmd.Flags().StringVar(&sourceURL, "url", "https://checkip.amazonaws.com", "HTTP endpoint that echoes the caller's address")
+	runCmd.Flags().StringVar(&sourceInterface, "interface", "", "network interface to read the address from")
+	runCmd.Flags().StringVar(&sourceCommand, "command", "", "shell command that prints the address")
+	runCmd.Flags().StringVar(&recordType, "type", "A", "record type: A or AAAA")
+	runCmd.Flags().UintVar(&ttl, "ttl", 300, "TTL for the record")
+	runCmd.Flags().DurationVar(&interval, "interval", 5*time.Minute, "how often to check the address")
+	runCmd.Flags().StringVar(&statePath, "state-file", "", "file remembering the last address written (default in the user cache directory)")
+	runCmd.Flags().BoolVar(&once, "once", false, "check and update once, then exit")
+
+	ddnsCmd.AddCommand(runCmd, newDDNSServeCmd(app))
+	return ddnsCmd
+}
+
+// addressSource reports this host's current address.
+type addressSource interface {
+	Address(ctx context.Context) (netip.Addr, error)
+}
+
+func newAddressSource(kind string, url string, iface string, command string, recordType string) (addressSource, error) {
+	var ipv6 bool
+	switch strings.ToUpper(recordType) {
+	case "A":
+	case "AAAA":
+		ipv6 = true
+	default:
+		return nil, fmt.Errorf("unsupported --type %q: must be one of A, AAAA", recordType)
+	}
+
+	switch kind {
+	case "http":
+		return httpAddressSource{url: url, ipv6: ipv6}, nil
+	case "interface":
+		if iface == "" {
+			return nil, fmt.Errorf("--source interface requires --interface")
+		}
+		return interfaceAddressSource{name: iface, ipv6: ipv6}, nil
+	case "command":
+		if command == "" {
+			return nil, fmt.Errorf("--source command requires --command")
+		}
+		return commandAddressSource{command: command, ipv6: ipv6}, nil
+	default:
+		return nil, fmt.Errorf("unsupported --source %q: must be one of http, interface, command", kind)
+	}
+}
+
+// httpAddressSource asks an echo endpoint such as checkip.amazonaws.com.
+type httpAddressSource struct {
+	url  string
+	ipv6 bool
+}
+
+func (s httpAddressSource) Address(ctx context.Context) (netip.Addr, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return netip.Addr{}, fmt.Errorf("%s returned %s", s.url, resp.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 256))
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	return parseAddress(string(body), s.ipv6)
+}
+
+// interfaceAddressSource reads the first global address on an interface.
+type interfaceAddressSource struct {
+	name string
+	ipv6 bool
+}
+
+func (s interfaceAddressSource) Address(_ context.Context) (netip.Addr, error) {
+	iface, err := net.InterfaceByName(s.name)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return netip.Addr{}, err
+	}
+
+	for _, addr := range addrs {
+		prefix, err := netip.ParsePrefix(addr.String())
+		if err != nil {
+			continue
+		}
+		ip := prefix.Addr()
+		if ip.Is6() == s.ipv6 && ip.IsGlobalUnicast() {
+			return ip, nil
+		}
+	}
+	return netip.Addr{}, fmt.Errorf("no global %s address on interface %s", addressFamily(s.ipv6), s.name)
+}
+
+// commandAddressSource runs a shell command that prints the address.
+type commandAddressSource struct {
+	command string
+	ipv6    bool
+}
+
+func (s commandAddressSource) Address(ctx context.Context) (netip.Addr, error) {
+	out, err := exec.CommandContext(ctx, "sh", "-c", s.command).Output()
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("running %q: %w", s.command, err)
+	}
+	return parseAddress(string(out), s.ipv6)
+}
+
+func parseAddress(s string, ipv6 bool) (netip.Addr, error) {
+	ip, err := netip.ParseAddr(strings.TrimSpace(s))
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("invalid address %q", strings.TrimSpace(s))
+	}
+	ip = ip.Unmap()
+	if ip.Is6() != ipv6 {
+		return netip.Addr{}, fmt.Errorf("got %s, expected an %s address", ip, addressFamily(ipv6))
+	}
+	return ip, nil
+}
+
+func addressFamily(ipv6 bool) string {
+	if ipv6 {
+		return "IPv6"
+	}
+	return "IPv4"
+}
+
+// ddnsState is what the updater last wrote to Cloud DNS.
+type ddnsState struct {
+	DomainID string    `json:"domainId"`
+	Name     string    `json:"name"`
+	Type     string    `json:"type"`
+	Data     string    `json:"data"`
+	RecordID string    `json:"recordId"`
+	Updated  time.Time `json:"updated"`
+}
+
+type ddnsUpdater struct {
+	service    *gophercloud.ServiceClient
+	domID      string
+	name       string
+	recordType string
+	ttl        uint
+	source     addressSource
+	statePath  string
+	timeout    time.Duration
+}
+
+// run checks the address every interval until ctx is cancelled. Failures
+// are reported and retried on the next tick rather than ending the loop.
+func (u *ddnsUpdater) run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := u.runOnce(ctx); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			fmt.Fprintf(os.Stderr, "%s %s: %v\n", time.Now().Format(time.RFC3339), u.name, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// runOnce reads the current address and updates the record if it differs
+// from what was last written.
+func (u *ddnsUpdater) runOnce(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+
+	addr, err := u.source.Address(ctx)
+	if err != nil {
+		return err
+	}
+	data := addr.String()
+
+	state, err := u.loadState()
+	if err != nil {
+		return err
+	}
+	if state != nil && state.Data == data {
+		fmt.Printf("%s %s %s %s unchanged\n", time.Now().Format(time.RFC3339), u.name, u.recordType, data)
+		return nil
+	}
+
+	opts := records.CreateOpts{
+		Name: u.name,
+		Type: u.recordType,
+		Data: data,
+		TTL:  u.ttl,
+	}
+	record, changed, err := records.Ensure(ctx, u.service, u.domID, opts)
+	if err != nil {
+		return err
+	}
+
+	status := "unchanged"
+	if changed {
+		status = "updated"
+	}
+	dryRun := goclouddns.IsDryRun(u.service)
+	if changed && dryRun {
+		status = "would be updated"
+	}
+	fmt.Printf("%s %s %s %s %s\n", time.Now().Format(time.RFC3339), u.name, u.recordType, data, status)
+
+	// the state file must only hold what was really written, or the next
+	// run without --dry-run would take the address as unchanged
+	if dryRun {
+		return nil
+	}
+	return u.saveState(ddnsState{
+		DomainID: u.domID,
+		Name:     u.name,
+		Type:     u.recordType,
+		Data:     data,
+		RecordID: record.ID,
+		Updated:  time.Now().UTC(),
+	})
+}
+
+// loadState returns nil if there is no state yet or it belongs to a
+// different record.
+func (u *ddnsUpdater) loadState() (*ddnsState, error) {
+	data, err := os.ReadFile(u.statePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var state ddnsState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("reading state file %s: %w", u.statePath, err)
+	}
+	if state.DomainID != u.domID || !strings.EqualFold(state.Name, u.name) || state.Type != u.recordType {
+		return nil, nil
+	}
+	return &state, nil
+}
+
+func (u *ddnsUpdater) saveState(state ddnsState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(u.statePath), 0o755); err != nil {
+		return err
+	}
+	tmp := u.statePath + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, u.statePath)
+}
+
+func defaultDDNSStatePath(name string, recordType string) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("finding a place for the state file, set --state-file: %w", err)
+	}
+	file := fmt.Sprintf("ddns-%s-%s.json", strings.ToLower(name), strings.ToUpper(recordType))
+	return filepath.Join(dir, "clouddns", file), nil
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rackerlabs/goclouddns/domains"
+	"github.com/rackerlabs/goclouddns/fakedns"
+)
+
+type staticAddressSource string
+
+func (s staticAddressSource) Address(context.Context) (netip.Addr, error) {
+	return netip.ParseAddr(string(s))
+}
+
+func TestDDNSUpdaterSkipsAPIWhenStateMatches(t *testing.T) {
+	server := fakedns.NewServer()
+	defer server.Close()
+	domID := server.AddDomain(domains.CreateOpts{Name: "example.com", Email: "admin@example.com"})
+
+	updater := &ddnsUpdater{
+		service:    server.ServiceClient(),
+		domID:      domID,
+		name:       "office1.example.com",
+		recordType: "A",
+		ttl:        300,
+		source:     staticAddressSource("203.0.113.10"),
+		statePath:  filepath.Join(t.TempDir(), "state.json"),
+		timeout:    time.Minute,
+	}
+
+	var recID string
+	captureStdout(t, func() {
+		if err := updater.runOnce(context.Background()); err != nil {
+			t.Fatalf("runOnce() returned error: %v", err)
+		}
+		state, err := updater.loadState()
+		if err != nil || state == nil {
+			t.Fatalf("expected state to be saved, got %v, %v", state, err)
+		}
+		recID = state.RecordID
+
+		// with the state file matching, the next run must not touch the API
+		server.Close()
+		if err := updater.runOnce(context.Background()); err != nil {
+			t.Fatalf("runOnce() with matching state returned error: %v", err)
+		}
+	})
+
+	if recID == "" {
+		t.Errorf("expected the record ID to be saved")
+	}
+}
+
+func TestDDNSUpdaterUpdatesChangedAddress(t *testing.T) {
+	server := fakedns.NewServer()
+	defer server.Close()
+	domID := server.AddDomain(domains.CreateOpts{Name: "example.com", Email: "admin@example.com"})
+
+	updater := &ddnsUpdater{
+		service:    server.ServiceClient(),
+		domID:      domID,
+		name:       "office1.example.com",
+		recordType: "A",
+		source:     staticAddressSource("203.0.113.10"),
+		statePath:  filepath.Join(t.TempDir(), "state.json"),
+		timeout:    time.Minute,
+	}
+
+	output := captureStdout(t, func() {
+		for _, addr := range []string{"203.0.113.10", "203.0.113.11"} {
+			updater.source = staticAddressSource(addr)
+			if err := updater.runOnce(context.Background()); err != nil {
+				t.Fatalf("runOnce() returned error: %v", err)
+			}
+		}
+	})
+
+	state, _ := updater.loadState()
+	if record, _ := server.Record(domID, state.RecordID); record.Data != "203.0.113.11" {
+		t.Errorf("expected record to follow the new address, got %q", record.Data)
+	}
+	if got := strings.Count(output, "updated"); got != 2 {
+		t.Errorf("expected 2 updates, got %d in %q", got, output)
+	}
+}
+
+func TestDDNSDryRunSkipsStateFile(t *testing.T) {
+	server := fakedns.NewServer()
+	defer server.Close()
+	domID := server.AddDomain(domains.CreateOpts{Name: "example.com", Email: "admin@example.com"})
+	statePath := filepath.Join(t.TempDir(), "state.json")
+
+	output := captureStdout(t, func() {
+		if err := runAgainst(server, "--dry-run", "ddns", "run", domID, "office1.example.com", "--once",
+			"--source", "command", "--command", "echo 203.0.113.10", "--state-file", statePath); err != nil {
+			t.Fatalf("ddns run --dry-run returned error: %v", err)
+		}
+	})
+	if !strings.Contains(output, "would be updated") {
+		t.Errorf("expected the dry run to say the record would be updated, got %q", output)
+	}
+	if _, err := os.Stat(statePath); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected no state file after a dry run, got %v", err)
+	}
+
+	output = captureStdout(t, func() {
+		if err := runAgainst(server, "ddns", "run", domID, "office1.example.com", "--once",
+			"--source", "command", "--command", "echo 203.0.113.10", "--state-file", statePath); err != nil {
+			t.Fatalf("ddns run returned error: %v", err)
+		}
+	})
+	if !strings.Contains(output, " updated") {
+		t.Errorf("expected the real run to update the record, got %q", output)
+	}
+}
+
+func TestHTTPAddressSource(t *testing.T) {
+	echo := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprintln(w, "203.0.113.10")
+	}))
+	defer echo.Close()
+
+	addr, err := httpAddressSource{url: echo.URL}.Address(context.Background())
+	if err != nil {
+		t.Fatalf("Address() returned error: %v", err)
+	}
+	if addr.String() != "203.0.113.10" {
+		t.Errorf("unexpected address %s", addr)
+	}
+
+	if _, err := (httpAddressSource{url: echo.URL, ipv6: true}).Address(context.Background()); err == nil {
+		t.Errorf("expected an IPv4 answer to be rejected for AAAA")
+	}
+}
+
+func TestCommandAddressSource(t *testing.T) {
+	addr, err := commandAddressSource{command: "echo 2001:db8::1", ipv6: true}.Address(context.Background())
+	if err != nil {
+		t.Fatalf("Address() returned error: %v", err)
+	}
+	if addr.String() != "2001:db8::1" {
+		t.Errorf("unexpected address %s", addr)
+	}
+}
+
+func TestDDNSRunRejectsNonPositiveInterval(t *testing.T) {
+	cmd := newRootCmd()
+	cmd.SetArgs([]string{"ddns", "run", "domid", "office1.example.com", "--interval", "0"})
+
+	err := cmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "invalid --interval 0s: must be positive") {
+		t.Fatalf("expected invalid interval error, got %v", err)
+	}
+}
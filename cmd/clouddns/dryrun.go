@@ -0,0 +1,29 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/rackerlabs/goclouddns"
+)
+
+// dryRunPrinter prints each request --dry-run skips as it is planned, so
+// that long-running commands show them as they go.
+type dryRunPrinter struct {
+	w io.Writer
+}
+
+func (p dryRunPrinter) RecordRequest(req goclouddns.PlannedRequest) {
+	fmt.Fprintf(p.w, "dry run: %s %s\n", req.Method, req.URL)
+	if req.Body == nil {
+		return
+	}
+
+	body, err := json.Marshal(req.Body)
+	if err != nil {
+		fmt.Fprintf(p.w, "  (body: %v)\n", err)
+		return
+	}
+	fmt.Fprintf(p.w, "  %s\n", body)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/rackerlabs/goclouddns"
+	"github.com/rackerlabs/goclouddns/domains"
+	"github.com/rackerlabs/goclouddns/fakedns"
+	"github.com/rackerlabs/goclouddns/records"
+)
+
+func TestRecordDeleteDryRun(t *testing.T) {
+	server := fakedns.NewServer()
+	defer server.Close()
+	domID := server.AddDomain(domains.CreateOpts{Name: "example.com", Email: "admin@example.com"})
+	recID, err := server.AddRecord(domID, records.CreateOpts{Name: "www.example.com", Type: "A", Data: "10.5.19.11"})
+	if err != nil {
+		t.Fatalf("AddRecord() returned error: %v", err)
+	}
+
+	captureStdout(t, func() {
+		if err := runAgainst(server, "--dry-run", "record", "delete", domID, recID); err != nil {
+			t.Fatalf("Execute() returned error: %v", err)
+		}
+	})
+
+	if _, ok := server.Record(domID, recID); !ok {
+		t.Fatal("expected --dry-run to leave the record in place")
+	}
+}
+
+func TestDryRunPrinter(t *testing.T) {
+	var out bytes.Buffer
+	p := dryRunPrinter{w: &out}
+	p.RecordRequest(goclouddns.PlannedRequest{Method: "DELETE", URL: "https://dns.example/v1.0/123/domains/1"})
+	p.RecordRequest(goclouddns.PlannedRequest{
+		Method: "PUT",
+		URL:    "https://dns.example/v1.0/123/domains/1/records/A-1",
+		Body:   records.UpdateOpts{Name: "www.example.com", Data: "10.5.19.12"},
+	})
+
+	want := "dry run: DELETE https://dns.example/v1.0/123/domains/1\n" +
+		"dry run: PUT https://dns.example/v1.0/123/domains/1/records/A-1\n" +
+		`  {"name":"www.example.com","data":"10.5.19.12"}` + "\n"
+	if out.String() != want {
+		t.Fatalf("unexpected output:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
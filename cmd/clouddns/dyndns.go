@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gophercloud/gophercloud/v2"
+	"github.com/spf13/cobra"
+
+	"github.com/rackerlabs/goclouddns"
+	"github.com/rackerlabs/goclouddns/domains"
+	"github.com/rackerlabs/goclouddns/records"
+)
+
+// maxDynDNSHosts is the most hostnames a single dyndns2 request may update.
+const maxDynDNSHosts = 20
+
+func newDDNSServeCmd(app *cliApp) *cobra.Command {
+	var listen string
+	var usersPath string
+	var ttl uint
+	var trustProxy bool
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Accept DynDNS2 /nic/update requests from routers and appliances",
+		Long: strings.Join([]string{
+			"Serve the DynDNS2 update protocol. Each request is authenticated with",
+			"HTTP basic auth against the users file, which lists the hostnames each",
+			"user may update:",
+			"",
+			`  {"users": [{"username": "office1", "password": "...", "hostnames": ["office1.example.com"]}]}`,
+		}, "\n"),
+		Args: noArgsValidator("clouddns ddns serve"),
+		Example: strings.Join([]string{
+			"  clouddns ddns serve --users users.json",
+			"  clouddns ddns serve --users users.json --listen :8245 --trust-proxy",
+		}, "\n"),
+		RunE: func(_ *cobra.Command, _ []string) error {
+			users, err := loadDynDNSUsers(usersPath)
+			if err != nil {
+				return err
+			}
+
+			return app.withLongRunningService(func(ctx context.Context, service *gophercloud.ServiceClient) error {
+				handler := &dynDNSHandler{
+					service:    service,
+					users:      users,
+					ttl:        ttl,
+					timeout:    app.operationTimeout(),
+					trustProxy: trustProxy,
+					zones:      newZoneFinder(service),
+				}
+				server := &http.Server{
+					Addr:              listen,
+					Handler:           handler,
+					ReadHeaderTimeout: 10 * time.Second,
+				}
+				return serveUntilDone(ctx, server)
+			})
+		},
+	}
+	cmd.Flags().StringVar(&listen, "listen", ":8245", "address to serve updates on")
+	cmd.Flags().StringVar(&usersPath, "users", "", "JSON file of users and the hostnames they may update")
+	cmd.Flags().UintVar(&ttl, "ttl", 300, "TTL for updated records")
+	cmd.Flags().BoolVar(&trustProxy, "trust-proxy", false, "take the client address from X-Forwarded-For when myip is not given")
+	_ = cmd.MarkFlagRequired("users")
+
+	return cmd
+}
+
+type dynDNSUser struct {
+	Username  string   `json:"username"`
+	Password  string   `json:"password"`
+	Hostnames []string `json:"hostnames"`
+}
+
+func loadDynDNSUsers(path string) ([]dynDNSUser, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file struct {
+		Users []dynDNSUser `json:"users"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("reading users file %s: %w", path, err)
+	}
+	if len(file.Users) == 0 {
+		return nil, fmt.Errorf("users file %s has no users", path)
+	}
+	return file.Users, nil
+}
+
+// dynDNSHandler answers /nic/update with the standard DynDNS2 return codes,
+// one line per hostname.
+type dynDNSHandler struct {
+	service    *gophercloud.ServiceClient
+	users      []dynDNSUser
+	ttl        uint
+	timeout    time.Duration
+	trustProxy bool
+	zones      *zoneFinder
+}
+
+func (h *dynDNSHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/nic/update" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain")
+
+	user := h.authenticate(r)
+	if user == nil {
+		w.Header().Set("WWW-Authenticate", `Basic realm="clouddns"`)
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprintln(w, "badauth")
+		return
+	}
+
+	query := r.URL.Query()
+	hostnames := strings.Split(query.Get("hostname"), ",")
+	if len(hostnames) > maxDynDNSHosts {
+		fmt.Fprintln(w, "numhost")
+		return
+	}
+
+	addrs, err := h.addresses(r, query.Get("myip"))
+	if err != nil {
+		goclouddns.Logger(h.service).Warn("dyndns: bad request", "error", err)
+		fmt.Fprintln(w, "dnserr")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.timeout)
+	defer cancel()
+
+	for _, hostname := range hostnames {
+		fmt.Fprintln(w, h.update(ctx, user, strings.ToLower(strings.TrimSpace(hostname)), addrs))
+	}
+}
+
+func (h *dynDNSHandler) authenticate(r *http.Request) *dynDNSUser {
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		return nil
+	}
+
+	for i, user := range h.users {
+		if subtle.ConstantTimeCompare([]byte(user.Username), []byte(username)) == 1 &&
+			subtle.ConstantTimeCompare([]byte(user.Password), []byte(password)) == 1 {
+			return &h.users[i]
+		}
+	}
+	return nil
+}
+
+// addresses parses myip, which some clients send as an IPv4 and IPv6 pair,
+// falling back to the address the request came from.
+func (h *dynDNSHandler) addresses(r *http.Request, myip string) ([]netip.Addr, error) {
+	if myip == "" {
+		myip = r.RemoteAddr
+		if host, _, err := net.SplitHostPort(myip); err == nil {
+			myip = host
+		}
+		if forwarded := r.Header.Get("X-Forwarded-For"); h.trustProxy && forwarded != "" {
+			myip, _, _ = strings.Cut(forwarded, ",")
+		}
+	}
+
+	var addrs []netip.Addr
+	for _, s := range strings.Split(myip, ",") {
+		addr, err := netip.ParseAddr(strings.TrimSpace(s))
+		if err != nil {
+			return nil, fmt.Errorf("invalid myip %q", s)
+		}
+		addrs = append(addrs, addr.Unmap())
+	}
+	return addrs, nil
+}
+
+func (h *dynDNSHandler) update(ctx context.Context, user *dynDNSUser, hostname string, addrs []netip.Addr) string {
+	if !strings.Contains(hostname, ".") {
+		return "notfqdn"
+	}
+	if !slices.ContainsFunc(user.Hostnames, func(allowed string) bool {
+		return strings.EqualFold(allowed, hostname)
+	}) {
+		return "nohost"
+	}
+
+	zone, err := h.zones.find(ctx, hostname)
+	if err != nil {
+		goclouddns.Logger(h.service).Error("dyndns: update failed", "hostname", hostname, "error", err)
+		if errors.As(err, &gophercloud.ErrResourceNotFound{}) {
+			return "nohost"
+		}
+		return "dnserr"
+	}
+
+	changed := false
+	for _, addr := range addrs {
+		recordType := "A"
+		if addr.Is6() {
+			recordType = "AAAA"
+		}
+
+		opts := records.CreateOpts{Name: hostname, Type: recordType, Data: addr.String(), TTL: h.ttl}
+		_, c, err := records.Ensure(ctx, h.service, zone.ID, opts)
+		if err != nil {
+			goclouddns.Logger(h.service).Error("dyndns: update failed", "hostname", hostname, "error", err)
+			return "dnserr"
+		}
+		changed = changed || c
+	}
+
+	ips := make([]string, 0, len(addrs))
+	for _, addr := range addrs {
+		ips = append(ips, addr.String())
+	}
+	if changed {
+		return "good " + strings.Join(ips, ",")
+	}
+	return "nochg " + strings.Join(ips, ",")
+}
+
+// zoneFinder resolves host names to the most specific Cloud DNS domain
+// containing them, caching what it finds.
+type zoneFinder struct {
+	service *gophercloud.ServiceClient
+
+	mu    sync.Mutex
+	cache map[string]domains.DomainList
+}
+
+func newZoneFinder(service *gophercloud.ServiceClient) *zoneFinder {
+	return &zoneFinder{service: service, cache: map[string]domains.DomainList{}}
+}
+
+func (f *zoneFinder) find(ctx context.Context, fqdn string) (*domains.DomainList, error) {
+	name := strings.TrimSuffix(strings.ToLower(fqdn), ".")
+
+	f.mu.Lock()
+	zone, ok := f.cache[name]
+	f.mu.Unlock()
+	if ok {
+		return &zone, nil
+	}
+
+	found, err := domains.FindZone(ctx, f.service, name)
+	if err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	f.cache[name] = *found
+	f.mu.Unlock()
+	return found, nil
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gophercloud/gophercloud/v2"
+
+	"github.com/rackerlabs/goclouddns/domains"
+	"github.com/rackerlabs/goclouddns/fakedns"
+	"github.com/rackerlabs/goclouddns/records"
+)
+
+func newTestDynDNSHandler(t *testing.T) (*dynDNSHandler, *fakedns.Server, string) {
+	t.Helper()
+
+	server := fakedns.NewServer()
+	t.Cleanup(server.Close)
+	domID := server.AddDomain(domains.CreateOpts{Name: "example.com", Email: "admin@example.com"})
+
+	service := server.ServiceClient()
+	handler := &dynDNSHandler{
+		service: service,
+		users: []dynDNSUser{
+			{Username: "office1", Password: "secret", Hostnames: []string{"office1.example.com"}},
+		},
+		ttl:     300,
+		timeout: time.Minute,
+		zones:   newZoneFinder(service),
+	}
+	return handler, server, domID
+}
+
+func dynDNSUpdate(handler *dynDNSHandler, query string, username string, password string) (int, string) {
+	req := httptest.NewRequest("GET", "/nic/update?"+query, nil)
+	req.RemoteAddr = "198.51.100.7:51234"
+	if username != "" {
+		req.SetBasicAuth(username, password)
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec.Code, strings.TrimSpace(rec.Body.String())
+}
+
+func TestDynDNSGoodThenNochg(t *testing.T) {
+	handler, server, domID := newTestDynDNSHandler(t)
+
+	if _, body := dynDNSUpdate(handler, "hostname=office1.example.com&myip=203.0.113.10", "office1", "secret"); body != "good 203.0.113.10" {
+		t.Fatalf("expected good, got %q", body)
+	}
+	if _, body := dynDNSUpdate(handler, "hostname=office1.example.com&myip=203.0.113.10", "office1", "secret"); body != "nochg 203.0.113.10" {
+		t.Fatalf("expected nochg, got %q", body)
+	}
+
+	if _, body := dynDNSUpdate(handler, "hostname=office1.example.com&myip=203.0.113.10,2001:db8::10", "office1", "secret"); body != "good 203.0.113.10,2001:db8::10" {
+		t.Fatalf("expected good for the new AAAA record, got %q", body)
+	}
+
+	ctx := context.Background()
+	page, err := records.List(ctx, server.ServiceClient(), domID, records.ListOpts{Name: "office1.example.com"}).AllPages(ctx)
+	if err != nil {
+		t.Fatalf("List() returned error: %v", err)
+	}
+	recordList, _ := records.ExtractRecords(page)
+	if len(recordList) != 2 {
+		t.Errorf("expected A and AAAA records, got %+v", recordList)
+	}
+}
+
+func TestDynDNSUsesRemoteAddressWithoutMyIP(t *testing.T) {
+	handler, _, _ := newTestDynDNSHandler(t)
+
+	if _, body := dynDNSUpdate(handler, "hostname=office1.example.com", "office1", "secret"); body != "good 198.51.100.7" {
+		t.Fatalf("expected update to the client address, got %q", body)
+	}
+}
+
+func TestDynDNSRejectsBadAuthAndOtherHosts(t *testing.T) {
+	handler, _, _ := newTestDynDNSHandler(t)
+
+	if code, body := dynDNSUpdate(handler, "hostname=office1.example.com&myip=203.0.113.10", "office1", "wrong"); code != 401 || body != "badauth" {
+		t.Errorf("expected 401 badauth, got %d %q", code, body)
+	}
+	if _, body := dynDNSUpdate(handler, "hostname=office2.example.com&myip=203.0.113.10", "office1", "secret"); body != "nohost" {
+		t.Errorf("expected nohost, got %q", body)
+	}
+	if _, body := dynDNSUpdate(handler, "hostname=office1&myip=203.0.113.10", "office1", "secret"); body != "notfqdn" {
+		t.Errorf("expected notfqdn, got %q", body)
+	}
+}
+
+func TestDynDNSReportsAPIErrors(t *testing.T) {
+	handler, _, _ := newTestDynDNSHandler(t)
+
+	if _, body := dynDNSUpdate(handler, "hostname=office1.example.com&myip=203.0.113.10", "office1", "secret"); body != "good 203.0.113.10" {
+		t.Fatalf("expected good, got %q", body)
+	}
+
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+	}))
+	defer api.Close()
+
+	service := &gophercloud.ServiceClient{ProviderClient: &gophercloud.ProviderClient{}, Endpoint: api.URL + "/"}
+	handler.service = service
+	handler.zones = newZoneFinder(service)
+
+	if _, body := dynDNSUpdate(handler, "hostname=office1.example.com&myip=203.0.113.11", "office1", "secret"); body != "dnserr" {
+		t.Fatalf("expected dnserr while the API fails, got %q", body)
+	}
+}
+
+func TestDynDNSNoHostWithoutZone(t *testing.T) {
+	handler, _, _ := newTestDynDNSHandler(t)
+	handler.users[0].Hostnames = append(handler.users[0].Hostnames, "office1.example.net")
+
+	if _, body := dynDNSUpdate(handler, "hostname=office1.example.net&myip=203.0.113.10", "office1", "secret"); body != "nohost" {
+		t.Fatalf("expected nohost for a host outside every domain, got %q", body)
+	}
+}
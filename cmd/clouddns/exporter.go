@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gophercloud/gophercloud/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/spf13/cobra"
+
+	"github.com/rackerlabs/goclouddns"
+	"github.com/rackerlabs/goclouddns/metrics"
+)
+
+func newExporterCmd(app *cliApp) *cobra.Command {
+	var listen string
+	var interval time.Duration
+	cmd := &cobra.Command{
+		Use:   "exporter",
+		Short: "Expose Cloud DNS inventory as Prometheus metrics",
+		Long: strings.Join([]string{
+			"Walk every domain and record on the account each interval and serve",
+			"domain counts, record counts by type and the TTL distribution on",
+			"/metrics. Scrapes are answered from the last walk, not the API.",
+		}, "\n"),
+		Args: noArgsValidator("clouddns exporter"),
+		Example: strings.Join([]string{
+			"  clouddns exporter",
+			"  clouddns exporter --listen :9153 --interval 15m",
+		}, "\n"),
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if interval <= 0 {
+				return friendlyUsageError(cmd, fmt.Sprintf("invalid --interval %s: must be positive", interval), "clouddns exporter")
+			}
+
+			return app.withLongRunningService(func(ctx context.Context, service *gophercloud.ServiceClient) error {
+				inventory := metrics.NewInventory(service)
+				jobs := metrics.NewJobMetrics()
+				goclouddns.SetJobObserver(service, jobs)
+
+				registry := prometheus.NewRegistry()
+				registry.MustRegister(
+					inventory,
+					jobs,
+					collectors.NewGoCollector(),
+					collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+				)
+
+				// the interval was checked above, so Run only returns once
+				// ctx is done
+				go func() { _ = inventory.Run(ctx, interval) }()
+
+				mux := http.NewServeMux()
+				mux.Handle("GET /metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+				server := &http.Server{
+					Addr:              listen,
+					Handler:           mux,
+					ReadHeaderTimeout: 10 * time.Second,
+				}
+				return serveUntilDone(ctx, server)
+			})
+		},
+	}
+	cmd.Flags().StringVar(&listen, "listen", ":9153", "address to serve /metrics on")
+	cmd.Flags().DurationVar(&interval, "interval", 5*time.Minute, "how often to walk domains and records")
+
+	return cmd
+}
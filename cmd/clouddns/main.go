@@ -2,12 +2,14 @@ package main
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 	"text/tabwriter"
 	"time"
 
@@ -33,6 +35,25 @@ type cliApp struct {
 	format  string
 	wide    bool
 	debug   bool
+	dryRun  bool
+
+	traceExporter string
+	traceEndpoint string
+
+	configPath  string
+	profileName string
+
+	// profileLoaded is set once the profile has been looked up, leaving
+	// activeProfile nil if none was chosen.
+	profileLoaded bool
+	activeProfile *profile
+
+	// stdin answers confirmation prompts. Tests set it; nil is os.Stdin.
+	stdin io.Reader
+
+	// connect builds the service client. Tests swap it out to talk to a
+	// fake server instead of authenticating against Rackspace.
+	connect func(context.Context) (*gophercloud.ServiceClient, error)
 }
 
 func main() {
@@ -56,13 +77,18 @@ func normalizeLegacyArgs(args []string) []string {
 }
 
 func newRootCmd() *cobra.Command {
-	app := &cliApp{}
+	return newRootCmdWithApp(&cliApp{})
+}
 
+func newRootCmdWithApp(app *cliApp) *cobra.Command {
 	rootCmd := &cobra.Command{
 		Use:           "clouddns",
 		Short:         "Manage Rackspace Cloud DNS domains and records",
 		SilenceUsage:  true,
 		SilenceErrors: true,
+		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+			return app.applyProfile(cmd)
+		},
 		RunE: func(cmd *cobra.Command, _ []string) error {
 			return cmd.Help()
 		},
@@ -70,60 +96,136 @@ func newRootCmd() *cobra.Command {
 	}
 
 	rootCmd.SetVersionTemplate("clouddns version {{.Version}}\ncommit: " + commit + "\nbuilt: " + date + "\n")
-	rootCmd.CompletionOptions.DisableDefaultCmd = true
+	// tests point configPath away from the user's config before the flags exist
+	rootCmd.PersistentFlags().StringVar(&app.configPath, "config", app.configPath, "config file (default ~/.config/clouddns/config.yaml)")
+	rootCmd.PersistentFlags().StringVar(&app.profileName, "profile", "", "config profile to use (default the current profile)")
 	rootCmd.PersistentFlags().UintVar(&app.timeout, "timeout", 60, "Operation timeout")
-	rootCmd.PersistentFlags().StringVar(&app.format, "format", "table", "output format: table or json")
+	rootCmd.PersistentFlags().StringVar(&app.format, "format", "table", "output format: "+outputFormatHelp)
 	rootCmd.PersistentFlags().BoolVar(&app.wide, "wide", false, "show full-width table output")
 	rootCmd.PersistentFlags().BoolVar(&app.debug, "debug", false, "show debug logging")
+	rootCmd.PersistentFlags().BoolVar(&app.dryRun, "dry-run", false, "print the requests that would change domains and records instead of sending them")
+	rootCmd.PersistentFlags().StringVar(&app.traceExporter, "trace-exporter", "none", "send OpenTelemetry traces to: none, stdout or otlp")
+	rootCmd.PersistentFlags().StringVar(&app.traceEndpoint, "trace-endpoint", "", "OTLP/HTTP endpoint URL for --trace-exporter otlp (default from OTEL_EXPORTER_OTLP_ENDPOINT)")
 
 	rootCmd.AddCommand(newDomainCmd(app))
 	rootCmd.AddCommand(newRecordCmd(app))
+	rootCmd.AddCommand(newTTLCmd(app))
+	rootCmd.AddCommand(newWebhookCmd(app))
+	rootCmd.AddCommand(newDDNSCmd(app))
+	rootCmd.AddCommand(newRFC2136Cmd(app))
+	rootCmd.AddCommand(newServeSnapshotCmd(app))
+	rootCmd.AddCommand(newExporterCmd(app))
+	rootCmd.AddCommand(newConfigCmd(app))
 
 	return rootCmd
 }
 
-func (app *cliApp) validateOutputFormat() error {
-	switch app.format {
-	case "table", "json":
-		return nil
-	default:
-		return fmt.Errorf("unsupported --format %q: must be one of table, json", app.format)
+func (app *cliApp) withService(run func(context.Context, *gophercloud.ServiceClient) error) error {
+	if err := app.prepare(); err != nil {
+		return err
 	}
-}
 
-func (app *cliApp) withService(run func(context.Context, *gophercloud.ServiceClient) error) error {
-	if err := app.validateOutputFormat(); err != nil {
+	ctx, cancel := context.WithTimeout(context.Background(), app.operationTimeout())
+	defer cancel()
+
+	service, err := app.connectService(ctx)
+	if err != nil {
 		return err
 	}
 
-	if app.debug {
-		log.SetOutput(os.Stderr)
-	} else {
-		log.SetOutput(io.Discard)
+	stopTracing, err := app.startTracing(ctx, service)
+	if err != nil {
+		return err
 	}
+	defer stopTracing()
 
-	timeout := time.Duration(app.timeout) * time.Second
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
-	defer cancel()
+	return run(ctx, service)
+}
 
-	opts, err := goraxauth.AuthOptionsFromEnv()
-	if err != nil {
+// withLongRunningService is withService for commands that run until they
+// are interrupted, such as servers and daemons, and for bulk changes that
+// may take longer than --timeout as a whole. --timeout only bounds
+// authentication, and run applies it to each step itself; ctx is cancelled
+// on SIGINT or SIGTERM.
+func (app *cliApp) withLongRunningService(run func(context.Context, *gophercloud.ServiceClient) error) error {
+	if err := app.prepare(); err != nil {
 		return err
 	}
 
-	provider, err := goraxauth.AuthenticatedClient(ctx, opts)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	connectCtx, cancel := context.WithTimeout(ctx, app.operationTimeout())
+	service, err := app.connectService(connectCtx)
+	cancel()
 	if err != nil {
 		return err
 	}
 
-	service, err := goclouddns.NewCloudDNS(provider, gophercloud.EndpointOpts{})
+	stopTracing, err := app.startTracing(ctx, service)
 	if err != nil {
 		return err
 	}
+	defer stopTracing()
 
 	return run(ctx, service)
 }
 
+func (app *cliApp) prepare() error {
+	if err := app.validateOutputFormat(); err != nil {
+		return err
+	}
+	if err := app.validateTraceExporter(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (app *cliApp) operationTimeout() time.Duration {
+	return time.Duration(app.timeout) * time.Second
+}
+
+func (app *cliApp) connectService(ctx context.Context) (*gophercloud.ServiceClient, error) {
+	connect := app.connectFromConfig
+	if app.connect != nil {
+		connect = app.connect
+	}
+
+	service, err := connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	goclouddns.SetLogger(service, app.logger())
+	if app.dryRun {
+		goclouddns.SetDryRun(service, dryRunPrinter{w: os.Stderr})
+	}
+	return service, nil
+}
+
+// logger writes to stderr, including every request and job poll when
+// --debug is set.
+func (app *cliApp) logger() *slog.Logger {
+	level := slog.LevelInfo
+	if app.debug {
+		level = slog.LevelDebug
+	}
+	return slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+}
+
+func connectFromEnv(ctx context.Context) (*gophercloud.ServiceClient, error) {
+	opts, err := goraxauth.AuthOptionsFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	provider, err := goraxauth.AuthenticatedClient(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return goclouddns.NewCloudDNS(provider, gophercloud.EndpointOpts{})
+}
+
 func exactArgsValidator(n int, usage string, expected string) cobra.PositionalArgs {
 	return func(cmd *cobra.Command, args []string) error {
 		switch {
@@ -191,14 +293,54 @@ func requireAnyFlag(cmd *cobra.Command, names ...string) error {
 	return fmt.Errorf("specify at least one of %s", strings.Join(formatted, ", "))
 }
 
-func printJSON(v any) error {
-	data, err := json.MarshalIndent(v, "", "  ")
-	if err != nil {
-		return err
+func listAllDomains(ctx context.Context, service *gophercloud.ServiceClient, opts domains.ListOptsBuilder) ([]domains.DomainList, error) {
+	var domainList []domains.DomainList
+
+	err := domains.List(ctx, service, opts).EachPage(ctx, func(ctx context.Context, page pagination.Page) (bool, error) {
+		pageDomains, err := domains.ExtractDomains(page)
+		if err != nil {
+			return false, err
+		}
+
+		domainList = append(domainList, pageDomains...)
+		return true, nil
+	})
+
+	return domainList, err
+}
+
+// resolveDomainID accepts either a domain ID or a fully qualified domain
+// name, looking names up with domains.IDFromName. IDs never contain a dot.
+func resolveDomainID(ctx context.Context, service *gophercloud.ServiceClient, domain string) (string, error) {
+	if !strings.Contains(domain, ".") {
+		return domain, nil
 	}
 
-	fmt.Println(string(data))
-	return nil
+	id, err := domains.IDFromName(ctx, service, domain)
+	var multiple gophercloud.ErrMultipleResourcesFound
+	if errors.As(err, &multiple) {
+		return "", fmt.Errorf("domain name %s is ambiguous: %d domains match, pass a domain ID instead", domain, multiple.Count)
+	}
+	if errors.As(err, &gophercloud.ErrResourceNotFound{}) {
+		return "", fmt.Errorf("no domain named %s", domain)
+	}
+	return id, err
+}
+
+func listAllRecords(ctx context.Context, service *gophercloud.ServiceClient, domID string, opts records.ListOptsBuilder) ([]records.RecordList, error) {
+	var recordList []records.RecordList
+
+	err := records.List(ctx, service, domID, opts).EachPage(ctx, func(ctx context.Context, page pagination.Page) (bool, error) {
+		pageRecords, err := records.ExtractRecords(page)
+		if err != nil {
+			return false, err
+		}
+
+		recordList = append(recordList, pageRecords...)
+		return true, nil
+	})
+
+	return recordList, err
 }
 
 func newTabWriter() *tabwriter.Writer {
@@ -229,8 +371,8 @@ func truncate(s string, max int) string {
 }
 
 func printDomainList(format string, wide bool, domain *domains.DomainList) error {
-	if format == "json" {
-		return printJSON(domain)
+	if format != "table" {
+		return printFormatted(format, domain)
 	}
 
 	w := newTabWriter()
@@ -249,8 +391,8 @@ func printDomainList(format string, wide bool, domain *domains.DomainList) error
 }
 
 func printDomainLists(format string, wide bool, domainList []domains.DomainList) error {
-	if format == "json" {
-		return printJSON(domainList)
+	if format != "table" {
+		return printFormatted(format, domainList)
 	}
 
 	w := newTabWriter()
@@ -273,8 +415,8 @@ func printDomainLists(format string, wide bool, domainList []domains.DomainList)
 }
 
 func printDomainShow(format string, domain *domains.DomainShow) error {
-	if format == "json" {
-		return printJSON(domain)
+	if format != "table" {
+		return printFormatted(format, domain)
 	}
 
 	nameservers := make([]string, 0, len(domain.Nameservers))
@@ -298,8 +440,8 @@ func printDomainShow(format string, domain *domains.DomainShow) error {
 }
 
 func printRecordList(format string, wide bool, record *records.RecordList) error {
-	if format == "json" {
-		return printJSON(record)
+	if format != "table" {
+		return printFormatted(format, record)
 	}
 
 	w := newTabWriter()
@@ -320,8 +462,8 @@ func printRecordList(format string, wide bool, record *records.RecordList) error
 }
 
 func printRecordLists(format string, wide bool, recordList []records.RecordList) error {
-	if format == "json" {
-		return printJSON(recordList)
+	if format != "table" {
+		return printFormatted(format, recordList)
 	}
 
 	w := newTabWriter()
@@ -346,8 +488,8 @@ func printRecordLists(format string, wide bool, recordList []records.RecordList)
 }
 
 func printRecordShow(format string, record *records.RecordShow) error {
-	if format == "json" {
-		return printJSON(record)
+	if format != "table" {
+		return printFormatted(format, record)
 	}
 
 	w := newTabWriter()
@@ -368,6 +510,7 @@ func newDomainCmd(app *cliApp) *cobra.Command {
 	domainCmd := &cobra.Command{
 		Use:   "domain",
 		Short: "Manage domains",
+		Long:  "Manage domains. A domain can be given by its ID or by its fully qualified name.",
 	}
 
 	var createComment string
@@ -414,19 +557,8 @@ func newDomainCmd(app *cliApp) *cobra.Command {
 		}, "\n"),
 		RunE: func(_ *cobra.Command, _ []string) error {
 			return app.withService(func(ctx context.Context, service *gophercloud.ServiceClient) error {
-				opts := domains.ListOpts{Name: listName}
-				pager := domains.List(ctx, service, opts)
-				var domainList []domains.DomainList
-
-				if err := pager.EachPage(ctx, func(ctx context.Context, page pagination.Page) (bool, error) {
-					pageDomains, err := domains.ExtractDomains(page)
-					if err != nil {
-						return false, err
-					}
-
-					domainList = append(domainList, pageDomains...)
-					return true, nil
-				}); err != nil {
+				domainList, err := listAllDomains(ctx, service, domains.ListOpts{Name: listName})
+				if err != nil {
 					return err
 				}
 
@@ -437,16 +569,23 @@ func newDomainCmd(app *cliApp) *cobra.Command {
 	listCmd.Flags().StringVar(&listName, "name", "", "filter domains matching this")
 
 	showCmd := &cobra.Command{
-		Use:   "show ID",
-		Short: "Show a domain",
-		Args:  exactArgsValidator(1, "clouddns domain show ID", "ID"),
+		Use:               "show ID",
+		Short:             "Show a domain",
+		Args:              exactArgsValidator(1, "clouddns domain show ID", "ID"),
+		ValidArgsFunction: app.completeArgs(domainIDCompleter),
 		Example: strings.Join([]string{
 			"  clouddns domain show <domain-id>",
+			"  clouddns domain show example.com",
 			"  clouddns domain show <domain-id> --format json",
 		}, "\n"),
 		RunE: func(_ *cobra.Command, args []string) error {
 			return app.withService(func(ctx context.Context, service *gophercloud.ServiceClient) error {
-				domain, err := domains.Get(ctx, service, args[0]).Extract()
+				domID, err := resolveDomainID(ctx, service, args[0])
+				if err != nil {
+					return err
+				}
+
+				domain, err := domains.Get(ctx, service, domID).Extract()
 				if err != nil {
 					return err
 				}
@@ -460,9 +599,10 @@ func newDomainCmd(app *cliApp) *cobra.Command {
 	var updateComment string
 	var updateTTL uint
 	updateCmd := &cobra.Command{
-		Use:   "update ID",
-		Short: "Update a domain",
-		Args:  exactArgsValidator(1, "clouddns domain update ID", "ID"),
+		Use:               "update ID",
+		Short:             "Update a domain",
+		Args:              exactArgsValidator(1, "clouddns domain update ID", "ID"),
+		ValidArgsFunction: app.completeArgs(domainIDCompleter),
 		Example: strings.Join([]string{
 			"  clouddns domain update <domain-id> --email admin@example.com",
 			"  clouddns domain update <domain-id> --ttl 7200 --comment \"updated zone\"",
@@ -473,7 +613,12 @@ func newDomainCmd(app *cliApp) *cobra.Command {
 			}
 
 			return app.withService(func(ctx context.Context, service *gophercloud.ServiceClient) error {
-				domain, err := domains.Get(ctx, service, args[0]).Extract()
+				domID, err := resolveDomainID(ctx, service, args[0])
+				if err != nil {
+					return err
+				}
+
+				domain, err := domains.Get(ctx, service, domID).Extract()
 				if err != nil {
 					return err
 				}
@@ -497,14 +642,42 @@ func newDomainCmd(app *cliApp) *cobra.Command {
 	updateCmd.Flags().StringVar(&updateComment, "comment", "", "optional comments")
 	updateCmd.Flags().UintVar(&updateTTL, "ttl", 0, "optional change to TTL for the SOA record")
 
+	var deleteYes, deleteOverride bool
 	deleteCmd := &cobra.Command{
-		Use:     "delete ID",
-		Short:   "Delete a domain",
-		Args:    exactArgsValidator(1, "clouddns domain delete ID", "ID"),
-		Example: "  clouddns domain delete <domain-id>",
+		Use:   "delete ID",
+		Short: "Delete a domain",
+		Long: strings.Join([]string{
+			"Delete a domain and all of its records. The domain's name and record count",
+			"are shown and the deletion has to be confirmed, unless --yes is given.",
+			"Domains protected with 'clouddns config protect' are only deleted with",
+			"--override-protection.",
+		}, "\n"),
+		Args:              exactArgsValidator(1, "clouddns domain delete ID", "ID"),
+		ValidArgsFunction: app.completeArgs(domainIDCompleter),
+		Example: strings.Join([]string{
+			"  clouddns domain delete <domain-id>",
+			"  clouddns domain delete old.example.com --yes",
+		}, "\n"),
 		RunE: func(_ *cobra.Command, args []string) error {
 			return app.withService(func(ctx context.Context, service *gophercloud.ServiceClient) error {
-				if err := domains.Delete(ctx, service, args[0]).ExtractErr(); err != nil {
+				domID, err := resolveDomainID(ctx, service, args[0])
+				if err != nil {
+					return err
+				}
+
+				domain, err := domains.Get(ctx, service, domID).Extract()
+				if err != nil {
+					return err
+				}
+				if err := app.checkProtected(domain.Name, deleteOverride); err != nil {
+					return err
+				}
+				prompt := fmt.Sprintf("Delete domain %s (%s) and its %d records?", domain.Name, domain.ID, domain.RecordsList.TotalEntries)
+				if err := app.confirmDelete(prompt, deleteYes); err != nil {
+					return err
+				}
+
+				if err := domains.Delete(ctx, service, domID).ExtractErr(); err != nil {
 					return err
 				}
 
@@ -514,7 +687,10 @@ func newDomainCmd(app *cliApp) *cobra.Command {
 		},
 	}
 
-	domainCmd.AddCommand(createCmd, listCmd, showCmd, updateCmd, deleteCmd)
+	deleteCmd.Flags().BoolVarP(&deleteYes, "yes", "y", false, "delete without asking")
+	deleteCmd.Flags().BoolVar(&deleteOverride, "override-protection", false, "delete the domain even if it is protected")
+
+	domainCmd.AddCommand(createCmd, listCmd, showCmd, updateCmd, deleteCmd, newDomainExportTerraformCmd(app))
 	return domainCmd
 }
 
@@ -522,20 +698,29 @@ func newRecordCmd(app *cliApp) *cobra.Command {
 	recordCmd := &cobra.Command{
 		Use:   "record",
 		Short: "Manage records",
+		Long:  "Manage records. DOMID can be a domain ID or the domain's fully qualified name.",
 	}
 
 	var createComment string
 	var createTTL uint
+	var createVerify verifyOptions
 	createCmd := &cobra.Command{
-		Use:   "create DOMID NAME TYPE DATA",
-		Short: "Create a record",
-		Args:  exactArgsValidator(4, "clouddns record create DOMID NAME TYPE DATA", "DOMID, NAME, TYPE, and DATA"),
+		Use:               "create DOMID NAME TYPE DATA",
+		Short:             "Create a record",
+		Args:              exactArgsValidator(4, "clouddns record create DOMID NAME TYPE DATA", "DOMID, NAME, TYPE, and DATA"),
+		ValidArgsFunction: app.completeArgs(domainIDCompleter),
 		Example: strings.Join([]string{
 			"  clouddns record create <domain-id> app.prod.example.com A 10.5.19.11",
 			"  clouddns record create <domain-id> mail.prod.example.com MX mail.example.com --ttl 300 --comment \"mail route\"",
+			"  clouddns record create <domain-id> app.prod.example.com A 10.5.19.11 --verify",
 		}, "\n"),
 		RunE: func(_ *cobra.Command, args []string) error {
 			return app.withService(func(ctx context.Context, service *gophercloud.ServiceClient) error {
+				domID, err := resolveDomainID(ctx, service, args[0])
+				if err != nil {
+					return err
+				}
+
 				opts := records.CreateOpts{
 					Name:    args[1],
 					Type:    args[2],
@@ -544,47 +729,48 @@ func newRecordCmd(app *cliApp) *cobra.Command {
 					Comment: createComment,
 				}
 
-				record, err := records.Create(ctx, service, args[0], opts).Extract()
+				record, err := records.Create(ctx, service, domID, opts).Extract()
 				if err != nil {
 					return err
 				}
 
-				return printRecordList(app.format, app.wide, record)
+				if err := printRecordList(app.format, app.wide, record); err != nil {
+					return err
+				}
+				return createVerify.verify(ctx, service, domID, *record, false)
 			})
 		},
 	}
 	createCmd.Flags().StringVar(&createComment, "comment", "", "optional comments")
 	createCmd.Flags().UintVar(&createTTL, "ttl", 0, "TTL for the record")
+	createVerify.addFlags(createCmd)
 
 	var listType string
 	listCmd := &cobra.Command{
-		Use:   "list DOMID",
-		Short: "List records",
-		Args:  exactArgsValidator(1, "clouddns record list DOMID", "DOMID"),
+		Use:               "list DOMID",
+		Short:             "List records",
+		Args:              exactArgsValidator(1, "clouddns record list DOMID", "DOMID"),
+		ValidArgsFunction: app.completeArgs(domainIDCompleter),
 		Example: strings.Join([]string{
 			"  clouddns record list <domain-id>",
+			"  clouddns record list example.com",
 			"  clouddns record list <domain-id> --type A",
 			"  clouddns record list <domain-id> --format json",
 			"  clouddns record list <domain-id> --wide",
 		}, "\n"),
 		RunE: func(_ *cobra.Command, args []string) error {
 			return app.withService(func(ctx context.Context, service *gophercloud.ServiceClient) error {
+				domID, err := resolveDomainID(ctx, service, args[0])
+				if err != nil {
+					return err
+				}
+
 				opts := records.ListOpts{
 					Type: listType,
 				}
 
-				pager := records.List(ctx, service, args[0], opts)
-				var recordList []records.RecordList
-
-				if err := pager.EachPage(ctx, func(ctx context.Context, page pagination.Page) (bool, error) {
-					pageRecords, err := records.ExtractRecords(page)
-					if err != nil {
-						return false, err
-					}
-
-					recordList = append(recordList, pageRecords...)
-					return true, nil
-				}); err != nil {
+				recordList, err := listAllRecords(ctx, service, domID, opts)
+				if err != nil {
 					return err
 				}
 
@@ -594,17 +780,40 @@ func newRecordCmd(app *cliApp) *cobra.Command {
 	}
 	listCmd.Flags().StringVar(&listType, "type", "", "filter records matching this type")
 
+	var showSelector recordSelector
 	showCmd := &cobra.Command{
-		Use:   "show DOMID ID",
-		Short: "Show a record",
-		Args:  exactArgsValidator(2, "clouddns record show DOMID ID", "DOMID and ID"),
+		Use:               "show DOMID ID",
+		Short:             "Show a record",
+		Long:              "Show a record, given its ID or selected with --name, --type and --data.",
+		Args:              showSelector.args("clouddns record show DOMID ID"),
+		ValidArgsFunction: app.completeArgs(domainIDCompleter, recordIDCompleter),
 		Example: strings.Join([]string{
 			"  clouddns record show <domain-id> <record-id>",
 			"  clouddns record show <domain-id> <record-id> --format json",
+			"  clouddns record show example.com --name www.example.com --type A",
 		}, "\n"),
 		RunE: func(_ *cobra.Command, args []string) error {
 			return app.withService(func(ctx context.Context, service *gophercloud.ServiceClient) error {
-				record, err := records.Get(ctx, service, args[0], args[1]).Extract()
+				domID, err := resolveDomainID(ctx, service, args[0])
+				if err != nil {
+					return err
+				}
+
+				recordID := ""
+				if len(args) == 2 {
+					recordID = args[1]
+				} else {
+					matches, err := showSelector.find(ctx, service, domID, args)
+					if err != nil {
+						return err
+					}
+					if len(matches) > 1 {
+						return printRecordLists(app.format, app.wide, matches)
+					}
+					recordID = matches[0].ID
+				}
+
+				record, err := records.Get(ctx, service, domID, recordID).Extract()
 				if err != nil {
 					return err
 				}
@@ -613,18 +822,29 @@ func newRecordCmd(app *cliApp) *cobra.Command {
 			})
 		},
 	}
+	showSelector.addFlags(showCmd, "data", "")
 
 	var updateData string
 	var updateComment string
 	var updatePriority uint
 	var updateTTL uint
+	var updateVerify verifyOptions
+	var updateSelector recordSelector
 	updateCmd := &cobra.Command{
 		Use:   "update DOMID ID",
 		Short: "Update a record",
-		Args:  exactArgsValidator(2, "clouddns record update DOMID ID", "DOMID and ID"),
+		Long: strings.Join([]string{
+			"Update a record, given its ID or selected with --name, --type and",
+			"--match-data. When the selection matches several records, all of them are",
+			"updated once confirmed, or straight away with --all.",
+		}, "\n"),
+		Args:              updateSelector.args("clouddns record update DOMID ID"),
+		ValidArgsFunction: app.completeArgs(domainIDCompleter, recordIDCompleter),
 		Example: strings.Join([]string{
 			"  clouddns record update <domain-id> <record-id> --data 10.5.19.11",
 			"  clouddns record <domain-id> update <record-id> --data 10.5.19.11",
+			"  clouddns record update example.com --name app.example.com --type A --data 10.5.19.11",
+			"  clouddns record update example.com --name app.example.com --type A --ttl 300 --all",
 		}, "\n"),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if err := requireAnyFlag(cmd, "data", "ttl", "priority", "comment"); err != nil {
@@ -632,24 +852,53 @@ func newRecordCmd(app *cliApp) *cobra.Command {
 			}
 
 			return app.withService(func(ctx context.Context, service *gophercloud.ServiceClient) error {
-				record, err := records.Get(ctx, service, args[0], args[1]).Extract()
+				domID, err := resolveDomainID(ctx, service, args[0])
 				if err != nil {
 					return err
 				}
 
-				opts := records.UpdateOpts{
-					Name:     record.Name,
-					Data:     updateData,
-					Priority: updatePriority,
-					TTL:      updateTTL,
-					Comment:  updateComment,
+				recordIDs, err := app.selectRecordIDs(ctx, service, domID, args, &updateSelector, "Update")
+				if err != nil {
+					return err
 				}
 
-				if err := records.Update(ctx, service, args[0], record, opts).ExtractErr(); err != nil {
-					return err
+				for _, recordID := range recordIDs {
+					record, err := records.Get(ctx, service, domID, recordID).Extract()
+					if err != nil {
+						return err
+					}
+
+					opts := records.UpdateOpts{
+						Name:     record.Name,
+						Data:     updateData,
+						Priority: updatePriority,
+						TTL:      updateTTL,
+						Comment:  updateComment,
+					}
+
+					if err := records.Update(ctx, service, domID, record, opts).ExtractErr(); err != nil {
+						return err
+					}
+				}
+
+				if len(recordIDs) == 1 {
+					fmt.Println("record updated")
+				} else {
+					fmt.Printf("%d records updated\n", len(recordIDs))
 				}
 
-				fmt.Println("record updated")
+				if !updateVerify.enabled {
+					return nil
+				}
+				for _, recordID := range recordIDs {
+					updated, err := records.Get(ctx, service, domID, recordID).Extract()
+					if err != nil {
+						return err
+					}
+					if err := updateVerify.verify(ctx, service, domID, recordListFromShow(updated), false); err != nil {
+						return err
+					}
+				}
 				return nil
 			})
 		},
@@ -658,24 +907,105 @@ func newRecordCmd(app *cliApp) *cobra.Command {
 	updateCmd.Flags().UintVar(&updatePriority, "priority", 0, "optional change to priority for the record")
 	updateCmd.Flags().UintVar(&updateTTL, "ttl", 0, "optional change to TTL for the record")
 	updateCmd.Flags().StringVar(&updateComment, "comment", "", "optional comments")
+	updateVerify.addFlags(updateCmd)
+	updateSelector.addFlags(updateCmd, "match-data", "update")
 
+	var deleteVerify verifyOptions
+	var deleteSelector recordSelector
+	var deleteYes, deleteOverride bool
 	deleteCmd := &cobra.Command{
-		Use:     "delete DOMID ID",
-		Short:   "Delete a record",
-		Args:    exactArgsValidator(2, "clouddns record delete DOMID ID", "DOMID and ID"),
-		Example: "  clouddns record delete <domain-id> <record-id>",
+		Use:   "delete DOMID ID",
+		Short: "Delete a record",
+		Long: strings.Join([]string{
+			"Delete a record, given its ID or selected with --name, --type and --data.",
+			"The records to delete are listed and the deletion has to be confirmed,",
+			"unless --yes is given, or --all when --name matches several records.",
+			"Records of domains protected with 'clouddns config protect' are only",
+			"deleted with --override-protection.",
+		}, "\n"),
+		Args:              deleteSelector.args("clouddns record delete DOMID ID"),
+		ValidArgsFunction: app.completeArgs(domainIDCompleter, recordIDCompleter),
+		Example: strings.Join([]string{
+			"  clouddns record delete <domain-id> <record-id>",
+			"  clouddns record delete <domain-id> <record-id> --verify",
+			"  clouddns record delete example.com --name old.example.com --type CNAME",
+			"  clouddns record delete example.com --name www.example.com --type A --all",
+			"  clouddns record delete <domain-id> <record-id> --yes",
+		}, "\n"),
 		RunE: func(_ *cobra.Command, args []string) error {
 			return app.withService(func(ctx context.Context, service *gophercloud.ServiceClient) error {
-				if err := records.Delete(ctx, service, args[0], args[1]).ExtractErr(); err != nil {
+				domID, err := resolveDomainID(ctx, service, args[0])
+				if err != nil {
 					return err
 				}
 
-				fmt.Println("Successfully deleted")
+				// the records are shown before asking, and needed to know
+				// what should stop being served
+				deleted, err := deleteSelector.find(ctx, service, domID, args)
+				if err != nil {
+					return err
+				}
+				if deleted == nil {
+					record, err := records.Get(ctx, service, domID, args[1]).Extract()
+					if err != nil {
+						return err
+					}
+					deleted = []records.RecordList{recordListFromShow(record)}
+				}
+
+				domain, err := domains.Get(ctx, service, domID).Extract()
+				if err != nil {
+					return err
+				}
+				if err := app.checkProtected(domain.Name, deleteOverride); err != nil {
+					return err
+				}
+
+				// --all only vouches for the records of a --name selection
+				// that matched several
+				if !deleteYes && (!deleteSelector.all || len(args) == 2 || len(deleted) < 2) {
+					var prompt strings.Builder
+					fmt.Fprintf(&prompt, "%d of the %d records of %s would be deleted:\n", len(deleted), domain.RecordsList.TotalEntries, domain.Name)
+					for _, record := range deleted {
+						fmt.Fprintf(&prompt, "  %s  %s %s %s\n", record.ID, record.Name, record.Type, record.Data)
+					}
+					prompt.WriteString("Delete them?")
+					if err := app.confirmDelete(prompt.String(), false); err != nil {
+						return err
+					}
+				}
+
+				for _, record := range deleted {
+					if err := records.Delete(ctx, service, domID, record.ID).ExtractErr(); err != nil {
+						return err
+					}
+				}
+
+				if len(deleted) == 1 {
+					fmt.Println("Successfully deleted")
+				} else {
+					fmt.Printf("Successfully deleted %d records\n", len(deleted))
+				}
+
+				if !deleteVerify.enabled {
+					return nil
+				}
+				for _, record := range deleted {
+					if err := deleteVerify.verify(ctx, service, domID, record, true); err != nil {
+						return err
+					}
+				}
 				return nil
 			})
 		},
 	}
+	deleteVerify.addFlags(deleteCmd)
+	deleteSelector.addFlags(deleteCmd, "data", "delete")
+	deleteCmd.Flags().BoolVarP(&deleteYes, "yes", "y", false, "delete without asking")
+	deleteCmd.Flags().BoolVar(&deleteOverride, "override-protection", false, "delete the records even if their domain is protected")
 
-	recordCmd.AddCommand(createCmd, listCmd, showCmd, updateCmd, deleteCmd)
+	recordCmd.AddCommand(createCmd, listCmd, showCmd, updateCmd, deleteCmd,
+		newRecordSearchCmd(app), newRecordReplaceCmd(app),
+		newRecordExportCmd(app), newRecordImportCmd(app))
 	return recordCmd
 }
@@ -2,13 +2,17 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"io"
 	"os"
 	"reflect"
 	"strings"
 	"testing"
 
+	"github.com/gophercloud/gophercloud/v2"
+
 	"github.com/rackerlabs/goclouddns/domains"
+	"github.com/rackerlabs/goclouddns/fakedns"
 	"github.com/rackerlabs/goclouddns/records"
 )
 
@@ -78,7 +82,7 @@ func TestRootVersionFlag(t *testing.T) {
 
 func TestInvalidFormatFailsBeforeServiceSetup(t *testing.T) {
 	cmd := newRootCmd()
-	cmd.SetArgs([]string{"domain", "list", "--format", "yaml"})
+	cmd.SetArgs([]string{"domain", "list", "--format", "xml"})
 
 	err := cmd.Execute()
 	if err == nil {
@@ -270,6 +274,76 @@ func TestPrintRecordListsCompactTable(t *testing.T) {
 	}
 }
 
+func TestDomainListAgainstFakeServer(t *testing.T) {
+	server := fakedns.NewServer()
+	defer server.Close()
+	server.AddDomain(domains.CreateOpts{Name: "prod.undercloud.rackspace.net", Email: "hostmaster@rackspace.com"})
+
+	output := captureStdout(t, func() {
+		if err := runAgainst(server, "domain", "list"); err != nil {
+			t.Fatalf("Execute() returned error: %v", err)
+		}
+	})
+
+	if !strings.Contains(output, "prod.undercloud.rackspace.net") {
+		t.Fatalf("expected domain in output, got %q", output)
+	}
+}
+
+func TestRecordCreateAgainstFakeServer(t *testing.T) {
+	server := fakedns.NewServer()
+	defer server.Close()
+	domID := server.AddDomain(domains.CreateOpts{Name: "example.com", Email: "admin@example.com"})
+
+	output := captureStdout(t, func() {
+		if err := runAgainst(server, "record", "create", domID, "app.example.com", "A", "10.5.19.11", "--ttl", "300"); err != nil {
+			t.Fatalf("Execute() returned error: %v", err)
+		}
+	})
+
+	if !strings.Contains(output, "app.example.com") || !strings.Contains(output, "10.5.19.11") {
+		t.Fatalf("expected created record in output, got %q", output)
+	}
+}
+
+func TestRecordUpdateReportsJobError(t *testing.T) {
+	server := fakedns.NewServer()
+	defer server.Close()
+	domID := server.AddDomain(domains.CreateOpts{Name: "example.com", Email: "admin@example.com"})
+	recID, err := server.AddRecord(domID, records.CreateOpts{Name: "app.example.com", Type: "A", Data: "10.5.19.11"})
+	if err != nil {
+		t.Fatalf("AddRecord() returned error: %v", err)
+	}
+	server.FailNextJob("Record is locked")
+
+	err = runAgainst(server, "record", "update", domID, recID, "--data", "10.5.19.12")
+	if err == nil || err.Error() != "Record is locked" {
+		t.Fatalf("expected job error, got %v", err)
+	}
+}
+
+// runAgainst executes the CLI with its service client pointed at server,
+// answering no to any prompt.
+func runAgainst(server *fakedns.Server, args ...string) error {
+	return runWithStdin(server, "", args...)
+}
+
+// runWithStdin is runAgainst with answers for confirmation prompts.
+func runWithStdin(server *fakedns.Server, stdin string, args ...string) error {
+	app := &cliApp{
+		// keep the user's own config file out of tests
+		configPath: os.DevNull,
+		stdin:      strings.NewReader(stdin),
+		connect: func(context.Context) (*gophercloud.ServiceClient, error) {
+			return server.ServiceClient(), nil
+		},
+	}
+
+	cmd := newRootCmdWithApp(app)
+	cmd.SetArgs(args)
+	return cmd.Execute()
+}
+
 func captureStdout(t *testing.T, fn func()) string {
 	t.Helper()
 
@@ -297,3 +371,51 @@ func captureStdout(t *testing.T, fn func()) string {
 
 	return string(out)
 }
+
+func TestRecordListByDomainName(t *testing.T) {
+	server := fakedns.NewServer()
+	defer server.Close()
+	server.AddDomain(domains.CreateOpts{Name: "sub.example.com", Email: "admin@example.com"})
+	domID := server.AddDomain(domains.CreateOpts{Name: "example.com", Email: "admin@example.com"})
+	if _, err := server.AddRecord(domID, records.CreateOpts{Name: "app.example.com", Type: "A", Data: "10.5.19.11"}); err != nil {
+		t.Fatalf("AddRecord() returned error: %v", err)
+	}
+
+	output := captureStdout(t, func() {
+		if err := runAgainst(server, "record", "list", "example.com."); err != nil {
+			t.Fatalf("Execute() returned error: %v", err)
+		}
+	})
+	if !strings.Contains(output, "app.example.com") {
+		t.Fatalf("expected the record of example.com, got %q", output)
+	}
+
+	err := runAgainst(server, "domain", "show", "missing.example.org")
+	if err == nil || err.Error() != "no domain named missing.example.org" {
+		t.Fatalf("expected unknown name error, got %v", err)
+	}
+}
+
+func TestDomainNameAmbiguous(t *testing.T) {
+	server := fakedns.NewServer()
+	defer server.Close()
+	server.AddDomain(domains.CreateOpts{Name: "example.com", Email: "admin@example.com"})
+	server.AddDomain(domains.CreateOpts{Name: "EXAMPLE.COM", Email: "admin@example.com"})
+
+	err := runAgainst(server, "domain", "delete", "example.com")
+	if err == nil || !strings.Contains(err.Error(), "ambiguous") {
+		t.Fatalf("expected ambiguous name error, got %v", err)
+	}
+}
+
+func TestExporterRejectsNonPositiveInterval(t *testing.T) {
+	for _, interval := range []string{"0", "-1m"} {
+		cmd := newRootCmd()
+		cmd.SetArgs([]string{"exporter", "--interval", interval})
+
+		err := cmd.Execute()
+		if err == nil || !strings.Contains(err.Error(), "must be positive") {
+			t.Fatalf("expected invalid interval error for %s, got %v", interval, err)
+		}
+	}
+}
@@ -0,0 +1,432 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// outputFormats lists the --format values that take no argument.
+var outputFormats = []string{"table", "json", "yaml", "csv", "jsonl"}
+
+// outputFormatHelp describes --format for the flag help and errors.
+const outputFormatHelp = "table, json, yaml, csv, jsonl, go-template=TEMPLATE or jsonpath=EXPR"
+
+// validateOutputFormat checks --format, including that its template or
+// expression parses.
+func (app *cliApp) validateOutputFormat() error {
+	format := app.format
+	if kind, arg, ok := strings.Cut(format, "="); ok {
+		switch kind {
+		case "go-template":
+			_, err := template.New("format").Parse(arg)
+			if err != nil {
+				return fmt.Errorf("invalid --format go-template: %w", err)
+			}
+			return nil
+		case "jsonpath":
+			_, err := parseJSONPath(arg)
+			if err != nil {
+				return fmt.Errorf("invalid --format jsonpath: %w", err)
+			}
+			return nil
+		}
+	}
+
+	for _, known := range outputFormats {
+		if format == known {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported --format %q: must be one of %s", format, outputFormatHelp)
+}
+
+// printFormatted writes v, a resource or a slice of them, to stdout in any
+// --format except table. Every format works from v's JSON encoding, so
+// templates and expressions use the field names of --format json.
+func printFormatted(format string, v any) error {
+	return writeFormatted(os.Stdout, format, v)
+}
+
+func writeFormatted(w io.Writer, format string, v any) error {
+	kind, arg, _ := strings.Cut(format, "=")
+	if kind == "json" {
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	}
+	if kind == "csv" {
+		return writeCSV(w, v)
+	}
+
+	data, err := genericValue(v)
+	if err != nil {
+		return err
+	}
+
+	switch kind {
+	case "yaml":
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(data); err != nil {
+			return err
+		}
+		return enc.Close()
+	case "jsonl":
+		items, ok := data.([]any)
+		if !ok {
+			items = []any{data}
+		}
+		enc := json.NewEncoder(w)
+		for _, item := range items {
+			if err := enc.Encode(item); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "go-template":
+		tmpl, err := template.New("format").Option("missingkey=error").Parse(arg)
+		if err != nil {
+			return err
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return err
+		}
+		return writeLine(w, buf.String())
+	case "jsonpath":
+		path, err := parseJSONPath(arg)
+		if err != nil {
+			return err
+		}
+		out, err := path.execute(data)
+		if err != nil {
+			return err
+		}
+		return writeLine(w, out)
+	default:
+		return fmt.Errorf("unsupported --format %q: must be one of %s", format, outputFormatHelp)
+	}
+}
+
+// writeLine writes s, ending it with a newline if it has none.
+func writeLine(w io.Writer, s string) error {
+	if s != "" && !strings.HasSuffix(s, "\n") {
+		s += "\n"
+	}
+	_, err := io.WriteString(w, s)
+	return err
+}
+
+// genericValue round-trips v through JSON into the maps and slices that
+// templates and expressions walk.
+func genericValue(v any) (any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var out any
+	err = json.Unmarshal(data, &out)
+	return out, err
+}
+
+// writeCSV writes v as a header and a row per resource. The columns are
+// the scalar JSON fields of the resource type, in declaration order;
+// nested lists and objects are left out.
+func writeCSV(w io.Writer, v any) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		rv = rv.Elem()
+	}
+
+	var rows []reflect.Value
+	if rv.Kind() == reflect.Slice {
+		for i := 0; i < rv.Len(); i++ {
+			rows = append(rows, rv.Index(i))
+		}
+	} else {
+		rows = append(rows, rv)
+	}
+
+	elem := rv.Type()
+	if elem.Kind() == reflect.Slice {
+		elem = elem.Elem()
+	}
+	columns := csvColumns(elem)
+
+	out := csv.NewWriter(w)
+	header := make([]string, len(columns))
+	for i, c := range columns {
+		header[i] = c.name
+	}
+	if err := out.Write(header); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		record := make([]string, len(columns))
+		for i, c := range columns {
+			record[i] = fmt.Sprint(row.FieldByIndex(c.index).Interface())
+		}
+		if err := out.Write(record); err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+type csvColumn struct {
+	name  string
+	index []int
+}
+
+func csvColumns(t reflect.Type) []csvColumn {
+	var columns []csvColumn
+	for _, field := range reflect.VisibleFields(t) {
+		if !field.IsExported() || field.Anonymous {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		switch field.Type.Kind() {
+		case reflect.String, reflect.Bool,
+			reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+			columns = append(columns, csvColumn{name: name, index: field.Index})
+		}
+	}
+	return columns
+}
+
+// jsonPath is a kubectl-style JSONPath template such as "{[*].id}": text
+// with expressions in braces. A template without braces is one
+// expression. Expressions support .field, ['field'], [n], [-n], [*] and
+// .*; every match is printed, separated by spaces.
+type jsonPath struct {
+	parts []jsonPathPart
+}
+
+type jsonPathPart struct {
+	text  string
+	steps []jsonPathStep
+	expr  bool
+}
+
+type jsonPathStep struct {
+	field string
+	index int
+	kind  jsonPathStepKind
+}
+
+type jsonPathStepKind int
+
+const (
+	stepField jsonPathStepKind = iota
+	stepIndex
+	stepAll
+)
+
+func parseJSONPath(tmpl string) (*jsonPath, error) {
+	if !strings.Contains(tmpl, "{") {
+		tmpl = "{" + tmpl + "}"
+	}
+
+	var path jsonPath
+	for tmpl != "" {
+		open := strings.IndexByte(tmpl, '{')
+		if open < 0 {
+			path.parts = append(path.parts, jsonPathPart{text: tmpl})
+			break
+		}
+		if open > 0 {
+			path.parts = append(path.parts, jsonPathPart{text: tmpl[:open]})
+		}
+
+		end := strings.IndexByte(tmpl[open:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("unclosed { in %q", tmpl)
+		}
+		expr := strings.TrimSpace(tmpl[open+1 : open+end])
+		tmpl = tmpl[open+end+1:]
+
+		if unquoted, err := strconv.Unquote(expr); err == nil && strings.HasPrefix(expr, `"`) {
+			path.parts = append(path.parts, jsonPathPart{text: unquoted})
+			continue
+		}
+		steps, err := parseJSONPathSteps(expr)
+		if err != nil {
+			return nil, err
+		}
+		path.parts = append(path.parts, jsonPathPart{steps: steps, expr: true})
+	}
+	return &path, nil
+}
+
+func parseJSONPathSteps(expr string) ([]jsonPathStep, error) {
+	rest := strings.TrimPrefix(expr, "$")
+	if rest != "" && rest[0] != '.' && rest[0] != '[' {
+		rest = "." + rest
+	}
+
+	var steps []jsonPathStep
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			name := rest[:end]
+			rest = rest[end:]
+
+			switch name {
+			case "":
+				if rest == "" && len(steps) == 0 {
+					// "." alone is the whole value
+					return nil, nil
+				}
+				return nil, fmt.Errorf("empty field name in %q", expr)
+			case "*":
+				steps = append(steps, jsonPathStep{kind: stepAll})
+			default:
+				steps = append(steps, jsonPathStep{kind: stepField, field: name})
+			}
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed [ in %q", expr)
+			}
+			inner := strings.TrimSpace(rest[1:end])
+			rest = rest[end+1:]
+
+			switch {
+			case inner == "*":
+				steps = append(steps, jsonPathStep{kind: stepAll})
+			case len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0]:
+				steps = append(steps, jsonPathStep{kind: stepField, field: inner[1 : len(inner)-1]})
+			default:
+				n, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, fmt.Errorf("invalid index [%s] in %q", inner, expr)
+				}
+				steps = append(steps, jsonPathStep{kind: stepIndex, index: n})
+			}
+		default:
+			return nil, fmt.Errorf("unexpected %q in %q", rest[0], expr)
+		}
+	}
+	return steps, nil
+}
+
+func (p *jsonPath) execute(data any) (string, error) {
+	var b strings.Builder
+	for _, part := range p.parts {
+		if !part.expr {
+			b.WriteString(part.text)
+			continue
+		}
+
+		values, err := evalJSONPath(part.steps, data)
+		if err != nil {
+			return "", err
+		}
+		for i, v := range values {
+			if i > 0 {
+				b.WriteByte(' ')
+			}
+			s, err := jsonPathString(v)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(s)
+		}
+	}
+	return b.String(), nil
+}
+
+func evalJSONPath(steps []jsonPathStep, data any) ([]any, error) {
+	values := []any{data}
+	for _, step := range steps {
+		var next []any
+		for _, v := range values {
+			switch step.kind {
+			case stepField:
+				obj, ok := v.(map[string]any)
+				if !ok {
+					return nil, fmt.Errorf("jsonpath: cannot take field %q of %T", step.field, v)
+				}
+				field, ok := obj[step.field]
+				if !ok {
+					return nil, fmt.Errorf("jsonpath: field %q not found", step.field)
+				}
+				next = append(next, field)
+			case stepIndex:
+				list, ok := v.([]any)
+				if !ok {
+					return nil, fmt.Errorf("jsonpath: cannot index %T", v)
+				}
+				i := step.index
+				if i < 0 {
+					i += len(list)
+				}
+				if i < 0 || i >= len(list) {
+					return nil, fmt.Errorf("jsonpath: index %d out of range", step.index)
+				}
+				next = append(next, list[i])
+			case stepAll:
+				switch v := v.(type) {
+				case []any:
+					next = append(next, v...)
+				case map[string]any:
+					keys := make([]string, 0, len(v))
+					for k := range v {
+						keys = append(keys, k)
+					}
+					sort.Strings(keys)
+					for _, k := range keys {
+						next = append(next, v[k])
+					}
+				default:
+					return nil, fmt.Errorf("jsonpath: cannot iterate %T", v)
+				}
+			}
+		}
+		values = next
+	}
+	return values, nil
+}
+
+// jsonPathString prints strings bare and anything else as JSON.
+func jsonPathString(v any) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case nil:
+		return "", nil
+	default:
+		data, err := json.Marshal(v)
+		return string(data), err
+	}
+}
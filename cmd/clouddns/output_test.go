@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/rackerlabs/goclouddns/domains"
+	"github.com/rackerlabs/goclouddns/fakedns"
+	"github.com/rackerlabs/goclouddns/records"
+)
+
+var outputRecords = []records.RecordList{
+	{ID: "A-1", Name: "www.example.com", Type: "A", Data: "192.0.2.10", TTL: 300},
+	{ID: "MX-2", Name: "example.com", Type: "MX", Data: "mail.example.com", TTL: 3600, Priority: 10, Comment: "mail, primary"},
+}
+
+func TestWriteFormatted(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"jsonpath={[*].ID}", "A-1 MX-2\n"},
+		{"jsonpath=[1].Priority", "10\n"},
+		{`jsonpath={[-1].Name}{"\t"}{[-1].TTL}`, "example.com\t3600\n"},
+		{`go-template={{range .}}{{.ID}},{{.Type}}{{"\n"}}{{end}}`, "A-1,A\nMX-2,MX\n"},
+		{"jsonl", `{"Comment":"","Data":"192.0.2.10","ID":"A-1","Name":"www.example.com","Priority":0,"TTL":300,"Type":"A"}` + "\n{"},
+		{"csv", "ID,Name,Type,Data,TTL,Priority,Comment\nA-1,www.example.com,A,192.0.2.10,300,0,\nMX-2,example.com,MX,mail.example.com,3600,10,\"mail, primary\"\n"},
+		{"yaml", "- Comment: \"\"\n  Data: 192.0.2.10\n  ID: A-1\n  Name: www.example.com\n  Priority: 0\n  TTL: 300\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := writeFormatted(&buf, tt.format, outputRecords); err != nil {
+				t.Fatalf("writeFormatted() returned error: %v", err)
+			}
+			if !strings.HasPrefix(buf.String(), tt.want) {
+				t.Errorf("writeFormatted() = %q, want prefix %q", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteFormattedJSONPathMissingField(t *testing.T) {
+	var buf bytes.Buffer
+	err := writeFormatted(&buf, "jsonpath={[*].nope}", outputRecords)
+	if err == nil || !strings.Contains(err.Error(), `"nope" not found`) {
+		t.Fatalf("expected missing field error, got %v", err)
+	}
+}
+
+func TestValidateOutputFormatChecksTemplates(t *testing.T) {
+	for _, format := range []string{"yaml", "csv", "jsonl", "go-template={{.ID}}", "jsonpath={.ID}"} {
+		if err := (&cliApp{format: format}).validateOutputFormat(); err != nil {
+			t.Errorf("validateOutputFormat(%q) returned error: %v", format, err)
+		}
+	}
+	for _, format := range []string{"xml", "go-template={{.id", "jsonpath={[x]}"} {
+		if err := (&cliApp{format: format}).validateOutputFormat(); err == nil {
+			t.Errorf("validateOutputFormat(%q) returned no error", format)
+		}
+	}
+}
+
+func TestDomainListJSONPathAgainstFakeServer(t *testing.T) {
+	server := fakedns.NewServer()
+	defer server.Close()
+	first := server.AddDomain(domains.CreateOpts{Name: "example.com", Email: "admin@example.com"})
+	second := server.AddDomain(domains.CreateOpts{Name: "example.net", Email: "admin@example.net"})
+
+	output := captureStdout(t, func() {
+		if err := runAgainst(server, "domain", "list", "--format", "jsonpath={[*].ID}"); err != nil {
+			t.Fatalf("Execute() returned error: %v", err)
+		}
+	})
+
+	if output != first+" "+second+"\n" {
+		t.Fatalf("expected domain IDs, got %q", output)
+	}
+}
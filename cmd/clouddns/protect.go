@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// normalizeDomainName makes domain names comparable: lower case, without
+// the trailing dot.
+func normalizeDomainName(name string) string {
+	return strings.ToLower(strings.TrimSuffix(name, "."))
+}
+
+// isProtected reports whether name is on the config's protected-domains
+// list.
+func (cfg *config) isProtected(name string) bool {
+	return slices.Contains(cfg.ProtectedDomains, normalizeDomainName(name))
+}
+
+// checkProtected refuses to go on with deleting the domain name, or
+// deleting or changing its records, when it is protected, unless override
+// is set.
+//
+// Besides deletes, it guards the bulk writes record replace, record import
+// and ttl lower. Each can rewrite most of a domain in one go: a replace
+// across all domains can repoint a protected zone along with the rest, and
+// an import or lowered TTLs can do as much harm as deleting the records.
+// Record create and update are left alone, as they only change records
+// the user picked by name and type.
+func (app *cliApp) checkProtected(name string, override bool) error {
+	return app.withConfig(func(cfg *config, path string) error {
+		if !cfg.isProtected(name) || override {
+			return nil
+		}
+		return fmt.Errorf("domain %s is protected in %s: pass --override-protection to go ahead anyway", name, path)
+	})
+}
+
+// confirmDelete asks before deleting what prompt describes, unless yes is
+// set or nothing is really deleted because of --dry-run.
+func (app *cliApp) confirmDelete(prompt string, yes bool) error {
+	if yes || app.dryRun {
+		return nil
+	}
+
+	ok, err := app.confirm(prompt)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("not deleted: answer yes or pass --yes to delete without asking")
+	}
+	return nil
+}
+
+func newConfigProtectCmd(app *cliApp) *cobra.Command {
+	return &cobra.Command{
+		Use:   "protect [DOMAIN...]",
+		Short: "Refuse to delete domains, or list the protected ones",
+		Long: strings.Join([]string{
+			"Add domains to the protected-domains list of the config file. clouddns",
+			"refuses to delete a protected domain, or to delete, replace, import or",
+			"lower the TTLs of its records, unless --override-protection is given.",
+			"Without arguments, the protected domains are listed.",
+		}, "\n"),
+		Example: strings.Join([]string{
+			"  clouddns config protect example.com example.org",
+			"  clouddns config protect",
+		}, "\n"),
+		RunE: func(_ *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return app.withConfig(func(cfg *config, _ string) error {
+					for _, name := range cfg.ProtectedDomains {
+						fmt.Println(name)
+					}
+					return nil
+				})
+			}
+
+			return app.updateConfig(func(cfg *config) error {
+				for _, name := range args {
+					if !cfg.isProtected(name) {
+						cfg.ProtectedDomains = append(cfg.ProtectedDomains, normalizeDomainName(name))
+					}
+				}
+				slices.Sort(cfg.ProtectedDomains)
+				return nil
+			})
+		},
+	}
+}
+
+func newConfigUnprotectCmd(app *cliApp) *cobra.Command {
+	return &cobra.Command{
+		Use:   "unprotect DOMAIN...",
+		Short: "Remove domains from the protected-domains list",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			return app.updateConfig(func(cfg *config) error {
+				for _, name := range args {
+					if !cfg.isProtected(name) {
+						return fmt.Errorf("domain %s is not protected", name)
+					}
+					cfg.ProtectedDomains = slices.DeleteFunc(cfg.ProtectedDomains, func(protected string) bool {
+						return protected == normalizeDomainName(name)
+					})
+				}
+				return nil
+			})
+		},
+	}
+}
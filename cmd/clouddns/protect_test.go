@@ -0,0 +1,153 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rackerlabs/goclouddns/domains"
+	"github.com/rackerlabs/goclouddns/fakedns"
+	"github.com/rackerlabs/goclouddns/records"
+)
+
+func TestDomainDeleteConfirmation(t *testing.T) {
+	server := fakedns.NewServer()
+	defer server.Close()
+	domID := server.AddDomain(domains.CreateOpts{Name: "example.com", Email: "admin@example.com"})
+	if _, err := server.AddRecord(domID, records.CreateOpts{Name: "www.example.com", Type: "A", Data: "10.5.19.11"}); err != nil {
+		t.Fatalf("AddRecord() returned error: %v", err)
+	}
+
+	err := runWithStdin(server, "n\n", "domain", "delete", domID)
+	if err == nil || !strings.Contains(err.Error(), "pass --yes") {
+		t.Fatalf("expected unconfirmed delete error, got %v", err)
+	}
+	if _, ok := server.Domain(domID); !ok {
+		t.Fatal("expected the domain to be kept when the delete is not confirmed")
+	}
+
+	captureStdout(t, func() {
+		if err := runWithStdin(server, "yes\n", "domain", "delete", domID); err != nil {
+			t.Fatalf("Execute() returned error: %v", err)
+		}
+	})
+	if _, ok := server.Domain(domID); ok {
+		t.Fatal("expected the domain to be deleted once confirmed")
+	}
+}
+
+func TestDomainDeleteProtected(t *testing.T) {
+	server := fakedns.NewServer()
+	defer server.Close()
+	domID := server.AddDomain(domains.CreateOpts{Name: "example.com", Email: "admin@example.com"})
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	runConfig(t, path, "config", "protect", "Example.com.")
+	if got := runConfig(t, path, "config", "protect"); got != "example.com\n" {
+		t.Fatalf("unexpected protected domains %q", got)
+	}
+
+	err := runAgainst(server, "--config", path, "domain", "delete", "example.com", "--yes")
+	if err == nil || !strings.Contains(err.Error(), "domain example.com is protected") {
+		t.Fatalf("expected protected domain error, got %v", err)
+	}
+	if _, ok := server.Domain(domID); !ok {
+		t.Fatal("expected the protected domain to be kept")
+	}
+
+	captureStdout(t, func() {
+		if err := runAgainst(server, "--config", path, "domain", "delete", "example.com", "--yes", "--override-protection"); err != nil {
+			t.Fatalf("Execute() returned error: %v", err)
+		}
+	})
+	if _, ok := server.Domain(domID); ok {
+		t.Fatal("expected --override-protection to delete the domain")
+	}
+
+	runConfig(t, path, "config", "unprotect", "example.com")
+	if got := runConfig(t, path, "config", "protect"); got != "" {
+		t.Fatalf("expected no protected domains, got %q", got)
+	}
+}
+
+func TestRecordChangesProtected(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	server := fakedns.NewServer()
+	defer server.Close()
+	domID := server.AddDomain(domains.CreateOpts{Name: "example.com", Email: "admin@example.com"})
+	recID, err := server.AddRecord(domID, records.CreateOpts{Name: "www.example.com", Type: "A", Data: "10.5.19.11", TTL: 3600})
+	if err != nil {
+		t.Fatalf("AddRecord() returned error: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	runConfig(t, path, "config", "protect", "example.com")
+
+	for _, args := range [][]string{
+		{"record", "delete", domID, recID, "--yes"},
+		{"record", "replace", "--from", "10.5.19.11", "--to", "10.8.0.11", "--yes"},
+		{"record", "import", "example.com", "-"},
+		{"ttl", "lower", "example.com", "--to", "60"},
+	} {
+		err := runAgainst(server, append([]string{"--config", path}, args...)...)
+		if err == nil || !strings.Contains(err.Error(), "domain example.com is protected") {
+			t.Errorf("%s: expected protected domain error, got %v", strings.Join(args, " "), err)
+		}
+	}
+	if record, ok := server.Record(domID, recID); !ok || record.Data != "10.5.19.11" || record.TTL != 3600 {
+		t.Fatalf("expected the record to be left alone, got %+v", record)
+	}
+
+	captureStdout(t, func() {
+		if err := runAgainst(server, "--config", path, "record", "delete", domID, recID, "--yes", "--override-protection"); err != nil {
+			t.Fatalf("Execute() returned error: %v", err)
+		}
+	})
+	if _, ok := server.Record(domID, recID); ok {
+		t.Fatal("expected --override-protection to delete the record")
+	}
+}
+
+func TestRecordDeleteAllOnlySkipsSeveralMatches(t *testing.T) {
+	server := fakedns.NewServer()
+	defer server.Close()
+	domID := server.AddDomain(domains.CreateOpts{Name: "example.com", Email: "admin@example.com"})
+	recID, err := server.AddRecord(domID, records.CreateOpts{Name: "www.example.com", Type: "A", Data: "10.5.19.11"})
+	if err != nil {
+		t.Fatalf("AddRecord() returned error: %v", err)
+	}
+
+	for _, args := range [][]string{
+		{"record", "delete", domID, recID, "--all"},
+		{"record", "delete", domID, "--name", "www.example.com", "--all"},
+	} {
+		err := runWithStdin(server, "n\n", args...)
+		if err == nil || !strings.Contains(err.Error(), "pass --yes") {
+			t.Errorf("%s: expected the delete to be confirmed, got %v", strings.Join(args, " "), err)
+		}
+	}
+	if _, ok := server.Record(domID, recID); !ok {
+		t.Fatal("expected the record to be kept when the delete is not confirmed")
+	}
+}
+
+func TestRecordDeleteAllSeveralMatches(t *testing.T) {
+	server := fakedns.NewServer()
+	defer server.Close()
+	domID := server.AddDomain(domains.CreateOpts{Name: "example.com", Email: "admin@example.com"})
+	for _, data := range []string{"10.5.19.11", "10.5.19.12"} {
+		if _, err := server.AddRecord(domID, records.CreateOpts{Name: "www.example.com", Type: "A", Data: data}); err != nil {
+			t.Fatalf("AddRecord() returned error: %v", err)
+		}
+	}
+
+	output := captureStdout(t, func() {
+		if err := runAgainst(server, "record", "delete", domID, "--name", "www.example.com", "--all"); err != nil {
+			t.Fatalf("Execute() returned error: %v", err)
+		}
+	})
+	if !strings.Contains(output, "Successfully deleted 2 records") {
+		t.Fatalf("unexpected output %q", output)
+	}
+}
@@ -0,0 +1,400 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gophercloud/gophercloud/v2"
+	"github.com/spf13/cobra"
+
+	"github.com/rackerlabs/goclouddns/domains"
+	"github.com/rackerlabs/goclouddns/records"
+)
+
+// recordCSVColumns are the columns of 'record export' and 'record import'.
+// Only name, type and data are required on import; rows with an id update
+// that record, the others are created.
+var recordCSVColumns = []string{"id", "name", "type", "data", "ttl", "priority", "comment"}
+
+// importTypes are the record types 'record import' accepts.
+var importTypes = []string{"A", "AAAA", "CNAME", "MX", "NS", "PTR", "SRV", "TXT"}
+
+func writeRecordCSV(w io.Writer, recordList []records.RecordList) error {
+	out := csv.NewWriter(w)
+	if err := out.Write(recordCSVColumns); err != nil {
+		return err
+	}
+	for _, record := range recordList {
+		priority := ""
+		if record.Priority != 0 {
+			priority = strconv.FormatUint(uint64(record.Priority), 10)
+		}
+		row := []string{
+			record.ID,
+			record.Name,
+			record.Type,
+			record.Data,
+			strconv.FormatUint(uint64(record.TTL), 10),
+			priority,
+			record.Comment,
+		}
+		if err := out.Write(row); err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// importRow is one valid row of an import file.
+type importRow struct {
+	line int
+	id   string
+	opts records.CreateOpts
+
+	// clearComment is set for an empty comment cell, which removes the
+	// comment of the record updated. Without a comment column, comments
+	// are left alone.
+	clearComment bool
+}
+
+// readRecordCSV reads and checks every row of an import file for the
+// domain named domainName. It returns every problem found, each with its
+// line number, rather than stopping at the first.
+func readRecordCSV(r io.Reader, domainName string) ([]importRow, error) {
+	in := csv.NewReader(r)
+	in.FieldsPerRecord = -1
+
+	header, err := in.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("line 1: missing header, expected columns %s", strings.Join(recordCSVColumns, ","))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !slices.Contains(recordCSVColumns, name) {
+			return nil, fmt.Errorf("line 1: unknown column %q, expected %s", name, strings.Join(recordCSVColumns, ","))
+		}
+		columns[name] = i
+	}
+	for _, required := range []string{"name", "type", "data"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("line 1: missing column %q", required)
+		}
+	}
+
+	var rows []importRow
+	var errs []error
+	for {
+		fields, err := in.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := in.FieldPos(0)
+
+		if len(fields) != len(header) {
+			errs = append(errs, fmt.Errorf("line %d: got %d fields, expected %d", line, len(fields), len(header)))
+			continue
+		}
+		field := func(name string) string {
+			if i, ok := columns[name]; ok {
+				return strings.TrimSpace(fields[i])
+			}
+			return ""
+		}
+
+		row, err := parseImportRow(field, domainName)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("line %d: %w", line, err))
+			continue
+		}
+		row.line = line
+		_, hasComment := columns["comment"]
+		row.clearComment = hasComment && row.opts.Comment == ""
+		rows = append(rows, row)
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return rows, nil
+}
+
+func parseImportRow(field func(string) string, domainName string) (importRow, error) {
+	row := importRow{
+		id: field("id"),
+		opts: records.CreateOpts{
+			Name:    strings.TrimSuffix(field("name"), "."),
+			Type:    strings.ToUpper(field("type")),
+			Data:    field("data"),
+			Comment: field("comment"),
+		},
+	}
+
+	name := normalizeDomainName(row.opts.Name)
+	domain := normalizeDomainName(domainName)
+	switch {
+	case name == "":
+		return row, fmt.Errorf("name is empty")
+	case name != domain && !strings.HasSuffix(name, "."+domain):
+		return row, fmt.Errorf("name %s is not in %s", row.opts.Name, domainName)
+	case !slices.Contains(importTypes, row.opts.Type):
+		return row, fmt.Errorf("unsupported type %q, expected one of %s", field("type"), strings.Join(importTypes, ", "))
+	case row.opts.Data == "":
+		return row, fmt.Errorf("data is empty")
+	}
+
+	ip := net.ParseIP(row.opts.Data)
+	switch row.opts.Type {
+	case "A":
+		if ip == nil || ip.To4() == nil {
+			return row, fmt.Errorf("data %q is not an IPv4 address", row.opts.Data)
+		}
+	case "AAAA":
+		if ip == nil || ip.To4() != nil {
+			return row, fmt.Errorf("data %q is not an IPv6 address", row.opts.Data)
+		}
+	}
+
+	if ttl := field("ttl"); ttl != "" {
+		n, err := strconv.ParseUint(ttl, 10, 32)
+		if err != nil {
+			return row, fmt.Errorf("ttl %q is not a number of seconds", ttl)
+		}
+		row.opts.TTL = uint(n)
+	}
+
+	priority := field("priority")
+	if priority != "" {
+		n, err := strconv.ParseUint(priority, 10, 16)
+		if err != nil {
+			return row, fmt.Errorf("priority %q is not a number", priority)
+		}
+		row.opts.Priority = uint(n)
+	} else if row.opts.Type == "MX" || row.opts.Type == "SRV" {
+		return row, fmt.Errorf("%s records need a priority", row.opts.Type)
+	}
+
+	return row, nil
+}
+
+// checkImportUpdates checks that every row with an ID names one of the
+// existing records, and keeps its type, since the API cannot change it.
+func checkImportUpdates(rows []importRow, existing []records.RecordList) error {
+	types := map[string]string{}
+	for _, record := range existing {
+		types[record.ID] = record.Type
+	}
+
+	var errs []error
+	for _, row := range rows {
+		if row.id == "" {
+			continue
+		}
+		recordType, ok := types[row.id]
+		switch {
+		case !ok:
+			errs = append(errs, fmt.Errorf("line %d: no record %s in the domain", row.line, row.id))
+		case !strings.EqualFold(recordType, row.opts.Type):
+			errs = append(errs, fmt.Errorf("line %d: record %s is %s, and the type of a record cannot be changed to %s", row.line, row.id, recordType, row.opts.Type))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// describeLines names the lines of rows for messages, such as
+// "lines 2, 3, 5".
+func describeLines(rows []importRow) string {
+	lines := make([]string, len(rows))
+	for i, row := range rows {
+		lines[i] = strconv.Itoa(row.line)
+	}
+	if len(lines) == 1 {
+		return "line " + lines[0]
+	}
+	return "lines " + strings.Join(lines, ", ")
+}
+
+// importRecords creates the rows without an ID, records.MaxCreateMany at a
+// time, and updates the rows with one, a few at a time. A failure does not
+// stop the rest: every error names the lines that were not applied.
+func importRecords(ctx context.Context, service *gophercloud.ServiceClient, domID string, rows []importRow, timeout time.Duration) (created int, updated int, err error) {
+	var creates, updates []importRow
+	for _, row := range rows {
+		if row.id == "" {
+			creates = append(creates, row)
+		} else {
+			updates = append(updates, row)
+		}
+	}
+
+	var (
+		mu   sync.Mutex
+		errs []error
+	)
+	for batch := range slices.Chunk(creates, records.MaxCreateMany) {
+		opts := make([]records.CreateOpts, len(batch))
+		for i, row := range batch {
+			opts[i] = row.opts
+		}
+		batchCtx, cancel := context.WithTimeout(ctx, timeout)
+		recordList, err := records.CreateMany(batchCtx, service, domID, opts).Extract()
+		cancel()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: not created: %w", describeLines(batch), err))
+			continue
+		}
+		created += len(recordList)
+	}
+
+	forEachConcurrently(len(updates), 4, func(i int) {
+		row := updates[i]
+		opts := records.UpdateOpts{
+			Name:         row.opts.Name,
+			Data:         row.opts.Data,
+			TTL:          row.opts.TTL,
+			Comment:      row.opts.Comment,
+			Priority:     row.opts.Priority,
+			ClearComment: row.clearComment,
+		}
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		err := records.Update(ctx, service, domID, &records.RecordShow{ID: row.id}, opts).ExtractErr()
+
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("line %d: record %s not updated: %w", row.line, row.id, err))
+			return
+		}
+		updated++
+	})
+	return created, updated, errors.Join(errs...)
+}
+
+func newRecordExportCmd(app *cliApp) *cobra.Command {
+	return &cobra.Command{
+		Use:   "export DOMID",
+		Short: "Export a domain's records as CSV",
+		Long: strings.Join([]string{
+			"Export a domain's records with the columns " + strings.Join(recordCSVColumns, ", ") + ",",
+			"as 'record import' reads them. CSV is written unless --format asks for",
+			"another format than table or csv. Use it rather than 'record list --format",
+			"csv' for files to edit and import again: its columns are always the ones",
+			"'record import' reads, and the id column makes the import update the",
+			"records instead of adding copies.",
+		}, "\n"),
+		Args:              exactArgsValidator(1, "clouddns record export DOMID", "DOMID"),
+		ValidArgsFunction: app.completeArgs(domainIDCompleter),
+		Example: strings.Join([]string{
+			"  clouddns record export example.com > example.com.csv",
+			"  clouddns record export <domain-id> --format csv",
+		}, "\n"),
+		RunE: func(_ *cobra.Command, args []string) error {
+			return app.withService(func(ctx context.Context, service *gophercloud.ServiceClient) error {
+				domID, err := resolveDomainID(ctx, service, args[0])
+				if err != nil {
+					return err
+				}
+				recordList, err := listAllRecords(ctx, service, domID, nil)
+				if err != nil {
+					return err
+				}
+
+				if app.format != "table" && app.format != "csv" {
+					return printFormatted(app.format, recordList)
+				}
+				return writeRecordCSV(os.Stdout, recordList)
+			})
+		},
+	}
+}
+
+func newRecordImportCmd(app *cliApp) *cobra.Command {
+	var override bool
+	importCmd := &cobra.Command{
+		Use:   "import DOMID FILE",
+		Short: "Create and update records from a CSV file",
+		Long: strings.Join([]string{
+			"Create and update a domain's records from a CSV file with a header row. The",
+			"columns are " + strings.Join(recordCSVColumns, ", ") + "; name, type and data are",
+			"required. Rows with an id update that record, whose type cannot change, and",
+			"the others are created; an empty comment removes the record's comment.",
+			"Every row is checked first, and nothing is changed if any is wrong. FILE -",
+			"reads standard input. Protected domains are only imported into with",
+			"--override-protection.",
+		}, "\n"),
+		Args:              exactArgsValidator(2, "clouddns record import DOMID FILE", "DOMID and FILE"),
+		ValidArgsFunction: app.completeArgs(domainIDCompleter),
+		Example: strings.Join([]string{
+			"  clouddns record import example.com records.csv",
+			"  clouddns record export example.com > records.csv  # edit, then",
+			"  clouddns record import example.com records.csv",
+		}, "\n"),
+		RunE: func(_ *cobra.Command, args []string) error {
+			in := io.Reader(os.Stdin)
+			if args[1] != "-" {
+				f, err := os.Open(args[1])
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				in = f
+			}
+
+			// a large file can take longer than --timeout to import, so it
+			// bounds the lookups and each batch and update instead
+			return app.withLongRunningService(func(ctx context.Context, service *gophercloud.ServiceClient) error {
+				lookupCtx, cancel := context.WithTimeout(ctx, app.operationTimeout())
+				defer cancel()
+
+				domID, err := resolveDomainID(lookupCtx, service, args[0])
+				if err != nil {
+					return err
+				}
+				domain, err := domains.Get(lookupCtx, service, domID).Extract()
+				if err != nil {
+					return err
+				}
+				if err := app.checkProtected(domain.Name, override); err != nil {
+					return err
+				}
+
+				rows, err := readRecordCSV(in, domain.Name)
+				if err != nil {
+					return fmt.Errorf("%s: nothing imported:\n%w", args[1], err)
+				}
+				existing, err := listAllRecords(lookupCtx, service, domID, nil)
+				if err != nil {
+					return err
+				}
+				if err := checkImportUpdates(rows, existing); err != nil {
+					return fmt.Errorf("%s: nothing imported:\n%w", args[1], err)
+				}
+
+				created, updated, err := importRecords(ctx, service, domID, rows, app.operationTimeout())
+				fmt.Printf("%d records created, %d records updated\n", created, updated)
+				return err
+			})
+		},
+	}
+	importCmd.Flags().BoolVar(&override, "override-protection", false, "import even if the domain is protected")
+	return importCmd
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rackerlabs/goclouddns/domains"
+	"github.com/rackerlabs/goclouddns/fakedns"
+	"github.com/rackerlabs/goclouddns/records"
+)
+
+func TestRecordExportAndImport(t *testing.T) {
+	server := fakedns.NewServer()
+	defer server.Close()
+	domID := server.AddDomain(domains.CreateOpts{Name: "example.com", Email: "admin@example.com"})
+	recID, err := server.AddRecord(domID, records.CreateOpts{Name: "www.example.com", Type: "A", Data: "10.5.19.11", TTL: 300, Comment: "web"})
+	if err != nil {
+		t.Fatalf("AddRecord() returned error: %v", err)
+	}
+
+	output := captureStdout(t, func() {
+		if err := runAgainst(server, "record", "export", "example.com"); err != nil {
+			t.Fatalf("Execute() returned error: %v", err)
+		}
+	})
+	want := "id,name,type,data,ttl,priority,comment\n" + recID + ",www.example.com,A,10.5.19.11,300,,web\n"
+	if output != want {
+		t.Fatalf("unexpected export:\n%s\nwant:\n%s", output, want)
+	}
+
+	edited := strings.Replace(output, "10.5.19.11", "10.5.19.12", 1) +
+		",api.example.com,A,10.5.19.13,600,,\n" +
+		",example.com,MX,mail.example.com,,10,mail\n"
+	path := filepath.Join(t.TempDir(), "records.csv")
+	if err := os.WriteFile(path, []byte(edited), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	output = captureStdout(t, func() {
+		if err := runAgainst(server, "record", "import", domID, path); err != nil {
+			t.Fatalf("Execute() returned error: %v", err)
+		}
+	})
+	if output != "2 records created, 1 records updated\n" {
+		t.Fatalf("unexpected output %q", output)
+	}
+	if record, _ := server.Record(domID, recID); record.Data != "10.5.19.12" {
+		t.Fatalf("expected the record with an id to be updated, got %+v", record)
+	}
+	found, err := records.FindByOpts(t.Context(), server.ServiceClient(), domID, records.ListOpts{Type: "MX"})
+	if err != nil || len(found) != 1 || found[0].Priority != 10 {
+		t.Fatalf("expected the MX record to be created, got %+v, %v", found, err)
+	}
+}
+
+func TestReadRecordCSVReportsEveryLine(t *testing.T) {
+	input := strings.Join([]string{
+		"Name,Type,Data,TTL,Priority",
+		"www.example.com,A,10.5.19.11,300,",
+		"www.example.org,A,10.5.19.12,300,",
+		"ftp.example.com,A,not-an-ip,300,",
+		"example.com,MX,mail.example.com,,",
+		"txt.example.com,TXT,hello,soon,",
+		"bad.example.com,BOGUS,x,,",
+		"short.example.com,A",
+		"",
+	}, "\n")
+
+	_, err := readRecordCSV(strings.NewReader(input), "example.com")
+	if err == nil {
+		t.Fatal("expected validation errors")
+	}
+	for _, want := range []string{
+		"line 3: name www.example.org is not in example.com",
+		`line 4: data "not-an-ip" is not an IPv4 address`,
+		"line 5: MX records need a priority",
+		`line 6: ttl "soon" is not a number of seconds`,
+		`line 7: unsupported type "BOGUS"`,
+		"line 8: got 2 fields, expected 5",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in errors, got:\n%v", want, err)
+		}
+	}
+	if strings.Contains(err.Error(), "line 2") {
+		t.Errorf("expected line 2 to be valid, got:\n%v", err)
+	}
+
+	_, err = readRecordCSV(strings.NewReader("name,type,value\n"), "example.com")
+	if err == nil || !strings.Contains(err.Error(), `line 1: unknown column "value"`) {
+		t.Fatalf("expected unknown column error, got %v", err)
+	}
+}
+
+func TestRecordImportUpdates(t *testing.T) {
+	server := fakedns.NewServer()
+	defer server.Close()
+	domID := server.AddDomain(domains.CreateOpts{Name: "example.com", Email: "admin@example.com"})
+	recID, err := server.AddRecord(domID, records.CreateOpts{Name: "www.example.com", Type: "A", Data: "10.5.19.11", TTL: 300, Comment: "web"})
+	if err != nil {
+		t.Fatalf("AddRecord() returned error: %v", err)
+	}
+
+	runImport := func(csv string) error {
+		path := filepath.Join(t.TempDir(), "records.csv")
+		if err := os.WriteFile(path, []byte(csv), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		var err error
+		captureStdout(t, func() { err = runAgainst(server, "record", "import", domID, path) })
+		return err
+	}
+
+	err = runImport("id,name,type,data\n" + recID + ",www.example.com,CNAME,web.example.net\n")
+	if err == nil || !strings.Contains(err.Error(), "line 2: record "+recID+" is A, and the type of a record cannot be changed to CNAME") {
+		t.Fatalf("expected type change error, got %v", err)
+	}
+	err = runImport("id,name,type,data\nA-missing,www.example.com,A,10.5.19.12\n")
+	if err == nil || !strings.Contains(err.Error(), "line 2: no record A-missing") {
+		t.Fatalf("expected missing record error, got %v", err)
+	}
+	if record, _ := server.Record(domID, recID); record.Type != "A" || record.Data != "10.5.19.11" {
+		t.Fatalf("expected nothing to be imported, got %+v", record)
+	}
+
+	// without a comment column the comment stays, an empty cell clears it
+	if err := runImport("id,name,type,data\n" + recID + ",www.example.com,A,10.5.19.12\n"); err != nil {
+		t.Fatalf("import returned error: %v", err)
+	}
+	if record, _ := server.Record(domID, recID); record.Comment != "web" {
+		t.Fatalf("expected the comment to be kept, got %+v", record)
+	}
+	if err := runImport("id,name,type,data,comment\n" + recID + ",www.example.com,A,10.5.19.12,\n"); err != nil {
+		t.Fatalf("import returned error: %v", err)
+	}
+	if record, _ := server.Record(domID, recID); record.Comment != "" {
+		t.Fatalf("expected the comment to be cleared, got %+v", record)
+	}
+
+	// a failed create batch still lets the updates through
+	server.FailNextJob("quota exceeded")
+	err = runImport("id,name,type,data\n,api.example.com,A,10.5.19.13\n" + recID + ",www.example.com,A,10.5.19.14\n")
+	if err == nil || !strings.Contains(err.Error(), "line 2: not created") {
+		t.Fatalf("expected the failed create to name its line, got %v", err)
+	}
+	if record, _ := server.Record(domID, recID); record.Data != "10.5.19.14" {
+		t.Fatalf("expected the update to be applied, got %+v", record)
+	}
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gophercloud/gophercloud/v2"
+	"github.com/spf13/cobra"
+
+	"github.com/rackerlabs/goclouddns/records"
+)
+
+// replacement changes the data of one record. A list of them is also the
+// rollback file of a replace, read back with --rollback.
+type replacement struct {
+	DomainID   string `json:"domain_id"`
+	DomainName string `json:"domain_name"`
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	From       string `json:"from"`
+	To         string `json:"to"`
+}
+
+// reverse is the replacement undoing r.
+func (r replacement) reverse() replacement {
+	r.From, r.To = r.To, r.From
+	return r
+}
+
+// applyReplacements updates every record, limit at a time, giving each
+// timeout to be read and updated. A record whose data is no longer r.From
+// has been changed since and is left alone. It returns the replacements
+// made, in their original order, and every failure.
+func applyReplacements(ctx context.Context, service *gophercloud.ServiceClient, replacements []replacement, limit int, timeout time.Duration) ([]replacement, error) {
+	var (
+		mu   sync.Mutex
+		done = make([]bool, len(replacements))
+		errs []error
+	)
+	forEachConcurrently(len(replacements), limit, func(i int) {
+		r := replacements[i]
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		err := applyReplacement(ctx, service, r)
+
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s %s in %s: %w", r.Name, r.Type, r.DomainName, err))
+			return
+		}
+		done[i] = true
+	})
+
+	var updated []replacement
+	for i, r := range replacements {
+		if done[i] {
+			updated = append(updated, r)
+		}
+	}
+	return updated, errors.Join(errs...)
+}
+
+func applyReplacement(ctx context.Context, service *gophercloud.ServiceClient, r replacement) error {
+	record, err := records.Get(ctx, service, r.DomainID, r.ID).Extract()
+	if err != nil {
+		return err
+	}
+	if record.Data != r.From {
+		return fmt.Errorf("data is now %s, expected %s", record.Data, r.From)
+	}
+
+	opts := records.UpdateOpts{Name: record.Name, Data: r.To}
+	return records.Update(ctx, service, r.DomainID, record, opts).ExtractErr()
+}
+
+func readRollbackFile(path string) ([]replacement, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var replacements []replacement
+	if err := json.Unmarshal(data, &replacements); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	return replacements, nil
+}
+
+func writeRollbackFile(path string, replacements []replacement) error {
+	rollback := make([]replacement, len(replacements))
+	for i, r := range replacements {
+		rollback[i] = r.reverse()
+	}
+
+	data, err := json.MarshalIndent(rollback, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o600)
+}
+
+func printReplacements(format string, replacements []replacement) error {
+	if format != "table" {
+		return printFormatted(format, replacements)
+	}
+
+	w := newTabWriter()
+	fmt.Fprintln(w, "DOMAIN\tRECORD ID\tNAME\tTYPE\tFROM\tTO")
+	for _, r := range replacements {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", r.DomainName, r.ID, r.Name, r.Type, r.From, r.To)
+	}
+	return w.Flush()
+}
+
+func newRecordReplaceCmd(app *cliApp) *cobra.Command {
+	var search recordSearch
+	var from, to, rollbackFile, rollback string
+	var yes, override bool
+	replaceCmd := &cobra.Command{
+		Use:   "replace",
+		Short: "Change record data across all domains",
+		Long: strings.Join([]string{
+			"Change the data of every record whose data is --from to --to, across all",
+			"domains or those given with --domain. The changes are shown and have to be",
+			"confirmed, unless --yes is given, and are then made --concurrency at a time.",
+			"",
+			"Before changing anything, a rollback file is written that reverses the",
+			"change when given to --rollback. Once the changes are made, it is rewritten",
+			"with only the records actually changed. Records changed again since are left",
+			"alone.",
+			"Records of protected domains are only changed with --override-protection.",
+		}, "\n"),
+		Args: noArgsValidator("clouddns record replace"),
+		Example: strings.Join([]string{
+			"  clouddns record replace --from 10.5.19.11 --to 10.8.0.11 --type A",
+			"  clouddns record replace --from 10.5.19.11 --to 10.8.0.11 --domain example.com --domain example.org",
+			"  clouddns record replace --rollback clouddns-rollback-20260102T150405.json",
+		}, "\n"),
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if rollback != "" {
+				if cmd.Flags().Changed("from") || cmd.Flags().Changed("to") {
+					return fmt.Errorf("--rollback cannot be combined with --from or --to")
+				}
+			} else if from == "" || to == "" {
+				return fmt.Errorf("specify --from and --to, or --rollback")
+			}
+
+			// changing many records can take longer than --timeout, so it
+			// bounds the search and each record instead
+			return app.withLongRunningService(func(ctx context.Context, service *gophercloud.ServiceClient) error {
+				var replacements []replacement
+				if rollback != "" {
+					var err error
+					if replacements, err = readRollbackFile(rollback); err != nil {
+						return err
+					}
+				} else {
+					search.data = from
+					searchCtx, cancel := context.WithTimeout(ctx, app.operationTimeout())
+					found, err := search.run(searchCtx, service)
+					cancel()
+					if err != nil {
+						return err
+					}
+					for _, m := range found {
+						replacements = append(replacements, replacement{
+							DomainID:   m.DomainID,
+							DomainName: m.DomainName,
+							ID:         m.ID,
+							Name:       m.Name,
+							Type:       m.Type,
+							From:       m.Data,
+							To:         to,
+						})
+					}
+				}
+				if len(replacements) == 0 {
+					if rollback != "" {
+						return fmt.Errorf("%s lists no records", rollback)
+					}
+					return fmt.Errorf("no records have data %s", from)
+				}
+
+				checked := map[string]bool{}
+				for _, r := range replacements {
+					if checked[r.DomainID] {
+						continue
+					}
+					checked[r.DomainID] = true
+					if err := app.checkProtected(r.DomainName, override); err != nil {
+						return err
+					}
+				}
+
+				if err := printReplacements(app.format, replacements); err != nil {
+					return err
+				}
+				if !yes && !app.dryRun {
+					ok, err := app.confirm(fmt.Sprintf("Change %d records?", len(replacements)))
+					if err != nil {
+						return err
+					}
+					if !ok {
+						return fmt.Errorf("nothing changed: answer yes or pass --yes to change without asking")
+					}
+				}
+
+				if rollback == "" && !app.dryRun {
+					if rollbackFile == "" {
+						rollbackFile = fmt.Sprintf("clouddns-rollback-%s.json", time.Now().Format("20060102T150405"))
+					}
+					if err := writeRollbackFile(rollbackFile, replacements); err != nil {
+						return fmt.Errorf("writing rollback file: %w", err)
+					}
+					fmt.Fprintf(os.Stderr, "rollback file: %s\n", rollbackFile)
+				}
+
+				updated, err := applyReplacements(ctx, service, replacements, search.concurrency, app.operationTimeout())
+				fmt.Printf("%d of %d records updated\n", len(updated), len(replacements))
+
+				// the rollback file was written before any change was made;
+				// it must not undo records that were never changed
+				if rollback == "" && !app.dryRun && len(updated) < len(replacements) {
+					if len(updated) == 0 {
+						return errors.Join(err, os.Remove(rollbackFile))
+					}
+					if werr := writeRollbackFile(rollbackFile, updated); werr != nil {
+						return errors.Join(err, fmt.Errorf("writing rollback file: %w", werr))
+					}
+				}
+				return err
+			})
+		},
+	}
+	replaceCmd.Flags().StringVar(&from, "from", "", "data of the records to change")
+	replaceCmd.Flags().StringVar(&to, "to", "", "new data for the records")
+	replaceCmd.Flags().StringVar(&search.recordType, "type", "", "only change records of this type")
+	replaceCmd.Flags().StringSliceVar(&search.domains, "domain", nil, "only change records of these domains, by name or ID (repeatable)")
+	replaceCmd.Flags().IntVar(&search.concurrency, "concurrency", 4, "how many domains to search and records to update at once")
+	replaceCmd.Flags().StringVar(&rollbackFile, "rollback-file", "", "where to write the rollback file (default clouddns-rollback-TIME.json)")
+	replaceCmd.Flags().StringVar(&rollback, "rollback", "", "undo a replace with the rollback file it wrote")
+	replaceCmd.Flags().BoolVarP(&yes, "yes", "y", false, "change the records without asking")
+	replaceCmd.Flags().BoolVar(&override, "override-protection", false, "change records even in protected domains")
+	return replaceCmd
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rackerlabs/goclouddns/domains"
+	"github.com/rackerlabs/goclouddns/fakedns"
+	"github.com/rackerlabs/goclouddns/records"
+)
+
+func TestRecordReplaceAndRollback(t *testing.T) {
+	server := fakedns.NewServer()
+	defer server.Close()
+
+	type added struct{ domID, recID string }
+	var old, other []added
+	for _, name := range []string{"example.com", "example.org", "example.net"} {
+		domID := server.AddDomain(domains.CreateOpts{Name: name, Email: "admin@" + name})
+		recID, err := server.AddRecord(domID, records.CreateOpts{Name: "www." + name, Type: "A", Data: "10.5.19.11"})
+		if err != nil {
+			t.Fatalf("AddRecord() returned error: %v", err)
+		}
+		if name == "example.net" {
+			other = append(other, added{domID, recID})
+		} else {
+			old = append(old, added{domID, recID})
+		}
+	}
+
+	rollback := filepath.Join(t.TempDir(), "rollback.json")
+	args := []string{"record", "replace", "--from", "10.5.19.11", "--to", "10.8.0.11", "--type", "A",
+		"--domain", "example.com", "--domain", "example.org.", "--rollback-file", rollback}
+
+	err := runAgainst(server, args...)
+	if err == nil || !strings.Contains(err.Error(), "nothing changed") {
+		t.Fatalf("expected unconfirmed replace error, got %v", err)
+	}
+
+	output := captureStdout(t, func() {
+		if err := runWithStdin(server, "y\n", args...); err != nil {
+			t.Fatalf("Execute() returned error: %v", err)
+		}
+	})
+	if !strings.Contains(output, "www.example.org") || !strings.Contains(output, "2 of 2 records updated") {
+		t.Fatalf("unexpected output %q", output)
+	}
+	for _, r := range old {
+		if record, _ := server.Record(r.domID, r.recID); record.Data != "10.8.0.11" {
+			t.Fatalf("expected %s to be replaced, got %s", record.Name, record.Data)
+		}
+	}
+	if record, _ := server.Record(other[0].domID, other[0].recID); record.Data != "10.5.19.11" {
+		t.Fatalf("expected the record outside --domain to be kept, got %s", record.Data)
+	}
+
+	captureStdout(t, func() {
+		if err := runAgainst(server, "record", "replace", "--rollback", rollback, "--yes"); err != nil {
+			t.Fatalf("Execute() returned error: %v", err)
+		}
+	})
+	for _, r := range old {
+		if record, _ := server.Record(r.domID, r.recID); record.Data != "10.5.19.11" {
+			t.Fatalf("expected %s to be rolled back, got %s", record.Name, record.Data)
+		}
+	}
+}
+
+func TestRecordReplaceSkipsChangedRecords(t *testing.T) {
+	server := fakedns.NewServer()
+	defer server.Close()
+	domID := server.AddDomain(domains.CreateOpts{Name: "example.com", Email: "admin@example.com"})
+	recID, err := server.AddRecord(domID, records.CreateOpts{Name: "www.example.com", Type: "A", Data: "10.5.19.12"})
+	if err != nil {
+		t.Fatalf("AddRecord() returned error: %v", err)
+	}
+
+	updated, err := applyReplacements(t.Context(), server.ServiceClient(), []replacement{
+		{DomainID: domID, DomainName: "example.com", ID: recID, Name: "www.example.com", Type: "A", From: "10.5.19.11", To: "10.8.0.11"},
+	}, 2, time.Minute)
+	if len(updated) != 0 || err == nil || !strings.Contains(err.Error(), "data is now 10.5.19.12") {
+		t.Fatalf("expected the changed record to be skipped, got %+v, %v", updated, err)
+	}
+}
+
+func TestRecordReplaceRollbackListsOnlyUpdated(t *testing.T) {
+	server := fakedns.NewServer()
+	defer server.Close()
+	var recIDs []string
+	for _, name := range []string{"example.com", "example.org"} {
+		domID := server.AddDomain(domains.CreateOpts{Name: name, Email: "admin@" + name})
+		recID, err := server.AddRecord(domID, records.CreateOpts{Name: "www." + name, Type: "A", Data: "10.5.19.11"})
+		if err != nil {
+			t.Fatalf("AddRecord() returned error: %v", err)
+		}
+		recIDs = append(recIDs, recID)
+	}
+
+	// the update of www.example.com, made first, fails
+	server.FailNextJob("update failed")
+	rollback := filepath.Join(t.TempDir(), "rollback.json")
+	output := captureStdout(t, func() {
+		err := runAgainst(server, "record", "replace", "--from", "10.5.19.11", "--to", "10.8.0.11",
+			"--concurrency", "1", "--rollback-file", rollback, "--yes")
+		if err == nil {
+			t.Fatal("expected the failed update to be reported")
+		}
+	})
+	if !strings.Contains(output, "1 of 2 records updated") {
+		t.Fatalf("unexpected output %q", output)
+	}
+
+	replacements, err := readRollbackFile(rollback)
+	if err != nil {
+		t.Fatalf("readRollbackFile() returned error: %v", err)
+	}
+	if len(replacements) != 1 || replacements[0].ID != recIDs[1] || replacements[0].To != "10.5.19.11" {
+		t.Fatalf("expected only www.example.org in the rollback file, got %+v", replacements)
+	}
+}
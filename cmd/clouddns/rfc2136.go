@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gophercloud/gophercloud/v2"
+	"github.com/miekg/dns"
+	"github.com/spf13/cobra"
+
+	"github.com/rackerlabs/goclouddns/rfc2136"
+)
+
+func newRFC2136Cmd(app *cliApp) *cobra.Command {
+	rfc2136Cmd := &cobra.Command{
+		Use:   "rfc2136",
+		Short: "Accept RFC 2136 DNS UPDATE messages",
+	}
+
+	var listen string
+	var keys []string
+	var allowUnsigned bool
+	serveCmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Apply DNS UPDATE messages from nsupdate and DHCP servers to Cloud DNS",
+		Long: strings.Join([]string{
+			"Listen for DNS UPDATE messages over UDP and TCP and apply them to the",
+			"Cloud DNS domain named in their zone section. Updates must be signed with",
+			"one of the --tsig-key keys, given like nsupdate -y as [algorithm:]name:secret",
+			"with a base64 secret. The algorithm defaults to hmac-sha256.",
+		}, "\n"),
+		Args: noArgsValidator("clouddns rfc2136 serve"),
+		Example: strings.Join([]string{
+			"  clouddns rfc2136 serve --tsig-key dhcp-key:c2VjcmV0",
+			"  clouddns rfc2136 serve --listen 127.0.0.1:5353 --tsig-key hmac-sha512:dhcp-key:c2VjcmV0",
+		}, "\n"),
+		RunE: func(_ *cobra.Command, _ []string) error {
+			if len(keys) == 0 && !allowUnsigned {
+				return fmt.Errorf("at least one --tsig-key is required, or pass --allow-unsigned")
+			}
+
+			algorithms := map[string]string{}
+			secrets := map[string]string{}
+			for _, spec := range keys {
+				name, algorithm, secret, err := parseTSIGKey(spec)
+				if err != nil {
+					return err
+				}
+				algorithms[name] = algorithm
+				secrets[name] = secret
+			}
+
+			return app.withLongRunningService(func(ctx context.Context, service *gophercloud.ServiceClient) error {
+				handler := rfc2136.NewHandler(service)
+				handler.Keys = algorithms
+				handler.AllowUnsigned = allowUnsigned
+				handler.Timeout = app.operationTimeout()
+
+				return serveDNSUntilDone(ctx, []*dns.Server{
+					{Addr: listen, Net: "udp", Handler: handler, TsigSecret: secrets, MsgAcceptFunc: rfc2136.AcceptUpdate},
+					{Addr: listen, Net: "tcp", Handler: handler, TsigSecret: secrets, MsgAcceptFunc: rfc2136.AcceptUpdate},
+				})
+			})
+		},
+	}
+	serveCmd.Flags().StringVar(&listen, "listen", ":53", "address to listen on for UDP and TCP")
+	serveCmd.Flags().StringArrayVar(&keys, "tsig-key", nil, "TSIG key as [algorithm:]name:secret (repeatable)")
+	serveCmd.Flags().BoolVar(&allowUnsigned, "allow-unsigned", false, "accept updates without a TSIG signature")
+
+	rfc2136Cmd.AddCommand(serveCmd)
+	return rfc2136Cmd
+}
+
+// parseTSIGKey parses a key in nsupdate -y form, returning the fully
+// qualified key name, algorithm and base64 secret.
+func parseTSIGKey(spec string) (name string, algorithm string, secret string, err error) {
+	parts := strings.Split(spec, ":")
+	switch len(parts) {
+	case 2:
+		algorithm, name, secret = dns.HmacSHA256, parts[0], parts[1]
+	case 3:
+		algorithm, name, secret = dns.Fqdn(strings.ToLower(parts[0])), parts[1], parts[2]
+	default:
+		return "", "", "", fmt.Errorf("invalid --tsig-key %q: expected [algorithm:]name:secret", spec)
+	}
+
+	switch algorithm {
+	case dns.HmacSHA1, dns.HmacSHA224, dns.HmacSHA256, dns.HmacSHA384, dns.HmacSHA512:
+	default:
+		return "", "", "", fmt.Errorf("invalid --tsig-key %q: unsupported algorithm %s", spec, strings.TrimSuffix(algorithm, "."))
+	}
+	if name == "" {
+		return "", "", "", fmt.Errorf("invalid --tsig-key %q: missing key name", spec)
+	}
+	if _, err := base64.StdEncoding.DecodeString(secret); err != nil || secret == "" {
+		return "", "", "", fmt.Errorf("invalid --tsig-key %q: secret must be base64", spec)
+	}
+	return dns.CanonicalName(name), algorithm, secret, nil
+}
+
+// serveDNSUntilDone runs servers until ctx is cancelled or one of them
+// fails, then shuts them all down.
+func serveDNSUntilDone(ctx context.Context, servers []*dns.Server) error {
+	errc := make(chan error, len(servers))
+	for _, server := range servers {
+		go func() {
+			fmt.Fprintf(os.Stderr, "listening on %s/%s\n", server.Addr, server.Net)
+			errc <- server.ListenAndServe()
+		}()
+	}
+
+	var err error
+	select {
+	case err = <-errc:
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	for _, server := range servers {
+		if shutdownErr := server.ShutdownContext(shutdownCtx); shutdownErr != nil && err == nil {
+			err = shutdownErr
+		}
+	}
+	return err
+}
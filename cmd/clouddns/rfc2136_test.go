@@ -0,0 +1,36 @@
+package main
+
+import "testing"
+
+func TestParseTSIGKey(t *testing.T) {
+	tests := []struct {
+		spec      string
+		name      string
+		algorithm string
+		wantErr   bool
+	}{
+		{spec: "dhcp-key:c2VjcmV0", name: "dhcp-key.", algorithm: "hmac-sha256."},
+		{spec: "HMAC-SHA512:Dhcp-Key.:c2VjcmV0", name: "dhcp-key.", algorithm: "hmac-sha512."},
+		{spec: "hmac-md5:dhcp-key:c2VjcmV0", wantErr: true},
+		{spec: "dhcp-key:not base64", wantErr: true},
+		{spec: "c2VjcmV0", wantErr: true},
+		{spec: ":c2VjcmV0", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		name, algorithm, _, err := parseTSIGKey(tt.spec)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseTSIGKey(%q) expected an error", tt.spec)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseTSIGKey(%q) returned error: %v", tt.spec, err)
+			continue
+		}
+		if name != tt.name || algorithm != tt.algorithm {
+			t.Errorf("parseTSIGKey(%q) = %q, %q, want %q, %q", tt.spec, name, algorithm, tt.name, tt.algorithm)
+		}
+	}
+}
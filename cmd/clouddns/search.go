@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gophercloud/gophercloud/v2"
+	"github.com/spf13/cobra"
+
+	"github.com/rackerlabs/goclouddns/domains"
+	"github.com/rackerlabs/goclouddns/records"
+)
+
+// recordSearch selects records across every domain of the account.
+type recordSearch struct {
+	data       string
+	recordType string
+	nameRegex  *regexp.Regexp
+
+	// domains, when set, limits the search to the domains with these names
+	// or IDs.
+	domains []string
+
+	// concurrency bounds how many domains are listed at once.
+	concurrency int
+}
+
+// searchMatch is a record found by a search, with its domain.
+type searchMatch struct {
+	DomainID   string
+	DomainName string
+	ID         string
+	Name       string
+	Type       string
+	Data       string
+	TTL        uint
+}
+
+func (s *recordSearch) matches(record records.RecordList) bool {
+	return (s.data == "" || strings.EqualFold(record.Data, s.data)) &&
+		(s.recordType == "" || strings.EqualFold(record.Type, s.recordType)) &&
+		(s.nameRegex == nil || s.nameRegex.MatchString(record.Name))
+}
+
+// run lists the records of every domain, s.concurrency domains at a time,
+// and returns the matches ordered by domain and record name. The first
+// error stops the search.
+func (s *recordSearch) run(ctx context.Context, service *gophercloud.ServiceClient) ([]searchMatch, error) {
+	domainList, err := s.searchDomains(ctx, service)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu    sync.Mutex
+		found []searchMatch
+		errs  []error
+	)
+	forEachConcurrently(len(domainList), s.concurrency, func(i int) {
+		if ctx.Err() != nil {
+			return
+		}
+		domain := domainList[i]
+		matches, err := s.searchDomain(ctx, service, domain)
+
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			if ctx.Err() == nil {
+				errs = append(errs, fmt.Errorf("listing records of %s: %w", domain.Name, err))
+			}
+			cancel()
+			return
+		}
+		found = append(found, matches...)
+	})
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].DomainName != found[j].DomainName {
+			return found[i].DomainName < found[j].DomainName
+		}
+		if found[i].Name != found[j].Name {
+			return found[i].Name < found[j].Name
+		}
+		return found[i].ID < found[j].ID
+	})
+	return found, nil
+}
+
+// searchDomains returns the domains to search, checking that every domain
+// named by s.domains exists.
+func (s *recordSearch) searchDomains(ctx context.Context, service *gophercloud.ServiceClient) ([]domains.DomainList, error) {
+	domainList, err := listAllDomains(ctx, service, nil)
+	if err != nil || len(s.domains) == 0 {
+		return domainList, err
+	}
+
+	var selected []domains.DomainList
+	for _, want := range s.domains {
+		n := len(selected)
+		for _, domain := range domainList {
+			if domain.ID == want || normalizeDomainName(domain.Name) == normalizeDomainName(want) {
+				selected = append(selected, domain)
+			}
+		}
+		if len(selected) == n {
+			return nil, fmt.Errorf("no domain named %s", want)
+		}
+	}
+	return selected, nil
+}
+
+// forEachConcurrently calls fn with every index below n, running at most
+// limit calls at once, and returns when they are all done.
+func forEachConcurrently(n int, limit int, fn func(i int)) {
+	var wg sync.WaitGroup
+	workers := make(chan struct{}, max(limit, 1))
+	for i := range n {
+		wg.Add(1)
+		workers <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-workers }()
+			fn(i)
+		}()
+	}
+	wg.Wait()
+}
+
+func (s *recordSearch) searchDomain(ctx context.Context, service *gophercloud.ServiceClient, domain domains.DomainList) ([]searchMatch, error) {
+	// the API narrows the listing, the exact match is done here
+	recordList, err := listAllRecords(ctx, service, domain.ID, records.ListOpts{Type: s.recordType, Data: s.data})
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []searchMatch
+	for _, record := range recordList {
+		if !s.matches(record) {
+			continue
+		}
+		matches = append(matches, searchMatch{
+			DomainID:   domain.ID,
+			DomainName: domain.Name,
+			ID:         record.ID,
+			Name:       record.Name,
+			Type:       record.Type,
+			Data:       record.Data,
+			TTL:        record.TTL,
+		})
+	}
+	return matches, nil
+}
+
+func newRecordSearchCmd(app *cliApp) *cobra.Command {
+	var search recordSearch
+	var nameRegex string
+	searchCmd := &cobra.Command{
+		Use:   "search",
+		Short: "Find records across all domains",
+		Long: strings.Join([]string{
+			"Find records across every domain of the account by their data, type or name.",
+			"Data and type are matched exactly, without regard to case; --name-regex is",
+			"a Go regular expression matched against the fully qualified record name.",
+		}, "\n"),
+		Args: noArgsValidator("clouddns record search"),
+		Example: strings.Join([]string{
+			"  clouddns record search --data 10.5.19.11",
+			"  clouddns record search --data lb.example.net --type CNAME",
+			"  clouddns record search --type MX --name-regex '^example\\.(com|org)$'",
+		}, "\n"),
+		RunE: func(_ *cobra.Command, _ []string) error {
+			if search.data == "" && search.recordType == "" && nameRegex == "" {
+				return fmt.Errorf("specify at least one of --data, --type, --name-regex")
+			}
+			if nameRegex != "" {
+				re, err := regexp.Compile(nameRegex)
+				if err != nil {
+					return fmt.Errorf("invalid --name-regex: %w", err)
+				}
+				search.nameRegex = re
+			}
+
+			return app.withService(func(ctx context.Context, service *gophercloud.ServiceClient) error {
+				found, err := search.run(ctx, service)
+				if err != nil {
+					return err
+				}
+				return printSearchMatches(app.format, found)
+			})
+		},
+	}
+	searchCmd.Flags().StringVar(&search.data, "data", "", "find records with this data")
+	searchCmd.Flags().StringVar(&search.recordType, "type", "", "find records of this type")
+	searchCmd.Flags().StringVar(&nameRegex, "name-regex", "", "find records whose name matches this regular expression")
+	searchCmd.Flags().StringSliceVar(&search.domains, "domain", nil, "only search these domains, by name or ID (repeatable)")
+	searchCmd.Flags().IntVar(&search.concurrency, "concurrency", 4, "how many domains to search at once")
+	return searchCmd
+}
+
+func printSearchMatches(format string, found []searchMatch) error {
+	if format != "table" {
+		return printFormatted(format, found)
+	}
+
+	w := newTabWriter()
+	fmt.Fprintln(w, "DOMAIN\tDOMAIN ID\tRECORD ID\tNAME\tTYPE\tDATA\tTTL")
+	for _, m := range found {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%d\n", m.DomainName, m.DomainID, m.ID, m.Name, m.Type, m.Data, m.TTL)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rackerlabs/goclouddns/domains"
+	"github.com/rackerlabs/goclouddns/fakedns"
+	"github.com/rackerlabs/goclouddns/records"
+)
+
+func TestRecordSearchAcrossDomains(t *testing.T) {
+	server := fakedns.NewServer()
+	defer server.Close()
+	for _, name := range []string{"example.com", "example.org", "example.net"} {
+		domID := server.AddDomain(domains.CreateOpts{Name: name, Email: "admin@" + name})
+		for _, opts := range []records.CreateOpts{
+			{Name: "www." + name, Type: "A", Data: "10.5.19.11"},
+			{Name: "mail." + name, Type: "A", Data: "10.5.19.12"},
+		} {
+			if _, err := server.AddRecord(domID, opts); err != nil {
+				t.Fatalf("AddRecord() returned error: %v", err)
+			}
+		}
+	}
+
+	output := captureStdout(t, func() {
+		err := runAgainst(server, "record", "search", "--data", "10.5.19.11", "--name-regex", `\.(com|org)$`, "--concurrency", "2", "--format", "jsonpath={[*].Name}")
+		if err != nil {
+			t.Fatalf("Execute() returned error: %v", err)
+		}
+	})
+	if output != "www.example.com www.example.org\n" {
+		t.Fatalf("unexpected matches %q", output)
+	}
+
+	output = captureStdout(t, func() {
+		if err := runAgainst(server, "record", "search", "--type", "a", "--data", "10.5.19.12"); err != nil {
+			t.Fatalf("Execute() returned error: %v", err)
+		}
+	})
+	if strings.Count(output, "mail.example.") != 3 || !strings.HasPrefix(output, "DOMAIN") {
+		t.Fatalf("unexpected table %q", output)
+	}
+}
+
+func TestRecordSearchRequiresAFilter(t *testing.T) {
+	cmd := newRootCmd()
+	cmd.SetArgs([]string{"record", "search"})
+
+	err := cmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "specify at least one of --data, --type, --name-regex") {
+		t.Fatalf("expected missing filter error, got %v", err)
+	}
+}
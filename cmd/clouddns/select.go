@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/gophercloud/gophercloud/v2"
+	"github.com/spf13/cobra"
+
+	"github.com/rackerlabs/goclouddns/records"
+)
+
+// recordSelector picks the records a command acts on, either by the
+// record ID argument or by --name, --type and the data flag.
+type recordSelector struct {
+	name       string
+	recordType string
+	data       string
+	all        bool
+
+	dataFlag string
+}
+
+// addFlags adds the selector flags to cmd. dataFlag names the flag
+// matching record data, since update already uses --data for the new data.
+// verb, if set, is what --all does to every record matched; commands that
+// change nothing, such as show, leave it empty and get no --all.
+func (s *recordSelector) addFlags(cmd *cobra.Command, dataFlag string, verb string) {
+	s.dataFlag = dataFlag
+	cmd.Flags().StringVar(&s.name, "name", "", "select records by name instead of by ID")
+	cmd.Flags().StringVar(&s.recordType, "type", "", "with --name, only records of this type")
+	cmd.Flags().StringVar(&s.data, dataFlag, "", "with --name, only records with this data")
+	if verb != "" {
+		cmd.Flags().BoolVar(&s.all, "all", false, verb+" every record --name matches without asking")
+	}
+}
+
+func (s *recordSelector) selecting() bool {
+	return s.name != "" || s.recordType != "" || s.data != ""
+}
+
+// args validates DOMID and either ID or the selector flags.
+func (s *recordSelector) args(usage string) cobra.PositionalArgs {
+	return func(cmd *cobra.Command, args []string) error {
+		switch {
+		case len(args) == 0:
+			return friendlyUsageError(cmd, "missing required arguments: DOMID and ID", usage)
+		case len(args) > 2:
+			return friendlyUsageError(cmd, fmt.Sprintf("too many arguments: got %d, expected 2", len(args)), usage)
+		case len(args) == 2 && s.selecting():
+			return friendlyUsageError(cmd, "give either a record ID or --name, not both", usage)
+		case len(args) == 1 && s.name == "":
+			return friendlyUsageError(cmd, "missing required argument: ID or --name", usage)
+		default:
+			return nil
+		}
+	}
+}
+
+// describe names the selection for messages, such as
+// "www.example.com A".
+func (s *recordSelector) describe() string {
+	parts := []string{s.name}
+	if s.recordType != "" {
+		parts = append(parts, strings.ToUpper(s.recordType))
+	}
+	if s.data != "" {
+		parts = append(parts, s.data)
+	}
+	return strings.Join(parts, " ")
+}
+
+// find returns the records selected by the flags, or nil when a record ID
+// was given instead.
+func (s *recordSelector) find(ctx context.Context, service *gophercloud.ServiceClient, domID string, args []string) ([]records.RecordList, error) {
+	if len(args) == 2 {
+		return nil, nil
+	}
+
+	matches, err := records.FindByOpts(ctx, service, domID, records.ListOpts{Name: s.name, Type: s.recordType, Data: s.data})
+	if err != nil {
+		return nil, err
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("no records match %s", s.describe())
+	}
+	return matches, nil
+}
+
+// selectRecordIDs returns the IDs of the records a command changes. When the flags
+// select more than one record, they are listed and the user is asked to
+// confirm, unless --all was given.
+func (app *cliApp) selectRecordIDs(ctx context.Context, service *gophercloud.ServiceClient, domID string, args []string, s *recordSelector, verb string) ([]string, error) {
+	if len(args) == 2 {
+		return []string{args[1]}, nil
+	}
+
+	matches, err := s.find(ctx, service, domID, args)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(matches) > 1 && !s.all {
+		var b strings.Builder
+		fmt.Fprintf(&b, "%d records match %s:\n", len(matches), s.describe())
+		for _, record := range matches {
+			fmt.Fprintf(&b, "  %s  %s %s %s\n", record.ID, record.Name, record.Type, record.Data)
+		}
+		fmt.Fprintf(&b, "%s all %d?", verb, len(matches))
+
+		ok, err := app.confirm(b.String())
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, fmt.Errorf("%d records match %s: pass --all to %s them all", len(matches), s.describe(), strings.ToLower(verb))
+		}
+	}
+
+	ids := make([]string, 0, len(matches))
+	for _, record := range matches {
+		ids = append(ids, record.ID)
+	}
+	return ids, nil
+}
+
+// confirm asks prompt on stderr and reads a yes or no answer from stdin.
+// Anything but yes, including no input at all, is no.
+func (app *cliApp) confirm(prompt string) (bool, error) {
+	stdin := app.stdin
+	if stdin == nil {
+		stdin = os.Stdin
+	}
+
+	fmt.Fprintf(os.Stderr, "%s [y/N] ", prompt)
+	answer, err := bufio.NewReader(stdin).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return false, err
+	}
+	if errors.Is(err, io.EOF) && answer == "" {
+		fmt.Fprintln(os.Stderr)
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rackerlabs/goclouddns/domains"
+	"github.com/rackerlabs/goclouddns/fakedns"
+	"github.com/rackerlabs/goclouddns/records"
+)
+
+func addSelectRecords(t *testing.T, server *fakedns.Server) (string, []string) {
+	t.Helper()
+
+	domID := server.AddDomain(domains.CreateOpts{Name: "example.com", Email: "admin@example.com"})
+	var ids []string
+	for _, opts := range []records.CreateOpts{
+		{Name: "www.example.com", Type: "A", Data: "10.5.19.11"},
+		{Name: "www.example.com", Type: "A", Data: "10.5.19.12"},
+		{Name: "www.example.com", Type: "AAAA", Data: "2001:db8::1"},
+	} {
+		id, err := server.AddRecord(domID, opts)
+		if err != nil {
+			t.Fatalf("AddRecord() returned error: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	return domID, ids
+}
+
+func TestRecordDeleteByName(t *testing.T) {
+	server := fakedns.NewServer()
+	defer server.Close()
+	domID, ids := addSelectRecords(t, server)
+
+	captureStdout(t, func() {
+		if err := runAgainst(server, "record", "delete", "example.com", "--name", "www.example.com", "--type", "AAAA", "--yes"); err != nil {
+			t.Fatalf("Execute() returned error: %v", err)
+		}
+	})
+
+	if _, ok := server.Record(domID, ids[2]); ok {
+		t.Fatal("expected the AAAA record to be deleted")
+	}
+	if _, ok := server.Record(domID, ids[0]); !ok {
+		t.Fatal("expected the A records to be kept")
+	}
+}
+
+func TestRecordUpdateMultipleMatchesNeedsConfirmation(t *testing.T) {
+	server := fakedns.NewServer()
+	defer server.Close()
+	domID, ids := addSelectRecords(t, server)
+
+	err := runAgainst(server, "record", "update", domID, "--name", "www.example.com", "--type", "A", "--ttl", "600")
+	if err == nil || !strings.Contains(err.Error(), "2 records match www.example.com A: pass --all to update them all") {
+		t.Fatalf("expected multiple match error, got %v", err)
+	}
+	if record, _ := server.Record(domID, ids[0]); record.TTL == 600 {
+		t.Fatal("expected no record to be updated without confirmation")
+	}
+}
+
+func TestRecordDeleteMultipleMatchesNeedsConfirmation(t *testing.T) {
+	server := fakedns.NewServer()
+	defer server.Close()
+	domID, ids := addSelectRecords(t, server)
+
+	err := runAgainst(server, "record", "delete", domID, "--name", "www.example.com", "--type", "A")
+	if err == nil || !strings.Contains(err.Error(), "not deleted") {
+		t.Fatalf("expected unconfirmed delete error, got %v", err)
+	}
+	if _, ok := server.Record(domID, ids[0]); !ok {
+		t.Fatal("expected no record to be deleted without confirmation")
+	}
+
+	output := captureStdout(t, func() {
+		if err := runWithStdin(server, "y\n", "record", "delete", domID, "--name", "www.example.com", "--type", "A"); err != nil {
+			t.Fatalf("Execute() returned error: %v", err)
+		}
+	})
+	if !strings.Contains(output, "Successfully deleted 2 records") {
+		t.Fatalf("unexpected output %q", output)
+	}
+	for _, id := range ids[:2] {
+		if _, ok := server.Record(domID, id); ok {
+			t.Fatalf("expected record %s to be deleted", id)
+		}
+	}
+}
+
+func TestRecordUpdateAllMatches(t *testing.T) {
+	server := fakedns.NewServer()
+	defer server.Close()
+	domID, ids := addSelectRecords(t, server)
+
+	captureStdout(t, func() {
+		if err := runAgainst(server, "record", "update", domID, "--name", "www.example.com", "--ttl", "600", "--all"); err != nil {
+			t.Fatalf("Execute() returned error: %v", err)
+		}
+	})
+
+	for _, id := range ids {
+		record, _ := server.Record(domID, id)
+		if record.TTL != 600 {
+			t.Fatalf("record %s TTL = %d, want 600", id, record.TTL)
+		}
+	}
+}
+
+func TestRecordSelectorRejectsIDAndName(t *testing.T) {
+	cmd := newRootCmd()
+	cmd.SetArgs([]string{"record", "delete", "domid", "recid", "--name", "www.example.com"})
+
+	err := cmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "give either a record ID or --name, not both") {
+		t.Fatalf("expected conflicting selection error, got %v", err)
+	}
+}
+
+func TestRecordShowHasNoAll(t *testing.T) {
+	server := fakedns.NewServer()
+	defer server.Close()
+	domID := server.AddDomain(domains.CreateOpts{Name: "example.com", Email: "admin@example.com"})
+
+	err := runAgainst(server, "record", "show", domID, "--name", "www.example.com", "--all")
+	if err == nil || !strings.Contains(err.Error(), "unknown flag: --all") {
+		t.Fatalf("expected --all to be unknown to record show, got %v", err)
+	}
+}
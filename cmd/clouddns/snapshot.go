@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/gophercloud/gophercloud/v2"
+	"github.com/miekg/dns"
+	"github.com/spf13/cobra"
+
+	"github.com/rackerlabs/goclouddns"
+	"github.com/rackerlabs/goclouddns/snapshot"
+)
+
+func newServeSnapshotCmd(app *cliApp) *cobra.Command {
+	var listen string
+	var from []string
+	var saveDir string
+	cmd := &cobra.Command{
+		Use:   "serve-snapshot [DOMID...]",
+		Short: "Serve a snapshot of domains from a local authoritative DNS server",
+		Long: strings.Join([]string{
+			"Take a snapshot of each domain and answer DNS queries for it over UDP and",
+			"TCP, the way Cloud DNS will once the zone is live. Snapshots saved with",
+			"--save-dir can be served again later with --from, without credentials.",
+		}, "\n"),
+		Example: strings.Join([]string{
+			"  clouddns serve-snapshot <domain-id> --listen 127.0.0.1:5353",
+			"  dig @127.0.0.1 -p 5353 www.example.com",
+			"  clouddns serve-snapshot <domain-id> --save-dir snapshots/",
+			"  clouddns serve-snapshot --from snapshots/example.com.json",
+		}, "\n"),
+		RunE: func(_ *cobra.Command, args []string) error {
+			if len(args) == 0 && len(from) == 0 {
+				return fmt.Errorf("give at least one DOMID or --from snapshot file")
+			}
+			if saveDir != "" && len(args) == 0 {
+				return fmt.Errorf("--save-dir requires at least one DOMID")
+			}
+
+			var snapshots []*snapshot.Snapshot
+			for _, path := range from {
+				s, err := snapshot.Load(path)
+				if err != nil {
+					return err
+				}
+				snapshots = append(snapshots, s)
+			}
+
+			if len(args) == 0 {
+				if err := app.prepare(); err != nil {
+					return err
+				}
+				ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+				defer stop()
+				return serveSnapshots(ctx, listen, snapshots, app.logger())
+			}
+
+			return app.withLongRunningService(func(ctx context.Context, service *gophercloud.ServiceClient) error {
+				if saveDir != "" {
+					if err := os.MkdirAll(saveDir, 0o755); err != nil {
+						return err
+					}
+				}
+				for _, domain := range args {
+					fetchCtx, cancel := context.WithTimeout(ctx, app.operationTimeout())
+					s, err := fetchSnapshot(fetchCtx, service, domain)
+					cancel()
+					if err != nil {
+						return err
+					}
+
+					if saveDir != "" {
+						path := filepath.Join(saveDir, s.Domain.Name+".json")
+						if err := s.Save(path); err != nil {
+							return err
+						}
+						fmt.Fprintf(os.Stderr, "saved %s\n", path)
+					}
+					snapshots = append(snapshots, s)
+				}
+				return serveSnapshots(ctx, listen, snapshots, goclouddns.Logger(service))
+			})
+		},
+	}
+	cmd.Flags().StringVar(&listen, "listen", "127.0.0.1:5353", "address to answer queries on over UDP and TCP")
+	cmd.Flags().StringArrayVar(&from, "from", nil, "serve a snapshot saved with --save-dir (repeatable)")
+	cmd.Flags().StringVar(&saveDir, "save-dir", "", "directory to save the fetched snapshots in")
+
+	return cmd
+}
+
+func fetchSnapshot(ctx context.Context, service *gophercloud.ServiceClient, domain string) (*snapshot.Snapshot, error) {
+	domID, err := resolveDomainID(ctx, service, domain)
+	if err != nil {
+		return nil, err
+	}
+	return snapshot.Fetch(ctx, service, domID)
+}
+
+func serveSnapshots(ctx context.Context, listen string, snapshots []*snapshot.Snapshot, logger *slog.Logger) error {
+	handler, err := snapshot.NewHandler(snapshots...)
+	if err != nil {
+		return err
+	}
+	handler.Logger = logger
+
+	for _, s := range snapshots {
+		fmt.Fprintf(os.Stderr, "serving %s (%d records)\n", s.Domain.Name, len(s.Records))
+	}
+	return serveDNSUntilDone(ctx, []*dns.Server{
+		{Addr: listen, Net: "udp", Handler: handler},
+		{Addr: listen, Net: "tcp", Handler: handler},
+	})
+}
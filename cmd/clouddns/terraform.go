@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/gophercloud/gophercloud/v2"
+	"github.com/spf13/cobra"
+
+	"github.com/rackerlabs/goclouddns/domains"
+	"github.com/rackerlabs/goclouddns/records"
+)
+
+func newDomainExportTerraformCmd(app *cliApp) *cobra.Command {
+	var provider string
+	cmd := &cobra.Command{
+		Use:   "export-terraform ID",
+		Short: "Export a domain and its records as Terraform HCL",
+		Args:  exactArgsValidator(1, "clouddns domain export-terraform ID", "ID"),
+		Example: strings.Join([]string{
+			"  clouddns domain export-terraform <domain-id> > example.com.tf",
+			"  clouddns domain export-terraform example.com > example.com.tf",
+			"  clouddns domain export-terraform <domain-id> --provider rackspace",
+		}, "\n"),
+		RunE: func(_ *cobra.Command, args []string) error {
+			return app.withService(func(ctx context.Context, service *gophercloud.ServiceClient) error {
+				domID, err := resolveDomainID(ctx, service, args[0])
+				if err != nil {
+					return err
+				}
+
+				domain, err := domains.Get(ctx, service, domID).Extract()
+				if err != nil {
+					return err
+				}
+
+				recordList, err := listAllRecords(ctx, service, domain.ID, nil)
+				if err != nil {
+					return err
+				}
+
+				return writeTerraform(os.Stdout, provider, domain, recordList)
+			})
+		},
+	}
+	cmd.Flags().StringVar(&provider, "provider", "clouddns", "Terraform provider name used to prefix resource types")
+
+	return cmd
+}
+
+// writeTerraform renders a domain and its records as Terraform resources,
+// each preceded by an import block keyed by its Cloud DNS ID.
+func writeTerraform(w io.Writer, provider string, domain *domains.DomainShow, recordList []records.RecordList) error {
+	domainType := provider + "_domain"
+	recordType := provider + "_record"
+	domainLabel := terraformLabel(domain.Name)
+
+	var b strings.Builder
+	writeHCLBlock(&b, "import", nil, []hclAttr{
+		{"to", domainType + "." + domainLabel},
+		{"id", hclString(domain.ID)},
+	})
+	b.WriteString("\n")
+
+	domainAttrs := []hclAttr{
+		{"name", hclString(domain.Name)},
+		{"email", hclString(domain.EmailAddress)},
+		{"ttl", fmt.Sprintf("%d", domain.TTL)},
+	}
+	if domain.Comment != "" {
+		domainAttrs = append(domainAttrs, hclAttr{"comment", hclString(domain.Comment)})
+	}
+	writeHCLBlock(&b, "resource", []string{domainType, domainLabel}, domainAttrs)
+
+	seen := map[string]int{}
+	for _, record := range recordList {
+		label := terraformLabel(relativeName(record.Name, domain.Name) + "_" + record.Type)
+		seen[label]++
+		if n := seen[label]; n > 1 {
+			label = fmt.Sprintf("%s_%d", label, n)
+		}
+
+		b.WriteString("\n")
+		writeHCLBlock(&b, "import", nil, []hclAttr{
+			{"to", recordType + "." + label},
+			{"id", hclString(domain.ID + "/" + record.ID)},
+		})
+		b.WriteString("\n")
+
+		recordAttrs := []hclAttr{
+			{"domain_id", domainType + "." + domainLabel + ".id"},
+			{"name", hclString(record.Name)},
+			{"type", hclString(record.Type)},
+			{"data", hclString(record.Data)},
+			{"ttl", fmt.Sprintf("%d", record.TTL)},
+		}
+		if record.Priority != 0 {
+			recordAttrs = append(recordAttrs, hclAttr{"priority", fmt.Sprintf("%d", record.Priority)})
+		}
+		if record.Comment != "" {
+			recordAttrs = append(recordAttrs, hclAttr{"comment", hclString(record.Comment)})
+		}
+		writeHCLBlock(&b, "resource", []string{recordType, label}, recordAttrs)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+type hclAttr struct {
+	name  string
+	value string
+}
+
+// writeHCLBlock writes a block with its attributes aligned the way
+// `terraform fmt` would lay them out.
+func writeHCLBlock(b *strings.Builder, blockType string, labels []string, attrs []hclAttr) {
+	b.WriteString(blockType)
+	for _, label := range labels {
+		b.WriteString(" ")
+		b.WriteString(hclString(label))
+	}
+	b.WriteString(" {\n")
+
+	width := 0
+	for _, attr := range attrs {
+		width = max(width, len(attr.name))
+	}
+	for _, attr := range attrs {
+		fmt.Fprintf(b, "  %-*s = %s\n", width, attr.name, attr.value)
+	}
+	b.WriteString("}\n")
+}
+
+// hclString quotes s as an HCL string literal, escaping template sequences
+// so record data is never interpolated.
+func hclString(s string) string {
+	replacer := strings.NewReplacer(
+		`\`, `\\`,
+		`"`, `\"`,
+		"\n", `\n`,
+		"\r", `\r`,
+		"\t", `\t`,
+		"${", "$${",
+		"%{", "%%{",
+	)
+	return `"` + replacer.Replace(s) + `"`
+}
+
+// terraformLabel turns a DNS name into a valid Terraform resource name.
+func terraformLabel(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ReplaceAll(strings.ToLower(s), "*", "wildcard") {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '_':
+			b.WriteRune(r)
+		default:
+			b.WriteRune('_')
+		}
+	}
+
+	label := strings.Trim(b.String(), "_")
+	if label == "" || (label[0] >= '0' && label[0] <= '9') {
+		label = "r_" + label
+	}
+	return label
+}
+
+// relativeName strips the domain suffix from a record name, returning "apex"
+// for records at the top of the zone.
+func relativeName(name string, domain string) string {
+	name = strings.TrimSuffix(strings.ToLower(name), ".")
+	domain = strings.TrimSuffix(strings.ToLower(domain), ".")
+
+	if name == domain {
+		return "apex"
+	}
+	return strings.TrimSuffix(name, "."+domain)
+}
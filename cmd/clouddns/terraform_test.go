@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/rackerlabs/goclouddns/domains"
+	"github.com/rackerlabs/goclouddns/records"
+)
+
+func TestWriteTerraform(t *testing.T) {
+	domain := &domains.DomainShow{
+		ID:           "1234",
+		Name:         "example.com",
+		EmailAddress: "admin@example.com",
+		TTL:          3600,
+	}
+	recordList := []records.RecordList{
+		{ID: "A-1", Name: "www.example.com", Type: "A", Data: "10.5.19.11", TTL: 300},
+		{ID: "A-2", Name: "www.example.com", Type: "A", Data: "10.5.19.12", TTL: 300},
+		{ID: "MX-1", Name: "example.com", Type: "MX", Data: "mail.example.com", TTL: 300, Priority: 10},
+		{ID: "TXT-1", Name: "example.com", Type: "TXT", Data: `v=spf1 "${x}"`, TTL: 300},
+	}
+
+	var out bytes.Buffer
+	if err := writeTerraform(&out, "clouddns", domain, recordList); err != nil {
+		t.Fatalf("writeTerraform() returned error: %v", err)
+	}
+
+	output := out.String()
+	for _, want := range []string{
+		"import {\n  to = clouddns_domain.example_com\n  id = \"1234\"\n}\n",
+		"resource \"clouddns_domain\" \"example_com\" {\n  name  = \"example.com\"\n",
+		"to = clouddns_record.www_a\n  id = \"1234/A-1\"",
+		"to = clouddns_record.www_a_2\n  id = \"1234/A-2\"",
+		"resource \"clouddns_record\" \"apex_mx\"",
+		"domain_id = clouddns_domain.example_com.id",
+		"priority  = 10",
+		`data      = "v=spf1 \"$${x}\""`,
+	} {
+		if !strings.Contains(output, want) {
+			t.Fatalf("expected %q in output, got:\n%s", want, output)
+		}
+	}
+}
+
+func TestTerraformLabel(t *testing.T) {
+	tests := map[string]string{
+		"example.com":   "example_com",
+		"_dmarc_TXT":    "dmarc_txt",
+		"1.example.com": "r_1_example_com",
+		"*.example.com": "wildcard_example_com",
+		"":              "r_",
+	}
+
+	for in, want := range tests {
+		if got := terraformLabel(in); got != want {
+			t.Errorf("terraformLabel(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
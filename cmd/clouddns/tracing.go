@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/gophercloud/gophercloud/v2"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+
+	"github.com/rackerlabs/goclouddns"
+)
+
+func (app *cliApp) validateTraceExporter() error {
+	switch app.traceExporter {
+	case "none", "stdout", "otlp":
+		return nil
+	default:
+		return fmt.Errorf("unsupported --trace-exporter %q: must be one of none, stdout, otlp", app.traceExporter)
+	}
+}
+
+// startTracing sends the spans of calls made through service to the
+// --trace-exporter. The returned function flushes and stops the exporter.
+func (app *cliApp) startTracing(ctx context.Context, service *gophercloud.ServiceClient) (func(), error) {
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch app.traceExporter {
+	case "stdout":
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stderr), stdouttrace.WithPrettyPrint())
+	case "otlp":
+		// the endpoint also follows OTEL_EXPORTER_OTLP_ENDPOINT when unset
+		var opts []otlptracehttp.Option
+		if app.traceEndpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpointURL(app.traceEndpoint))
+		}
+		exporter, err = otlptracehttp.New(ctx, opts...)
+	default:
+		return func() {}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("starting %s trace exporter: %w", app.traceExporter, err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(
+			semconv.ServiceName("clouddns"),
+			semconv.ServiceVersion(version),
+		)),
+	)
+	goclouddns.SetTracerProvider(service, provider)
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := provider.Shutdown(ctx); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: flushing traces: %v\n", err)
+		}
+	}, nil
+}
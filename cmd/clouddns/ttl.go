@@ -0,0 +1,364 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gophercloud/gophercloud/v2"
+	"github.com/spf13/cobra"
+
+	"github.com/rackerlabs/goclouddns/domains"
+	"github.com/rackerlabs/goclouddns/records"
+)
+
+// ttlState is the state file of 'ttl lower', holding the TTLs that 'ttl
+// restore' puts back.
+type ttlState struct {
+	DomainID   string      `json:"domain_id"`
+	DomainName string      `json:"domain_name"`
+	To         uint        `json:"to"`
+	LoweredAt  time.Time   `json:"lowered_at"`
+	Records    []ttlRecord `json:"records"`
+}
+
+// ttlRecord is a lowered record and its original TTL.
+type ttlRecord struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+	TTL  uint   `json:"ttl"`
+}
+
+// largestTTL is the longest any resolver may still cache one of the
+// records with its old TTL, counted from LoweredAt.
+func (s *ttlState) largestTTL() time.Duration {
+	var largest uint
+	for _, r := range s.Records {
+		largest = max(largest, r.TTL)
+	}
+	return time.Duration(largest) * time.Second
+}
+
+// safeAt is when every cached copy of the records with an old TTL has
+// expired, so the migration can go ahead.
+func (s *ttlState) safeAt() time.Time {
+	return s.LoweredAt.Add(s.largestTTL())
+}
+
+// describeWait tells whether it is safe to proceed at now.
+func (s *ttlState) describeWait(now time.Time) string {
+	safe := s.safeAt()
+	if !now.Before(safe) {
+		return fmt.Sprintf("safe to proceed: the largest old TTL of %s (%s) expired at %s",
+			s.DomainName, s.largestTTL(), safe.Format(time.DateTime))
+	}
+	return fmt.Sprintf("not safe yet: wait %s, until %s, for the largest old TTL of %s (%s) to expire",
+		safe.Sub(now).Round(time.Second), safe.Format(time.DateTime), s.DomainName, s.largestTTL())
+}
+
+func ttlStatePath(domainName string) string {
+	return fmt.Sprintf("clouddns-ttl-%s.json", normalizeDomainName(domainName))
+}
+
+func readTTLState(path string) (*ttlState, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var state ttlState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	return &state, nil
+}
+
+// findTTLState reads the state file of domain, given by name or ID. Names
+// give the default path; for IDs the state files in the current directory
+// are searched.
+func findTTLState(domain string, path string) (string, *ttlState, error) {
+	if path == "" && !strings.Contains(domain, ".") {
+		paths, err := filepath.Glob(ttlStatePath("*"))
+		if err != nil {
+			return "", nil, err
+		}
+		for _, candidate := range paths {
+			state, err := readTTLState(candidate)
+			if err == nil && state.DomainID == domain {
+				return candidate, state, nil
+			}
+		}
+		return "", nil, fmt.Errorf("no state file for domain %s in the current directory", domain)
+	}
+
+	if path == "" {
+		path = ttlStatePath(domain)
+	}
+	state, err := readTTLState(path)
+	return path, state, err
+}
+
+// writeTTLState writes state to path. Unless replace is set, it refuses to
+// overwrite a state file whose TTLs have not been restored yet.
+func writeTTLState(path string, state *ttlState, replace bool) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if !replace {
+		flags |= os.O_EXCL
+	}
+	f, err := os.OpenFile(path, flags, 0o600)
+	if errors.Is(err, fs.ErrExist) {
+		return fmt.Errorf("%s already holds TTLs to restore: run 'clouddns ttl restore' first", path)
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// setTTLs updates the TTL of each record, a few at a time, giving each
+// timeout to be read and updated. change returns the TTL for a record as it
+// is now, or false to leave it alone. It returns the IDs of the records
+// updated, and every failure.
+func setTTLs(ctx context.Context, service *gophercloud.ServiceClient, domID string, ids []string, timeout time.Duration, change func(record *records.RecordShow) (uint, bool)) (map[string]bool, error) {
+	var (
+		mu      sync.Mutex
+		updated = map[string]bool{}
+		errs    []error
+	)
+	forEachConcurrently(len(ids), 4, func(i int) {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		record, err := records.Get(ctx, service, domID, ids[i]).Extract()
+		if err == nil {
+			ttl, ok := change(record)
+			if !ok {
+				return
+			}
+			opts := records.UpdateOpts{Name: record.Name, Data: record.Data, TTL: ttl, Priority: record.Priority}
+			err = records.Update(ctx, service, domID, record, opts).ExtractErr()
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("record %s: %w", ids[i], err))
+			return
+		}
+		updated[ids[i]] = true
+	})
+	return updated, errors.Join(errs...)
+}
+
+// keepRecords drops the records of state whose ID keep does not accept.
+func (s *ttlState) keepRecords(keep func(id string) bool) {
+	s.Records = slices.DeleteFunc(s.Records, func(r ttlRecord) bool { return !keep(r.ID) })
+}
+
+func newTTLCmd(app *cliApp) *cobra.Command {
+	ttlCmd := &cobra.Command{
+		Use:   "ttl",
+		Short: "Lower TTLs ahead of a migration and restore them after",
+		Long: strings.Join([]string{
+			"Lower the TTLs of a domain's records ahead of a migration and restore them",
+			"after. 'ttl lower' keeps the original TTLs in a state file, by default",
+			"clouddns-ttl-DOMAIN.json in the current directory, and says when the old TTLs",
+			"have expired; 'ttl status' says so again, and 'ttl restore' puts them back.",
+		}, "\n"),
+	}
+
+	var lowerTo uint
+	var lowerType, lowerNameRegex, lowerStateFile string
+	var lowerOverride bool
+	lowerCmd := &cobra.Command{
+		Use:               "lower DOMID",
+		Short:             "Lower the TTLs of a domain's records",
+		Args:              exactArgsValidator(1, "clouddns ttl lower DOMID", "DOMID"),
+		ValidArgsFunction: app.completeArgs(domainIDCompleter),
+		Example: strings.Join([]string{
+			"  clouddns ttl lower example.com --to 60",
+			"  clouddns ttl lower example.com --to 60 --type A --name-regex '^(www|api)\\.'",
+		}, "\n"),
+		RunE: func(_ *cobra.Command, args []string) error {
+			if lowerTo == 0 {
+				return fmt.Errorf("specify the new TTL with --to")
+			}
+			var nameRegex *regexp.Regexp
+			if lowerNameRegex != "" {
+				var err error
+				if nameRegex, err = regexp.Compile(lowerNameRegex); err != nil {
+					return fmt.Errorf("invalid --name-regex: %w", err)
+				}
+			}
+
+			// a large domain can take longer than --timeout to lower, so
+			// it bounds the lookups and each record instead
+			return app.withLongRunningService(func(ctx context.Context, service *gophercloud.ServiceClient) error {
+				lookupCtx, cancel := context.WithTimeout(ctx, app.operationTimeout())
+				defer cancel()
+
+				domID, err := resolveDomainID(lookupCtx, service, args[0])
+				if err != nil {
+					return err
+				}
+				domain, err := domains.Get(lookupCtx, service, domID).Extract()
+				if err != nil {
+					return err
+				}
+				if err := app.checkProtected(domain.Name, lowerOverride); err != nil {
+					return err
+				}
+				recordList, err := listAllRecords(lookupCtx, service, domID, records.ListOpts{Type: lowerType})
+				if err != nil {
+					return err
+				}
+
+				state := &ttlState{DomainID: domID, DomainName: domain.Name, To: lowerTo, LoweredAt: time.Now()}
+				var ids []string
+				for _, record := range recordList {
+					if record.TTL <= lowerTo || (nameRegex != nil && !nameRegex.MatchString(record.Name)) {
+						continue
+					}
+					state.Records = append(state.Records, ttlRecord{ID: record.ID, Name: record.Name, Type: record.Type, TTL: record.TTL})
+					ids = append(ids, record.ID)
+				}
+				if len(ids) == 0 {
+					return fmt.Errorf("no records of %s have a TTL above %d", domain.Name, lowerTo)
+				}
+
+				path := lowerStateFile
+				if path == "" {
+					path = ttlStatePath(domain.Name)
+				}
+				// the original TTLs are saved before any is changed
+				if !app.dryRun {
+					if err := writeTTLState(path, state, false); err != nil {
+						return err
+					}
+				}
+
+				updated, err := setTTLs(ctx, service, domID, ids, app.operationTimeout(), func(*records.RecordShow) (uint, bool) {
+					return lowerTo, true
+				})
+				if app.dryRun {
+					fmt.Printf("%d of %d records would be lowered to %ds; dry run, so no state file was written\n", len(updated), len(ids), lowerTo)
+					return err
+				}
+				fmt.Printf("%d of %d records lowered to %ds, original TTLs saved in %s\n", len(updated), len(ids), lowerTo, path)
+
+				// resolvers may cache the old TTLs from the moment the last
+				// change is made. Records that failed keep their place: the
+				// update may have gone through regardless, and restore
+				// leaves alone any whose TTL was not lowered.
+				state.LoweredAt = time.Now()
+				if werr := writeTTLState(path, state, true); werr != nil {
+					return errors.Join(err, werr)
+				}
+				fmt.Println(state.describeWait(state.LoweredAt))
+				return err
+			})
+		},
+	}
+	lowerCmd.Flags().UintVar(&lowerTo, "to", 0, "new TTL in seconds")
+	lowerCmd.Flags().StringVar(&lowerType, "type", "", "only lower records of this type")
+	lowerCmd.Flags().StringVar(&lowerNameRegex, "name-regex", "", "only lower records whose name matches this regular expression")
+	lowerCmd.Flags().StringVar(&lowerStateFile, "state-file", "", "where to save the original TTLs (default clouddns-ttl-DOMAIN.json)")
+	lowerCmd.Flags().BoolVar(&lowerOverride, "override-protection", false, "lower the TTLs even if the domain is protected")
+
+	var statusStateFile string
+	statusCmd := &cobra.Command{
+		Use:   "status DOMID",
+		Short: "Tell whether the old TTLs have expired",
+		Args:  exactArgsValidator(1, "clouddns ttl status DOMID", "DOMID"),
+		RunE: func(_ *cobra.Command, args []string) error {
+			_, state, err := findTTLState(args[0], statusStateFile)
+			if err != nil {
+				return err
+			}
+			fmt.Println(state.describeWait(time.Now()))
+			return nil
+		},
+	}
+	statusCmd.Flags().StringVar(&statusStateFile, "state-file", "", "state file written by 'ttl lower' (default clouddns-ttl-DOMAIN.json)")
+
+	var restoreStateFile string
+	restoreCmd := &cobra.Command{
+		Use:   "restore DOMID",
+		Short: "Restore the TTLs saved by 'ttl lower'",
+		Long: strings.Join([]string{
+			"Restore the TTLs saved by 'ttl lower'. Records whose TTL has been changed",
+			"again since are left alone and dropped from the state file, as the change was",
+			"on purpose. Once every record is restored or dropped, the state file is",
+			"removed; otherwise it is rewritten with only the records that failed, for",
+			"another try.",
+		}, "\n"),
+		Args: exactArgsValidator(1, "clouddns ttl restore DOMID", "DOMID"),
+		RunE: func(_ *cobra.Command, args []string) error {
+			path, state, err := findTTLState(args[0], restoreStateFile)
+			if err != nil {
+				return err
+			}
+
+			original := map[string]uint{}
+			ids := make([]string, 0, len(state.Records))
+			for _, r := range state.Records {
+				original[r.ID] = r.TTL
+				ids = append(ids, r.ID)
+			}
+
+			return app.withLongRunningService(func(ctx context.Context, service *gophercloud.ServiceClient) error {
+				var mu sync.Mutex
+				changed := map[string]bool{}
+				updated, err := setTTLs(ctx, service, state.DomainID, ids, app.operationTimeout(), func(record *records.RecordShow) (uint, bool) {
+					if record.TTL != state.To {
+						mu.Lock()
+						defer mu.Unlock()
+						changed[record.ID] = true
+						fmt.Fprintf(os.Stderr, "%s %s: TTL is now %d, not %d, leaving it alone\n", record.Name, record.Type, record.TTL, state.To)
+						return 0, false
+					}
+					return original[record.ID], true
+				})
+				fmt.Printf("%d of %d records restored\n", len(updated), len(ids))
+				if len(changed) > 0 {
+					fmt.Printf("%d records left alone as their TTL was changed since\n", len(changed))
+				}
+				if app.dryRun {
+					return err
+				}
+
+				// only the records that failed stay in the state file for
+				// another try
+				state.keepRecords(func(id string) bool { return !updated[id] && !changed[id] })
+				if len(state.Records) == 0 {
+					return errors.Join(err, os.Remove(path))
+				}
+				fmt.Fprintf(os.Stderr, "%d records not restored, their TTLs are kept in %s\n", len(state.Records), path)
+				return errors.Join(err, writeTTLState(path, state, true))
+			})
+		},
+	}
+	restoreCmd.Flags().StringVar(&restoreStateFile, "state-file", "", "state file written by 'ttl lower' (default clouddns-ttl-DOMAIN.json)")
+
+	ttlCmd.AddCommand(lowerCmd, statusCmd, restoreCmd)
+	return ttlCmd
+}
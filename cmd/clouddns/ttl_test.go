@@ -0,0 +1,189 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rackerlabs/goclouddns/domains"
+	"github.com/rackerlabs/goclouddns/fakedns"
+	"github.com/rackerlabs/goclouddns/records"
+)
+
+func TestTTLLowerAndRestore(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	server := fakedns.NewServer()
+	defer server.Close()
+	domID := server.AddDomain(domains.CreateOpts{Name: "example.com", Email: "admin@example.com"})
+	ttls := map[string]uint{}
+	for _, opts := range []records.CreateOpts{
+		{Name: "www.example.com", Type: "A", Data: "10.5.19.11", TTL: 3600},
+		{Name: "api.example.com", Type: "A", Data: "10.5.19.12", TTL: 300},
+		{Name: "low.example.com", Type: "A", Data: "10.5.19.13", TTL: 30},
+		{Name: "example.com", Type: "MX", Data: "mail.example.com", TTL: 86400, Priority: 10},
+	} {
+		id, err := server.AddRecord(domID, opts)
+		if err != nil {
+			t.Fatalf("AddRecord() returned error: %v", err)
+		}
+		ttls[id] = opts.TTL
+	}
+
+	output := captureStdout(t, func() {
+		if err := runAgainst(server, "ttl", "lower", "example.com", "--to", "60", "--type", "A"); err != nil {
+			t.Fatalf("Execute() returned error: %v", err)
+		}
+	})
+	if !strings.Contains(output, "2 of 2 records lowered to 60s") || !strings.Contains(output, "wait 1h0m0s") {
+		t.Fatalf("unexpected output %q", output)
+	}
+	for id, ttl := range ttls {
+		record, _ := server.Record(domID, id)
+		want := ttl
+		if record.Type == "A" && ttl > 60 {
+			want = 60
+		}
+		if record.TTL != want {
+			t.Errorf("%s TTL = %d, want %d", record.Name, record.TTL, want)
+		}
+	}
+
+	err := runAgainst(server, "ttl", "lower", "example.com", "--to", "60")
+	if err == nil || !strings.Contains(err.Error(), "already holds TTLs to restore") {
+		t.Fatalf("expected existing state file error, got %v", err)
+	}
+
+	output = captureStdout(t, func() {
+		if err := runAgainst(server, "ttl", "restore", domID); err != nil {
+			t.Fatalf("Execute() returned error: %v", err)
+		}
+	})
+	if !strings.Contains(output, "2 of 2 records restored") {
+		t.Fatalf("unexpected output %q", output)
+	}
+	for id, ttl := range ttls {
+		if record, _ := server.Record(domID, id); record.TTL != ttl {
+			t.Errorf("%s TTL = %d, want %d", record.Name, record.TTL, ttl)
+		}
+	}
+	if _, err := os.Stat(ttlStatePath("example.com")); !os.IsNotExist(err) {
+		t.Fatalf("expected the state file to be removed, got %v", err)
+	}
+}
+
+func TestTTLStateDescribeWait(t *testing.T) {
+	lowered := time.Date(2026, 1, 2, 15, 0, 0, 0, time.UTC)
+	state := &ttlState{
+		DomainName: "example.com",
+		To:         60,
+		LoweredAt:  lowered,
+		Records:    []ttlRecord{{TTL: 300}, {TTL: 1800}},
+	}
+
+	if got := state.describeWait(lowered.Add(10 * time.Minute)); !strings.HasPrefix(got, "not safe yet: wait 20m0s, until 2026-01-02 15:30:00") {
+		t.Fatalf("unexpected wait %q", got)
+	}
+	if got := state.describeWait(lowered.Add(30 * time.Minute)); !strings.HasPrefix(got, "safe to proceed") {
+		t.Fatalf("unexpected wait %q", got)
+	}
+}
+
+func TestTTLRestoreDropsChangedRecords(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	server := fakedns.NewServer()
+	defer server.Close()
+	domID := server.AddDomain(domains.CreateOpts{Name: "example.com", Email: "admin@example.com"})
+	wwwID, _ := server.AddRecord(domID, records.CreateOpts{Name: "www.example.com", Type: "A", Data: "10.5.19.11", TTL: 3600})
+	apiID, _ := server.AddRecord(domID, records.CreateOpts{Name: "api.example.com", Type: "A", Data: "10.5.19.12", TTL: 300})
+
+	output := captureStdout(t, func() {
+		if err := runAgainst(server, "--dry-run", "ttl", "lower", "example.com", "--to", "60"); err != nil {
+			t.Fatalf("Execute() returned error: %v", err)
+		}
+	})
+	if !strings.Contains(output, "2 of 2 records would be lowered to 60s; dry run") || strings.Contains(output, "saved in") {
+		t.Fatalf("unexpected dry-run output %q", output)
+	}
+
+	captureStdout(t, func() {
+		if err := runAgainst(server, "ttl", "lower", "example.com", "--to", "60"); err != nil {
+			t.Fatalf("Execute() returned error: %v", err)
+		}
+	})
+
+	// someone changes www again before the restore
+	client := server.ServiceClient()
+	www, _ := server.Record(domID, wwwID)
+	if err := records.Update(t.Context(), client, domID, &www, records.UpdateOpts{Name: www.Name, Data: www.Data, TTL: 120}).ExtractErr(); err != nil {
+		t.Fatalf("Update() returned error: %v", err)
+	}
+
+	output = captureStdout(t, func() {
+		if err := runAgainst(server, "ttl", "restore", "example.com"); err != nil {
+			t.Fatalf("Execute() returned error: %v", err)
+		}
+	})
+	if !strings.Contains(output, "1 of 2 records restored") || !strings.Contains(output, "1 records left alone") {
+		t.Fatalf("unexpected output %q", output)
+	}
+	if api, _ := server.Record(domID, apiID); api.TTL != 300 {
+		t.Errorf("api TTL = %d, want 300", api.TTL)
+	}
+	if www, _ := server.Record(domID, wwwID); www.TTL != 120 {
+		t.Errorf("www TTL = %d, want 120", www.TTL)
+	}
+
+	// www was changed on purpose, so nothing is left to restore and lower
+	// can run again
+	if _, err := os.Stat(ttlStatePath("example.com")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected the state file to be removed, got %v", err)
+	}
+	captureStdout(t, func() {
+		if err := runAgainst(server, "ttl", "lower", "example.com", "--to", "60"); err != nil {
+			t.Fatalf("Execute() returned error: %v", err)
+		}
+	})
+}
+
+func TestTTLRestoreKeepsFailedRecords(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	server := fakedns.NewServer()
+	defer server.Close()
+	domID := server.AddDomain(domains.CreateOpts{Name: "example.com", Email: "admin@example.com"})
+	wwwID, _ := server.AddRecord(domID, records.CreateOpts{Name: "www.example.com", Type: "A", Data: "10.5.19.11", TTL: 3600})
+	apiID, _ := server.AddRecord(domID, records.CreateOpts{Name: "api.example.com", Type: "A", Data: "10.5.19.12", TTL: 300})
+
+	captureStdout(t, func() {
+		if err := runAgainst(server, "ttl", "lower", "example.com", "--to", "60"); err != nil {
+			t.Fatalf("Execute() returned error: %v", err)
+		}
+	})
+
+	// the first restore update fails
+	server.FailNextJob("update failed")
+	captureStdout(t, func() {
+		if err := runAgainst(server, "ttl", "restore", "example.com"); err == nil {
+			t.Fatal("expected the failed update to be reported")
+		}
+	})
+
+	state, err := readTTLState(ttlStatePath("example.com"))
+	if err != nil {
+		t.Fatalf("expected the state file to be kept, got %v", err)
+	}
+	if len(state.Records) != 1 {
+		t.Fatalf("expected one record left to restore, got %+v", state.Records)
+	}
+	failed := state.Records[0]
+	if restored, _ := server.Record(domID, failed.ID); restored.TTL != 60 {
+		t.Errorf("expected the record kept in the state file to still have TTL 60, got %d", restored.TTL)
+	}
+	if failed.ID != wwwID && failed.ID != apiID {
+		t.Errorf("unexpected record %+v in the state file", failed)
+	}
+}
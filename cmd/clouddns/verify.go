@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/gophercloud/gophercloud/v2"
+	"github.com/spf13/cobra"
+
+	"github.com/rackerlabs/goclouddns"
+	"github.com/rackerlabs/goclouddns/domains"
+	"github.com/rackerlabs/goclouddns/propagation"
+	"github.com/rackerlabs/goclouddns/records"
+)
+
+// verifyOptions holds the --verify flags shared by the record commands
+// that change data.
+type verifyOptions struct {
+	enabled  bool
+	resolver string
+	port     string
+	timeout  time.Duration
+}
+
+func (o *verifyOptions) addFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&o.enabled, "verify", false, "wait until the domain's nameservers serve the change")
+	cmd.Flags().StringVar(&o.resolver, "resolver", "", "DNS server (host:port) used to look up the nameservers' addresses")
+	cmd.Flags().StringVar(&o.port, "verify-port", "53", "port to query the nameservers on")
+	cmd.Flags().DurationVar(&o.timeout, "verify-timeout", 5*time.Minute, "how long to wait for the change to propagate")
+}
+
+// verify waits for record to be served, or with absent to stop being
+// served, by every nameserver of the domain, reporting each server's
+// status on stderr. The wait is bounded by --verify-timeout, not --timeout.
+func (o *verifyOptions) verify(ctx context.Context, service *gophercloud.ServiceClient, domID string, record records.RecordList, absent bool) error {
+	if !o.enabled {
+		return nil
+	}
+	if goclouddns.IsDryRun(service) {
+		fmt.Fprintln(os.Stderr, "dry run: nothing changed to verify")
+		return nil
+	}
+
+	domain, err := domains.Get(ctx, service, domID).Extract()
+	if err != nil {
+		return err
+	}
+
+	v := propagation.NewVerifier()
+	v.Resolver = o.resolver
+	v.Port = o.port
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), o.timeout)
+	defer cancel()
+
+	var statuses []propagation.Status
+	if absent {
+		statuses, err = v.WaitForAbsent(ctx, propagation.Nameservers(domain), record)
+	} else {
+		statuses, err = v.WaitForRecord(ctx, propagation.Nameservers(domain), record)
+	}
+
+	for _, s := range statuses {
+		if s.Propagated {
+			fmt.Fprintf(os.Stderr, "%s (%s): propagated after %s\n", s.Nameserver, s.Address, s.Elapsed.Round(time.Second))
+		} else {
+			fmt.Fprintf(os.Stderr, "%s (%s): not propagated after %d attempts: %v\n", s.Nameserver, s.Address, s.Attempts, s.Err)
+		}
+	}
+	return err
+}
+
+func recordListFromShow(record *records.RecordShow) records.RecordList {
+	return records.RecordList{
+		ID:       record.ID,
+		Name:     record.Name,
+		Type:     record.Type,
+		Data:     record.Data,
+		TTL:      record.TTL,
+		Priority: record.Priority,
+		Comment:  record.Comment,
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/miekg/dns"
+
+	"github.com/rackerlabs/goclouddns/domains"
+	"github.com/rackerlabs/goclouddns/fakedns"
+	"github.com/rackerlabs/goclouddns/propagation"
+)
+
+// startNameserver serves a single A record for www.example.com on a
+// loopback port, standing in for the domain's nameservers.
+func startNameserver(t *testing.T, data string) string {
+	t.Helper()
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket() returned error: %v", err)
+	}
+
+	handler := dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		if rr, err := dns.NewRR("www.example.com. 300 IN A " + data); err == nil && r.Question[0].Name == "www.example.com." {
+			m.Answer = append(m.Answer, rr)
+		}
+		_ = w.WriteMsg(m)
+	})
+
+	started := make(chan struct{})
+	server := &dns.Server{PacketConn: pc, Handler: handler, NotifyStartedFunc: func() { close(started) }}
+	go func() { _ = server.ActivateAndServe() }()
+	t.Cleanup(func() { _ = server.Shutdown() })
+	<-started
+
+	_, port, _ := net.SplitHostPort(pc.LocalAddr().String())
+	return port
+}
+
+func TestRecordCreateVerify(t *testing.T) {
+	server := fakedns.NewServer()
+	defer server.Close()
+	server.Nameservers = []string{"127.0.0.1"}
+	domID := server.AddDomain(domains.CreateOpts{Name: "example.com", Email: "admin@example.com"})
+
+	port := startNameserver(t, "192.0.2.10")
+	captureStdout(t, func() {
+		if err := runAgainst(server, "record", "create", domID, "www.example.com", "A", "192.0.2.10", "--verify", "--verify-port", port); err != nil {
+			t.Errorf("record create --verify returned error: %v", err)
+		}
+	})
+
+	captureStdout(t, func() {
+		err := runAgainst(server, "record", "create", domID, "www.example.com", "A", "192.0.2.11",
+			"--verify", "--verify-port", port, "--verify-timeout", "200ms")
+		if !errors.Is(err, propagation.ErrNotPropagated) {
+			t.Errorf("expected ErrNotPropagated, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gophercloud/gophercloud/v2"
+	"github.com/spf13/cobra"
+
+	"github.com/rackerlabs/goclouddns/externaldns"
+)
+
+func newWebhookCmd(app *cliApp) *cobra.Command {
+	webhookCmd := &cobra.Command{
+		Use:   "webhook",
+		Short: "Run an external-dns webhook provider",
+	}
+
+	var listen string
+	var filter externaldns.DomainFilter
+	serveCmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve the external-dns webhook provider protocol",
+		Long: strings.Join([]string{
+			"Serve the external-dns webhook provider protocol, letting external-dns",
+			"manage records in Cloud DNS. Run it as a sidecar next to external-dns",
+			"started with --provider=webhook.",
+		}, "\n"),
+		Args: noArgsValidator("clouddns webhook serve"),
+		Example: strings.Join([]string{
+			"  clouddns webhook serve",
+			"  clouddns webhook serve --listen 127.0.0.1:8888 --domain-filter example.com --exclude-domains internal.example.com",
+		}, "\n"),
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return app.withLongRunningService(func(ctx context.Context, service *gophercloud.ServiceClient) error {
+				provider := externaldns.NewProvider(service, filter)
+				server := &http.Server{
+					Addr:              listen,
+					Handler:           externaldns.NewHandler(provider),
+					ReadHeaderTimeout: 10 * time.Second,
+				}
+				return serveUntilDone(ctx, server)
+			})
+		},
+	}
+	serveCmd.Flags().StringVar(&listen, "listen", "127.0.0.1:8888", "address to serve the webhook on")
+	serveCmd.Flags().StringSliceVar(&filter.Include, "domain-filter", nil, "limit to domains matching this (repeatable)")
+	serveCmd.Flags().StringSliceVar(&filter.Exclude, "exclude-domains", nil, "exclude domains matching this (repeatable)")
+
+	webhookCmd.AddCommand(serveCmd)
+	return webhookCmd
+}
+
+// serveUntilDone runs server until ctx is cancelled, then shuts it down
+// gracefully.
+func serveUntilDone(ctx context.Context, server *http.Server) error {
+	errc := make(chan error, 1)
+	go func() {
+		fmt.Fprintf(os.Stderr, "listening on %s\n", server.Addr)
+		errc <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -2,7 +2,8 @@ package domains
 
 import (
 	"context"
-	"log"
+	"strings"
+	"time"
 
 	"github.com/gophercloud/gophercloud/v2"
 	"github.com/gophercloud/gophercloud/v2/pagination"
@@ -28,7 +29,7 @@ func (opts ListOpts) ToDomainListQuery() (string, error) {
 	return q.String(), err
 }
 
-func List(_ctx context.Context, client *gophercloud.ServiceClient, opts ListOptsBuilder) pagination.Pager {
+func List(ctx context.Context, client *gophercloud.ServiceClient, opts ListOptsBuilder) pagination.Pager {
 	url := client.ServiceURL("domains")
 	if opts != nil {
 		query, err := opts.ToDomainListQuery()
@@ -38,30 +39,42 @@ func List(_ctx context.Context, client *gophercloud.ServiceClient, opts ListOpts
 		url += query
 	}
 
-	log.Printf("GET %s", url)
+	goclouddns.Logger(client).DebugContext(ctx, "request", "method", "GET", "url", url)
 
-	return pagination.NewPager(client, url, func(r pagination.PageResult) pagination.Page {
+	pager := pagination.NewPager(client, url, func(r pagination.PageResult) pagination.Page {
 		return DomainPage{pagination.LinkedPageBase{PageResult: r}}
 	})
+	return goclouddns.TracePages(client, pager, "domains.List")
 }
 
 // Get returns data about a specific domain by its ID.
 func Get(ctx context.Context, client *gophercloud.ServiceClient, id string) (r GetResult) {
+	ctx, span := goclouddns.StartSpan(ctx, client, "domains.Get", goclouddns.AttrDomainID.String(id))
+	defer func() { goclouddns.EndSpan(span, r.Err) }()
+
 	url := client.ServiceURL("domains", id)
-	log.Printf("GET %s", url)
+	start := time.Now()
 	_, r.Err = client.Get(ctx, url, &r.Body, nil)
+	goclouddns.LogRequest(ctx, client, "GET", url, start, r.Err)
 	return
 }
 
 // Delete deletes the specified domain ID.
 func Delete(ctx context.Context, client *gophercloud.ServiceClient, id string) (r DeleteResult) {
+	ctx, span := goclouddns.StartSpan(ctx, client, "domains.Delete", goclouddns.AttrDomainID.String(id))
+	defer func() { goclouddns.EndSpan(span, r.Err) }()
+
 	url := client.ServiceURL("domains", id)
-	log.Printf("DELETE %s", url)
+	if goclouddns.PlanRequest(ctx, client, "DELETE", url, nil) {
+		return
+	}
 
+	start := time.Now()
 	var resp goclouddns.AsyncResult
 	_, resp.Err = client.Delete(ctx, url, &gophercloud.RequestOpts{
 		JSONResponse: &resp.Body,
 	})
+	goclouddns.LogRequest(ctx, client, "DELETE", url, start, resp.Err)
 
 	if resp.Err != nil {
 		r.Err = resp.Err
@@ -87,22 +100,30 @@ type CreateOpts struct {
 
 // Create creates a requested domain
 func Create(ctx context.Context, client *gophercloud.ServiceClient, opts CreateOpts) (r CreateResult) {
+	ctx, span := goclouddns.StartSpan(ctx, client, "domains.Create")
+	defer func() { goclouddns.EndSpan(span, r.Err) }()
+
 	url := client.ServiceURL("domains")
 
 	if opts.TTL == 0 {
 		opts.TTL = 3600
 	}
 
-	log.Printf("POST %s", url)
-
 	var body = struct {
 		Domains []CreateOpts `json:"domains"`
 	}{
 		[]CreateOpts{opts},
 	}
 
+	if goclouddns.PlanRequest(ctx, client, "POST", url, body) {
+		r.Body = dryRunCreated(opts)
+		return
+	}
+
+	start := time.Now()
 	var resp goclouddns.AsyncResult
 	_, resp.Err = client.Post(ctx, url, body, &resp.Body, nil)
+	goclouddns.LogRequest(ctx, client, "POST", url, start, resp.Err)
 	if resp.Err != nil {
 		r.Err = resp.Err
 		return
@@ -116,6 +137,20 @@ func Create(ctx context.Context, client *gophercloud.ServiceClient, opts CreateO
 	return
 }
 
+// dryRunCreated is the body of a completed job that created opts, for
+// Create to return in a dry run.
+func dryRunCreated(opts CreateOpts) map[string]any {
+	domain := DomainList{
+		ID:    goclouddns.DryRunID,
+		Name:  opts.Name,
+		Email: opts.Email,
+	}
+	return map[string]any{
+		"status":   "COMPLETED",
+		"response": map[string]any{"domains": []DomainList{domain}},
+	}
+}
+
 // UpdateOpts contain the values necessary to create a domain
 type UpdateOpts struct {
 	Email   string `json:"emailAddress,omitempty"`
@@ -125,12 +160,18 @@ type UpdateOpts struct {
 
 // Update updates a requested domain
 func Update(ctx context.Context, client *gophercloud.ServiceClient, domain *DomainShow, opts UpdateOpts) (r UpdateResult) {
-	url := client.ServiceURL("domains", domain.ID)
+	ctx, span := goclouddns.StartSpan(ctx, client, "domains.Update", goclouddns.AttrDomainID.String(domain.ID))
+	defer func() { goclouddns.EndSpan(span, r.Err) }()
 
-	log.Printf("PUT %s", url)
+	url := client.ServiceURL("domains", domain.ID)
+	if goclouddns.PlanRequest(ctx, client, "PUT", url, opts) {
+		return
+	}
 
+	start := time.Now()
 	var resp goclouddns.AsyncResult
 	_, resp.Err = client.Put(ctx, url, opts, &resp.Body, nil)
+	goclouddns.LogRequest(ctx, client, "PUT", url, start, resp.Err)
 	if resp.Err != nil {
 		r.Err = resp.Err
 		return
@@ -143,3 +184,101 @@ func Update(ctx context.Context, client *gophercloud.ServiceClient, domain *Doma
 	r.Body = resp.Body
 	return
 }
+
+// IDFromName returns the ID of the domain named name, compared without
+// regard to case or a trailing dot. It returns a
+// gophercloud.ErrResourceNotFound when no domain has that name and a
+// gophercloud.ErrMultipleResourcesFound when more than one does.
+func IDFromName(ctx context.Context, client *gophercloud.ServiceClient, name string) (string, error) {
+	ctx, span := goclouddns.StartSpan(ctx, client, "domains.IDFromName")
+	id, err := idFromName(ctx, client, name)
+	goclouddns.EndSpan(span, err)
+	return id, err
+}
+
+func idFromName(ctx context.Context, client *gophercloud.ServiceClient, name string) (string, error) {
+	name = strings.TrimSuffix(name, ".")
+
+	domainList, err := listByName(ctx, client, name)
+	if err != nil {
+		return "", err
+	}
+
+	switch len(domainList) {
+	case 0:
+		return "", gophercloud.ErrResourceNotFound{Name: name, ResourceType: "domain"}
+	case 1:
+		return domainList[0].ID, nil
+	default:
+		return "", gophercloud.ErrMultipleResourcesFound{Name: name, Count: len(domainList), ResourceType: "domain"}
+	}
+}
+
+// FindZone returns the most specific domain that name falls under: the
+// domain called name itself, or else the closest parent, each compared
+// without regard to case or a trailing dot. It returns a
+// gophercloud.ErrResourceNotFound when there is none.
+func FindZone(ctx context.Context, client *gophercloud.ServiceClient, name string) (*DomainList, error) {
+	ctx, span := goclouddns.StartSpan(ctx, client, "domains.FindZone")
+	zone, err := findZone(ctx, client, name)
+	goclouddns.EndSpan(span, err)
+	return zone, err
+}
+
+func findZone(ctx context.Context, client *gophercloud.ServiceClient, name string) (*DomainList, error) {
+	for _, candidate := range zoneCandidates(name) {
+		domainList, err := listByName(ctx, client, candidate)
+		if err != nil {
+			return nil, err
+		}
+		if len(domainList) > 0 {
+			return &domainList[0], nil
+		}
+	}
+	return nil, gophercloud.ErrResourceNotFound{Name: strings.TrimSuffix(name, "."), ResourceType: "domain"}
+}
+
+// ZoneFor is FindZone for a listing already at hand: it returns the most
+// specific of domainList that name falls under, or nil.
+func ZoneFor(name string, domainList []DomainList) *DomainList {
+	for _, candidate := range zoneCandidates(name) {
+		for i, domain := range domainList {
+			if strings.EqualFold(strings.TrimSuffix(domain.Name, "."), candidate) {
+				return &domainList[i]
+			}
+		}
+	}
+	return nil
+}
+
+// zoneCandidates returns name and then each of its parents, most specific
+// first, leaving out the top-level label.
+func zoneCandidates(name string) []string {
+	labels := strings.Split(strings.TrimSuffix(strings.ToLower(strings.TrimSpace(name)), "."), ".")
+	candidates := make([]string, 0, len(labels))
+	for i := 0; i < len(labels)-1; i++ {
+		candidates = append(candidates, strings.Join(labels[i:], "."))
+	}
+	return candidates
+}
+
+// listByName returns the domains called name. The API's name filter also
+// matches subdomains and partial names, so results are checked again here.
+func listByName(ctx context.Context, client *gophercloud.ServiceClient, name string) ([]DomainList, error) {
+	page, err := List(ctx, client, ListOpts{Name: name}).AllPages(ctx)
+	if err != nil {
+		return nil, err
+	}
+	domainList, err := ExtractDomains(page)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []DomainList
+	for _, domain := range domainList {
+		if strings.EqualFold(strings.TrimSuffix(domain.Name, "."), name) {
+			matches = append(matches, domain)
+		}
+	}
+	return matches, nil
+}
@@ -0,0 +1,90 @@
+package domains_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gophercloud/gophercloud/v2"
+
+	"github.com/rackerlabs/goclouddns/domains"
+	"github.com/rackerlabs/goclouddns/fakedns"
+)
+
+func TestIDFromName(t *testing.T) {
+	server := fakedns.NewServer()
+	defer server.Close()
+	server.AddDomain(domains.CreateOpts{Name: "sub.example.com", Email: "admin@example.com"})
+	domID := server.AddDomain(domains.CreateOpts{Name: "example.com", Email: "admin@example.com"})
+	server.AddDomain(domains.CreateOpts{Name: "example.com.au", Email: "admin@example.com"})
+
+	client := server.ServiceClient()
+	for _, name := range []string{"example.com", "EXAMPLE.com."} {
+		id, err := domains.IDFromName(t.Context(), client, name)
+		if err != nil {
+			t.Fatalf("IDFromName(%q) returned error: %v", name, err)
+		}
+		if id != domID {
+			t.Errorf("IDFromName(%q) = %q, want %q", name, id, domID)
+		}
+	}
+
+	_, err := domains.IDFromName(t.Context(), client, "ample.com")
+	if !errors.As(err, &gophercloud.ErrResourceNotFound{}) {
+		t.Errorf("expected ErrResourceNotFound for a partial name, got %v", err)
+	}
+}
+
+func TestIDFromNameAmbiguous(t *testing.T) {
+	server := fakedns.NewServer()
+	defer server.Close()
+	server.AddDomain(domains.CreateOpts{Name: "example.com", Email: "admin@example.com"})
+	server.AddDomain(domains.CreateOpts{Name: "Example.com", Email: "admin@example.com"})
+
+	_, err := domains.IDFromName(t.Context(), server.ServiceClient(), "example.com")
+	var multiple gophercloud.ErrMultipleResourcesFound
+	if !errors.As(err, &multiple) || multiple.Count != 2 {
+		t.Fatalf("expected ErrMultipleResourcesFound with 2 domains, got %v", err)
+	}
+}
+
+func TestFindZone(t *testing.T) {
+	server := fakedns.NewServer()
+	defer server.Close()
+	subID := server.AddDomain(domains.CreateOpts{Name: "sub.example.com", Email: "admin@example.com"})
+	domID := server.AddDomain(domains.CreateOpts{Name: "example.com", Email: "admin@example.com"})
+
+	client := server.ServiceClient()
+	tests := map[string]string{
+		"example.com":                 domID,
+		"www.example.com.":            domID,
+		"WWW.Sub.Example.com":         subID,
+		"a.b.sub.example.com":         subID,
+		"_acme-challenge.example.com": domID,
+	}
+	for name, want := range tests {
+		zone, err := domains.FindZone(t.Context(), client, name)
+		if err != nil {
+			t.Fatalf("FindZone(%q) returned error: %v", name, err)
+		}
+		if zone.ID != want {
+			t.Errorf("FindZone(%q) = %s, want %s", name, zone.Name, want)
+		}
+
+		page, err := domains.List(t.Context(), client, nil).AllPages(t.Context())
+		if err != nil {
+			t.Fatal(err)
+		}
+		domainList, _ := domains.ExtractDomains(page)
+		if zone := domains.ZoneFor(name, domainList); zone == nil || zone.ID != want {
+			t.Errorf("ZoneFor(%q) = %+v, want %s", name, zone, want)
+		}
+	}
+
+	_, err := domains.FindZone(t.Context(), client, "www.example.org")
+	if !errors.As(err, &gophercloud.ErrResourceNotFound{}) {
+		t.Errorf("expected ErrResourceNotFound outside every domain, got %v", err)
+	}
+	if zone := domains.ZoneFor("www.example.org", nil); zone != nil {
+		t.Errorf("expected no zone, got %+v", zone)
+	}
+}
@@ -0,0 +1,73 @@
+package goclouddns
+
+import (
+	"context"
+	"sync"
+
+	"github.com/gophercloud/gophercloud/v2"
+)
+
+// DryRunID is the ID given to domains and records "created" in a dry run.
+const DryRunID = "dry-run"
+
+// PlannedRequest is a request a dry run would have made.
+type PlannedRequest struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Body   any    `json:"body,omitempty"`
+}
+
+// DryRunRecorder is told about each request a dry run skips. See
+// SetDryRun.
+type DryRunRecorder interface {
+	RecordRequest(req PlannedRequest)
+}
+
+// SetDryRun puts client in dry-run mode: the Create, Update and Delete
+// calls of the domains and records packages hand their request to
+// recorder and return a made-up successful result instead of calling the
+// API. Reads such as Get and List still go to the API. A nil recorder
+// turns dry-run mode off.
+func SetDryRun(client *gophercloud.ServiceClient, recorder DryRunRecorder) {
+	setOption(client, func(o *clientOptions) { o.dryRun = recorder })
+}
+
+// IsDryRun reports whether client is in dry-run mode.
+func IsDryRun(client *gophercloud.ServiceClient) bool {
+	return optionsFor(client).dryRun != nil
+}
+
+// PlanRequest reports whether client is in dry-run mode, and if so hands
+// the request to its recorder. The domains and records packages call it
+// before each request that changes something, skipping the request when
+// it returns true.
+func PlanRequest(ctx context.Context, client *gophercloud.ServiceClient, method string, url string, body any) bool {
+	recorder := optionsFor(client).dryRun
+	if recorder == nil {
+		return false
+	}
+
+	Logger(client).DebugContext(ctx, "dry run", "method", method, "url", url)
+	recorder.RecordRequest(PlannedRequest{Method: method, URL: url, Body: body})
+	return true
+}
+
+// DryRunLog is a DryRunRecorder that keeps every request it is given.
+type DryRunLog struct {
+	mu       sync.Mutex
+	requests []PlannedRequest
+}
+
+// RecordRequest implements DryRunRecorder.
+func (l *DryRunLog) RecordRequest(req PlannedRequest) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.requests = append(l.requests, req)
+}
+
+// Requests returns the requests recorded so far, oldest first.
+func (l *DryRunLog) Requests() []PlannedRequest {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]PlannedRequest(nil), l.requests...)
+}
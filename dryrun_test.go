@@ -0,0 +1,98 @@
+package goclouddns_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rackerlabs/goclouddns"
+	"github.com/rackerlabs/goclouddns/domains"
+	"github.com/rackerlabs/goclouddns/fakedns"
+	"github.com/rackerlabs/goclouddns/records"
+)
+
+func TestDryRunRecordsChanges(t *testing.T) {
+	server := fakedns.NewServer()
+	defer server.Close()
+	domID := server.AddDomain(domains.CreateOpts{Name: "example.com", Email: "admin@example.com"})
+	recID, err := server.AddRecord(domID, records.CreateOpts{Name: "www.example.com", Type: "A", Data: "192.0.2.10"})
+	if err != nil {
+		t.Fatalf("AddRecord() returned error: %v", err)
+	}
+
+	client := server.ServiceClient()
+	var plan goclouddns.DryRunLog
+	goclouddns.SetDryRun(client, &plan)
+
+	created, err := records.Create(t.Context(), client, domID, records.CreateOpts{Name: "app.example.com", Type: "A", Data: "192.0.2.11"}).Extract()
+	if err != nil {
+		t.Fatalf("Create() returned error: %v", err)
+	}
+	if created.ID != goclouddns.DryRunID || created.Name != "app.example.com" || created.Data != "192.0.2.11" {
+		t.Fatalf("unexpected dry-run record %+v", created)
+	}
+
+	record, err := records.Get(t.Context(), client, domID, recID).Extract()
+	if err != nil {
+		t.Fatalf("Get() returned error: %v", err)
+	}
+	if err := records.Update(t.Context(), client, domID, record, records.UpdateOpts{Name: record.Name, Data: "192.0.2.12"}).ExtractErr(); err != nil {
+		t.Fatalf("Update() returned error: %v", err)
+	}
+	if err := domains.Delete(t.Context(), client, domID).ExtractErr(); err != nil {
+		t.Fatalf("Delete() returned error: %v", err)
+	}
+
+	requests := plan.Requests()
+	if len(requests) != 3 {
+		t.Fatalf("expected 3 planned requests, got %+v", requests)
+	}
+	for i, method := range []string{"POST", "PUT", "DELETE"} {
+		if requests[i].Method != method {
+			t.Errorf("request %d method = %s, want %s", i, requests[i].Method, method)
+		}
+	}
+	if !strings.HasSuffix(requests[1].URL, "/domains/"+domID+"/records/"+recID) {
+		t.Errorf("unexpected update URL %s", requests[1].URL)
+	}
+	if opts, ok := requests[1].Body.(records.UpdateOpts); !ok || opts.Data != "192.0.2.12" {
+		t.Errorf("unexpected update body %#v", requests[1].Body)
+	}
+
+	if _, ok := server.Domain(domID); !ok {
+		t.Fatal("expected the domain to survive a dry-run delete")
+	}
+	if unchanged, _ := server.Record(domID, recID); unchanged.Data != "192.0.2.10" {
+		t.Fatalf("expected the record to be unchanged, got %+v", unchanged)
+	}
+	if recordList, err := records.Find(t.Context(), client, domID, "app.example.com", ""); err != nil || len(recordList) != 0 {
+		t.Fatalf("expected no created record, got %+v, %v", recordList, err)
+	}
+
+	goclouddns.SetDryRun(client, nil)
+	if err := records.Delete(t.Context(), client, domID, recID).ExtractErr(); err != nil {
+		t.Fatalf("Delete() returned error: %v", err)
+	}
+	if _, ok := server.Record(domID, recID); ok {
+		t.Fatal("expected the record to be deleted once dry run is off")
+	}
+}
+
+func TestDryRunDomainCreate(t *testing.T) {
+	server := fakedns.NewServer()
+	defer server.Close()
+
+	client := server.ServiceClient()
+	var plan goclouddns.DryRunLog
+	goclouddns.SetDryRun(client, &plan)
+
+	domain, err := domains.Create(t.Context(), client, domains.CreateOpts{Name: "example.org", Email: "admin@example.org"}).Extract()
+	if err != nil {
+		t.Fatalf("Create() returned error: %v", err)
+	}
+	if domain.ID != goclouddns.DryRunID || domain.Name != "example.org" || domain.Email != "admin@example.org" {
+		t.Fatalf("unexpected dry-run domain %+v", domain)
+	}
+	if requests := plan.Requests(); len(requests) != 1 || requests[0].Method != "POST" {
+		t.Fatalf("unexpected planned requests %+v", requests)
+	}
+}
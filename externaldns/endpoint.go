@@ -0,0 +1,73 @@
+package externaldns
+
+import (
+	"strings"
+)
+
+// MediaType is the content type external-dns negotiates for the webhook
+// protocol.
+const MediaType = "application/external.dns.webhook+json;version=1"
+
+// Endpoint is a DNS name with its targets, as exchanged with external-dns.
+type Endpoint struct {
+	DNSName          string             `json:"dnsName"`
+	Targets          []string           `json:"targets"`
+	RecordType       string             `json:"recordType"`
+	SetIdentifier    string             `json:"setIdentifier,omitempty"`
+	RecordTTL        int64              `json:"recordTTL,omitempty"`
+	Labels           map[string]string  `json:"labels,omitempty"`
+	ProviderSpecific []ProviderProperty `json:"providerSpecific,omitempty"`
+}
+
+// ProviderProperty is a provider specific key/value attached to an
+// Endpoint.
+type ProviderProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Changes is the set of changes external-dns asks the provider to apply.
+// UpdateOld and UpdateNew are paired by index.
+type Changes struct {
+	Create    []*Endpoint `json:"create,omitempty"`
+	UpdateOld []*Endpoint `json:"updateOld,omitempty"`
+	UpdateNew []*Endpoint `json:"updateNew,omitempty"`
+	Delete    []*Endpoint `json:"delete,omitempty"`
+}
+
+// DomainFilter limits which Cloud DNS domains the provider manages. It is
+// returned to external-dns during negotiation.
+type DomainFilter struct {
+	Include []string `json:"include,omitempty"`
+	Exclude []string `json:"exclude,omitempty"`
+}
+
+// Match reports whether name is covered by the filter. An empty Include
+// list matches every name.
+func (f DomainFilter) Match(name string) bool {
+	name = normalizeName(name)
+
+	for _, exclude := range f.Exclude {
+		if inDomain(name, normalizeName(exclude)) {
+			return false
+		}
+	}
+
+	if len(f.Include) == 0 {
+		return true
+	}
+	for _, include := range f.Include {
+		if inDomain(name, normalizeName(include)) {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeName(name string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(name)), ".")
+}
+
+func inDomain(name string, domain string) bool {
+	return name == domain || strings.HasSuffix(name, "."+domain)
+}
@@ -0,0 +1,71 @@
+package externaldns
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/rackerlabs/goclouddns"
+)
+
+// NewHandler serves the external-dns webhook protocol for p: negotiation on
+// "/", record listing and changes on "/records", "/adjustendpoints", and a
+// "/healthz" liveness check.
+func NewHandler(p *Provider) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(p, w, http.StatusOK, p.DomainFilter)
+	})
+
+	mux.HandleFunc("GET /records", func(w http.ResponseWriter, r *http.Request) {
+		endpoints, err := p.Records(r.Context())
+		if err != nil {
+			goclouddns.Logger(p.client).Error("listing records", "error", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if endpoints == nil {
+			endpoints = []*Endpoint{}
+		}
+		writeJSON(p, w, http.StatusOK, endpoints)
+	})
+
+	mux.HandleFunc("POST /records", func(w http.ResponseWriter, r *http.Request) {
+		var changes Changes
+		if err := json.NewDecoder(r.Body).Decode(&changes); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := p.ApplyChanges(r.Context(), &changes); err != nil {
+			goclouddns.Logger(p.client).Error("applying changes", "error", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	mux.HandleFunc("POST /adjustendpoints", func(w http.ResponseWriter, r *http.Request) {
+		var endpoints []*Endpoint
+		if err := json.NewDecoder(r.Body).Decode(&endpoints); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(p, w, http.StatusOK, p.AdjustEndpoints(endpoints))
+	})
+
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ok"))
+	})
+
+	return mux
+}
+
+func writeJSON(p *Provider, w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", MediaType)
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		goclouddns.Logger(p.client).Warn("writing response", "error", err)
+	}
+}
@@ -0,0 +1,366 @@
+// Package externaldns implements the external-dns webhook provider protocol
+// on top of Cloud DNS, so external-dns can manage records in Rackspace
+// domains.
+package externaldns
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gophercloud/gophercloud/v2"
+	"github.com/gophercloud/gophercloud/v2/pagination"
+
+	"github.com/rackerlabs/goclouddns/domains"
+	"github.com/rackerlabs/goclouddns/records"
+)
+
+// MinTTL is the lowest TTL Cloud DNS accepts. AdjustEndpoints raises lower
+// TTLs to it so external-dns does not keep trying to apply them.
+const MinTTL = 300
+
+// supportedTypes are the record types external-dns can manage here.
+var supportedTypes = []string{"A", "AAAA", "CNAME", "MX", "NS", "SRV", "TXT"}
+
+// Provider maps external-dns endpoints onto Cloud DNS records.
+type Provider struct {
+	client *gophercloud.ServiceClient
+
+	// DomainFilter limits which domains are listed and changed.
+	DomainFilter DomainFilter
+}
+
+// NewProvider returns a Provider managing the domains matched by filter.
+func NewProvider(client *gophercloud.ServiceClient, filter DomainFilter) *Provider {
+	return &Provider{client: client, DomainFilter: filter}
+}
+
+// Records returns every supported record in the managed domains, grouped
+// into one endpoint per name and type.
+func (p *Provider) Records(ctx context.Context) ([]*Endpoint, error) {
+	zones, err := p.zones(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var endpoints []*Endpoint
+	for _, zone := range zones {
+		recordList, err := p.zoneRecords(ctx, zone.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		byKey := map[string]*Endpoint{}
+		for _, record := range recordList {
+			recordType := strings.ToUpper(record.Type)
+			if !slices.Contains(supportedTypes, recordType) {
+				continue
+			}
+
+			name := normalizeName(record.Name)
+			key := name + " " + recordType
+			ep, ok := byKey[key]
+			if !ok {
+				ep = &Endpoint{DNSName: name, RecordType: recordType, RecordTTL: int64(record.TTL)}
+				byKey[key] = ep
+				endpoints = append(endpoints, ep)
+			}
+			ep.Targets = append(ep.Targets, targetFromRecord(record))
+		}
+	}
+
+	return endpoints, nil
+}
+
+// AdjustEndpoints normalizes desired endpoints into the form Records
+// reports, so external-dns does not see a difference where Cloud DNS would
+// store the same thing.
+func (p *Provider) AdjustEndpoints(endpoints []*Endpoint) []*Endpoint {
+	for _, ep := range endpoints {
+		ep.DNSName = normalizeName(ep.DNSName)
+		ep.RecordType = strings.ToUpper(ep.RecordType)
+
+		if ep.RecordTTL > 0 && ep.RecordTTL < MinTTL {
+			ep.RecordTTL = MinTTL
+		}
+
+		for i, target := range ep.Targets {
+			switch ep.RecordType {
+			case "TXT":
+				ep.Targets[i] = unquote(target)
+			case "CNAME", "NS":
+				ep.Targets[i] = normalizeName(target)
+			}
+		}
+	}
+	return endpoints
+}
+
+// ApplyChanges makes the requested changes, waiting for each Cloud DNS job
+// to complete. It stops at the first failure; external-dns will retry the
+// remaining changes on its next sync.
+func (p *Provider) ApplyChanges(ctx context.Context, changes *Changes) error {
+	zones, err := p.zones(ctx)
+	if err != nil {
+		return err
+	}
+
+	a := &applier{provider: p, zones: zones, cache: map[string][]records.RecordList{}}
+
+	for _, ep := range changes.Delete {
+		if err := a.deleteTargets(ctx, ep, ep.Targets); err != nil {
+			return err
+		}
+	}
+
+	if len(changes.UpdateOld) != len(changes.UpdateNew) {
+		return fmt.Errorf("mismatched updates: %d old endpoints, %d new", len(changes.UpdateOld), len(changes.UpdateNew))
+	}
+	for i, oldEp := range changes.UpdateOld {
+		if err := a.update(ctx, oldEp, changes.UpdateNew[i]); err != nil {
+			return err
+		}
+	}
+
+	for _, ep := range changes.Create {
+		if err := a.createTargets(ctx, ep, ep.Targets); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// zones returns the domains matched by the provider's filter.
+func (p *Provider) zones(ctx context.Context) ([]domains.DomainList, error) {
+	var zones []domains.DomainList
+
+	err := domains.List(ctx, p.client, nil).EachPage(ctx, func(ctx context.Context, page pagination.Page) (bool, error) {
+		domainList, err := domains.ExtractDomains(page)
+		if err != nil {
+			return false, err
+		}
+
+		for _, domain := range domainList {
+			if p.DomainFilter.Match(domain.Name) {
+				zones = append(zones, domain)
+			}
+		}
+		return true, nil
+	})
+
+	return zones, err
+}
+
+func (p *Provider) zoneRecords(ctx context.Context, domID string) ([]records.RecordList, error) {
+	page, err := records.List(ctx, p.client, domID, nil).AllPages(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return records.ExtractRecords(page)
+}
+
+// applier carries the zone list and per-zone record listings through a
+// single ApplyChanges call.
+type applier struct {
+	provider *Provider
+	zones    []domains.DomainList
+	cache    map[string][]records.RecordList
+}
+
+func (a *applier) update(ctx context.Context, oldEp *Endpoint, newEp *Endpoint) error {
+	var removed, added, kept []string
+	for _, target := range oldEp.Targets {
+		if slices.Contains(newEp.Targets, target) {
+			kept = append(kept, target)
+		} else {
+			removed = append(removed, target)
+		}
+	}
+	for _, target := range newEp.Targets {
+		if !slices.Contains(oldEp.Targets, target) {
+			added = append(added, target)
+		}
+	}
+
+	if err := a.deleteTargets(ctx, oldEp, removed); err != nil {
+		return err
+	}
+
+	if newEp.RecordTTL != oldEp.RecordTTL && newEp.RecordTTL > 0 {
+		zone, err := a.zoneFor(newEp.DNSName)
+		if err != nil {
+			return err
+		}
+		for _, target := range kept {
+			matches, err := a.matching(ctx, zone, newEp, target)
+			if err != nil {
+				return err
+			}
+			for _, record := range matches {
+				opts := records.UpdateOpts{
+					Name:     record.Name,
+					Data:     record.Data,
+					TTL:      uint(newEp.RecordTTL),
+					Priority: record.Priority,
+				}
+				show := &records.RecordShow{ID: record.ID}
+				if err := records.Update(ctx, a.provider.client, zone.ID, show, opts).ExtractErr(); err != nil {
+					return fmt.Errorf("updating %s %s: %w", newEp.RecordType, newEp.DNSName, err)
+				}
+			}
+		}
+	}
+
+	return a.createTargets(ctx, newEp, added)
+}
+
+func (a *applier) createTargets(ctx context.Context, ep *Endpoint, targets []string) error {
+	if len(targets) == 0 {
+		return nil
+	}
+
+	zone, err := a.zoneFor(ep.DNSName)
+	if err != nil {
+		return err
+	}
+
+	for _, target := range targets {
+		data, priority, err := recordData(ep.RecordType, target)
+		if err != nil {
+			return err
+		}
+
+		opts := records.CreateOpts{
+			Name:     normalizeName(ep.DNSName),
+			Type:     ep.RecordType,
+			Data:     data,
+			Priority: priority,
+		}
+		if ep.RecordTTL > 0 {
+			opts.TTL = uint(ep.RecordTTL)
+		}
+
+		if err := records.Create(ctx, a.provider.client, zone.ID, opts).Err; err != nil {
+			return fmt.Errorf("creating %s %s: %w", ep.RecordType, ep.DNSName, err)
+		}
+	}
+
+	delete(a.cache, zone.ID)
+	return nil
+}
+
+func (a *applier) deleteTargets(ctx context.Context, ep *Endpoint, targets []string) error {
+	if len(targets) == 0 {
+		return nil
+	}
+
+	zone, err := a.zoneFor(ep.DNSName)
+	if err != nil {
+		return err
+	}
+
+	for _, target := range targets {
+		matches, err := a.matching(ctx, zone, ep, target)
+		if err != nil {
+			return err
+		}
+
+		for _, record := range matches {
+			if err := records.Delete(ctx, a.provider.client, zone.ID, record.ID).ExtractErr(); err != nil {
+				return fmt.Errorf("deleting %s %s: %w", ep.RecordType, ep.DNSName, err)
+			}
+		}
+	}
+
+	delete(a.cache, zone.ID)
+	return nil
+}
+
+// matching returns the records in zone backing one target of ep.
+func (a *applier) matching(ctx context.Context, zone *domains.DomainList, ep *Endpoint, target string) ([]records.RecordList, error) {
+	recordList, ok := a.cache[zone.ID]
+	if !ok {
+		var err error
+		recordList, err = a.provider.zoneRecords(ctx, zone.ID)
+		if err != nil {
+			return nil, err
+		}
+		a.cache[zone.ID] = recordList
+	}
+
+	name := normalizeName(ep.DNSName)
+	var matches []records.RecordList
+	for _, record := range recordList {
+		if normalizeName(record.Name) != name || !strings.EqualFold(record.Type, ep.RecordType) {
+			continue
+		}
+		if targetFromRecord(record) == targetFromRecord(recordFromTarget(ep.RecordType, target)) {
+			matches = append(matches, record)
+		}
+	}
+	return matches, nil
+}
+
+// zoneFor returns the most specific managed domain containing name.
+func (a *applier) zoneFor(name string) (*domains.DomainList, error) {
+	zone := domains.ZoneFor(name, a.zones)
+	if zone == nil {
+		return nil, fmt.Errorf("no managed domain found for %s", normalizeName(name))
+	}
+	return zone, nil
+}
+
+// targetFromRecord renders a record the way external-dns writes targets,
+// with the priority leading for MX and SRV records.
+func targetFromRecord(record records.RecordList) string {
+	switch strings.ToUpper(record.Type) {
+	case "MX", "SRV":
+		return fmt.Sprintf("%d %s", record.Priority, normalizeName(record.Data))
+	case "CNAME", "NS":
+		return normalizeName(record.Data)
+	default:
+		return record.Data
+	}
+}
+
+// recordFromTarget is the inverse of targetFromRecord. Malformed targets
+// come back with their data unchanged.
+func recordFromTarget(recordType string, target string) records.RecordList {
+	data, priority, _ := recordData(recordType, target)
+	return records.RecordList{Type: recordType, Data: data, Priority: priority}
+}
+
+// recordData splits an external-dns target into Cloud DNS record data and
+// priority.
+func recordData(recordType string, target string) (string, uint, error) {
+	switch strings.ToUpper(recordType) {
+	case "MX", "SRV":
+		fields := strings.Fields(target)
+		if len(fields) < 2 {
+			return target, 0, fmt.Errorf("invalid %s target %q: expected a priority", recordType, target)
+		}
+		priority, err := strconv.ParseUint(fields[0], 10, 16)
+		if err != nil {
+			return target, 0, fmt.Errorf("invalid %s target %q: %w", recordType, target, err)
+		}
+		return normalizeName(strings.Join(fields[1:], " ")), uint(priority), nil
+	case "TXT":
+		return unquote(target), 0, nil
+	case "CNAME", "NS":
+		return normalizeName(target), 0, nil
+	default:
+		return target, 0, nil
+	}
+}
+
+// unquote strips one pair of surrounding double quotes, which the
+// external-dns TXT registry adds but Cloud DNS does not store.
+func unquote(s string) string {
+	if len(s) >= 2 && strings.HasPrefix(s, `"`) && strings.HasSuffix(s, `"`) {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
@@ -0,0 +1,170 @@
+package externaldns
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/rackerlabs/goclouddns/domains"
+	"github.com/rackerlabs/goclouddns/fakedns"
+	"github.com/rackerlabs/goclouddns/records"
+)
+
+func TestDomainFilterMatch(t *testing.T) {
+	filter := DomainFilter{Include: []string{"example.com"}, Exclude: []string{"internal.example.com"}}
+
+	tests := map[string]bool{
+		"example.com":            true,
+		"www.example.com.":       true,
+		"a.internal.example.com": false,
+		"example.org":            false,
+		"badexample.com":         false,
+	}
+	for name, want := range tests {
+		if got := filter.Match(name); got != want {
+			t.Errorf("Match(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestRecordsGroupsTargets(t *testing.T) {
+	server, domID := newTestServer(t)
+	addRecord(t, server, domID, records.CreateOpts{Name: "www.example.com", Type: "A", Data: "10.0.0.1", TTL: 300})
+	addRecord(t, server, domID, records.CreateOpts{Name: "www.example.com", Type: "A", Data: "10.0.0.2", TTL: 300})
+	addRecord(t, server, domID, records.CreateOpts{Name: "example.com", Type: "MX", Data: "mail.example.com", Priority: 10})
+
+	endpoints, err := NewProvider(server.ServiceClient(), DomainFilter{}).Records(context.Background())
+	if err != nil {
+		t.Fatalf("Records() returned error: %v", err)
+	}
+	if len(endpoints) != 2 {
+		t.Fatalf("expected 2 endpoints, got %+v", endpoints)
+	}
+	if !slices.Equal(endpoints[0].Targets, []string{"10.0.0.1", "10.0.0.2"}) {
+		t.Errorf("unexpected A targets %v", endpoints[0].Targets)
+	}
+	if !slices.Equal(endpoints[1].Targets, []string{"10 mail.example.com"}) {
+		t.Errorf("unexpected MX targets %v", endpoints[1].Targets)
+	}
+}
+
+func TestApplyChanges(t *testing.T) {
+	server, domID := newTestServer(t)
+	oldID := addRecord(t, server, domID, records.CreateOpts{Name: "old.example.com", Type: "A", Data: "10.0.0.1"})
+	keepID := addRecord(t, server, domID, records.CreateOpts{Name: "www.example.com", Type: "A", Data: "10.0.0.1", TTL: 300})
+	dropID := addRecord(t, server, domID, records.CreateOpts{Name: "www.example.com", Type: "A", Data: "10.0.0.2", TTL: 300})
+
+	provider := NewProvider(server.ServiceClient(), DomainFilter{})
+	changes := &Changes{
+		Create: []*Endpoint{
+			{DNSName: "txt.example.com", RecordType: "TXT", Targets: []string{`"heritage=external-dns,external-dns/owner=default"`}},
+		},
+		UpdateOld: []*Endpoint{
+			{DNSName: "www.example.com", RecordType: "A", Targets: []string{"10.0.0.1", "10.0.0.2"}, RecordTTL: 300},
+		},
+		UpdateNew: []*Endpoint{
+			{DNSName: "www.example.com", RecordType: "A", Targets: []string{"10.0.0.1", "10.0.0.3"}, RecordTTL: 600},
+		},
+		Delete: []*Endpoint{
+			{DNSName: "old.example.com", RecordType: "A", Targets: []string{"10.0.0.1"}},
+		},
+	}
+
+	if err := provider.ApplyChanges(context.Background(), changes); err != nil {
+		t.Fatalf("ApplyChanges() returned error: %v", err)
+	}
+
+	if _, ok := server.Record(domID, oldID); ok {
+		t.Errorf("expected deleted endpoint's record to be gone")
+	}
+	if _, ok := server.Record(domID, dropID); ok {
+		t.Errorf("expected removed target's record to be gone")
+	}
+	if kept, _ := server.Record(domID, keepID); kept.TTL != 600 {
+		t.Errorf("expected kept target's TTL to be updated, got %d", kept.TTL)
+	}
+
+	endpoints, err := provider.Records(context.Background())
+	if err != nil {
+		t.Fatalf("Records() returned error: %v", err)
+	}
+	var txt *Endpoint
+	for _, ep := range endpoints {
+		if ep.RecordType == "TXT" {
+			txt = ep
+		}
+	}
+	if txt == nil || txt.Targets[0] != "heritage=external-dns,external-dns/owner=default" {
+		t.Errorf("expected unquoted TXT registry record, got %+v", txt)
+	}
+}
+
+func TestAdjustEndpoints(t *testing.T) {
+	endpoints := NewProvider(nil, DomainFilter{}).AdjustEndpoints([]*Endpoint{
+		{DNSName: "WWW.Example.com.", RecordType: "cname", Targets: []string{"LB.example.com."}, RecordTTL: 60},
+	})
+
+	ep := endpoints[0]
+	if ep.DNSName != "www.example.com" || ep.RecordType != "CNAME" || ep.Targets[0] != "lb.example.com" {
+		t.Errorf("unexpected normalized endpoint %+v", ep)
+	}
+	if ep.RecordTTL != MinTTL {
+		t.Errorf("expected TTL raised to %d, got %d", MinTTL, ep.RecordTTL)
+	}
+}
+
+func TestHandlerNegotiateAndRecords(t *testing.T) {
+	server, domID := newTestServer(t)
+	addRecord(t, server, domID, records.CreateOpts{Name: "www.example.com", Type: "A", Data: "10.0.0.1"})
+
+	handler := NewHandler(NewProvider(server.ServiceClient(), DomainFilter{Include: []string{"example.com"}}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != MediaType {
+		t.Fatalf("unexpected negotiate response %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(rec.Body.String(), `"include":["example.com"]`) {
+		t.Errorf("expected domain filter in negotiate response, got %s", rec.Body)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/records", nil))
+	var endpoints []Endpoint
+	if err := json.Unmarshal(rec.Body.Bytes(), &endpoints); err != nil {
+		t.Fatalf("failed to decode records: %v", err)
+	}
+	if len(endpoints) != 1 || endpoints[0].DNSName != "www.example.com" {
+		t.Errorf("unexpected records %+v", endpoints)
+	}
+
+	body, _ := json.Marshal(Changes{Delete: []*Endpoint{&endpoints[0]}})
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("POST", "/records", bytes.NewReader(body)))
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("expected 204 from apply changes, got %d: %s", rec.Code, rec.Body)
+	}
+}
+
+func newTestServer(t *testing.T) (*fakedns.Server, string) {
+	t.Helper()
+
+	server := fakedns.NewServer()
+	t.Cleanup(server.Close)
+	return server, server.AddDomain(domains.CreateOpts{Name: "example.com", Email: "admin@example.com"})
+}
+
+func addRecord(t *testing.T, server *fakedns.Server, domID string, opts records.CreateOpts) string {
+	t.Helper()
+
+	id, err := server.AddRecord(domID, opts)
+	if err != nil {
+		t.Fatalf("AddRecord() returned error: %v", err)
+	}
+	return id
+}
@@ -0,0 +1,265 @@
+package fakedns
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Redacted replaces secrets in saved fixtures.
+const Redacted = "REDACTED"
+
+// sensitiveHeaders are never written to a fixture with their real values.
+var sensitiveHeaders = []string{
+	"Authorization",
+	"Cookie",
+	"Set-Cookie",
+	"X-Auth-Token",
+	"X-Subject-Token",
+}
+
+// sensitiveFields matches credentials in identity request and response
+// bodies.
+var sensitiveFields = regexp.MustCompile(`("(?:apiKey|password)"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+
+// Exchange is a single recorded HTTP request and its response.
+type Exchange struct {
+	Method          string      `json:"method"`
+	URL             string      `json:"url"`
+	RequestHeaders  http.Header `json:"requestHeaders,omitempty"`
+	RequestBody     string      `json:"requestBody,omitempty"`
+	StatusCode      int         `json:"statusCode"`
+	ResponseHeaders http.Header `json:"responseHeaders,omitempty"`
+	ResponseBody    string      `json:"responseBody,omitempty"`
+}
+
+// Fixture is an ordered list of exchanges, as saved by a Recorder and
+// served back by a Replayer.
+type Fixture struct {
+	Exchanges []Exchange `json:"exchanges"`
+}
+
+// LoadFixture reads a fixture previously written by Recorder.Save.
+func LoadFixture(path string) (*Fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var f Fixture
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("parsing fixture %s: %w", path, err)
+	}
+	return &f, nil
+}
+
+// Recorder is an http.RoundTripper that passes requests through to a real
+// transport and keeps a copy of every exchange. Set it as the Transport of
+// a ProviderClient's HTTPClient to capture a session, including every
+// WaitForStatus poll.
+type Recorder struct {
+	// Transport performs the real requests. http.DefaultTransport is used
+	// when it is nil.
+	Transport http.RoundTripper
+
+	mu        sync.Mutex
+	exchanges []Exchange
+	secrets   map[string]bool
+}
+
+// NewRecorder returns a Recorder wrapping next.
+func NewRecorder(next http.RoundTripper) *Recorder {
+	return &Recorder{Transport: next}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		var err error
+		reqBody, err = io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(reqBody))
+	}
+
+	transport := r.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.rememberSecrets(req.Header)
+	r.rememberSecrets(resp.Header)
+	r.exchanges = append(r.exchanges, Exchange{
+		Method:          req.Method,
+		URL:             req.URL.String(),
+		RequestHeaders:  req.Header.Clone(),
+		RequestBody:     string(reqBody),
+		StatusCode:      resp.StatusCode,
+		ResponseHeaders: resp.Header.Clone(),
+		ResponseBody:    string(respBody),
+	})
+
+	return resp, nil
+}
+
+// Fixture returns the exchanges recorded so far with tokens and credentials
+// redacted.
+func (r *Recorder) Fixture() *Fixture {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	f := &Fixture{Exchanges: make([]Exchange, 0, len(r.exchanges))}
+	for _, ex := range r.exchanges {
+		ex.URL = r.redact(ex.URL)
+		ex.RequestHeaders = redactHeaders(ex.RequestHeaders)
+		ex.RequestBody = r.redact(ex.RequestBody)
+		ex.ResponseHeaders = redactHeaders(ex.ResponseHeaders)
+		ex.ResponseBody = r.redact(ex.ResponseBody)
+		f.Exchanges = append(f.Exchanges, ex)
+	}
+	return f
+}
+
+// Save writes the sanitized fixture to path as indented JSON.
+func (r *Recorder) Save(path string) error {
+	data, err := json.MarshalIndent(r.Fixture(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+func (r *Recorder) rememberSecrets(h http.Header) {
+	for _, name := range sensitiveHeaders {
+		for _, v := range h.Values(name) {
+			if v == "" {
+				continue
+			}
+			if r.secrets == nil {
+				r.secrets = map[string]bool{}
+			}
+			r.secrets[v] = true
+		}
+	}
+}
+
+// redact removes credential fields and any token value seen in a header,
+// so a token echoed back in an identity response body is caught too.
+func (r *Recorder) redact(s string) string {
+	s = sensitiveFields.ReplaceAllString(s, `$1"`+Redacted+`"`)
+	for secret := range r.secrets {
+		s = strings.ReplaceAll(s, secret, Redacted)
+	}
+	return s
+}
+
+func redactHeaders(h http.Header) http.Header {
+	h = h.Clone()
+	for _, name := range sensitiveHeaders {
+		if h.Get(name) != "" {
+			h.Set(name, Redacted)
+		}
+	}
+	return h
+}
+
+// Replayer is an http.RoundTripper that serves a Fixture's exchanges back in
+// order without touching the network. A request that does not match the
+// next recorded exchange fails.
+type Replayer struct {
+	mu      sync.Mutex
+	fixture *Fixture
+	next    int
+}
+
+// NewReplayer returns a Replayer serving f.
+func NewReplayer(f *Fixture) *Replayer {
+	return &Replayer{fixture: f}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.next >= len(r.fixture.Exchanges) {
+		return nil, fmt.Errorf("fixture exhausted: unexpected %s %s", req.Method, req.URL)
+	}
+
+	ex := r.fixture.Exchanges[r.next]
+	if ex.Method != req.Method || ex.URL != req.URL.String() {
+		return nil, fmt.Errorf("fixture exchange %d: expected %s %s, got %s %s", r.next, ex.Method, ex.URL, req.Method, req.URL)
+	}
+
+	if req.Body != nil {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		if !sameBody(ex.RequestBody, string(body)) {
+			return nil, fmt.Errorf("fixture exchange %d: request body for %s %s does not match", r.next, req.Method, req.URL)
+		}
+	}
+	r.next++
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", ex.StatusCode, http.StatusText(ex.StatusCode)),
+		StatusCode:    ex.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        ex.ResponseHeaders.Clone(),
+		Body:          io.NopCloser(strings.NewReader(ex.ResponseBody)),
+		ContentLength: int64(len(ex.ResponseBody)),
+		Request:       req,
+	}, nil
+}
+
+// Remaining reports how many recorded exchanges have not been replayed.
+func (r *Replayer) Remaining() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.fixture.Exchanges) - r.next
+}
+
+// sameBody compares request bodies, ignoring JSON formatting and any
+// credentials that were redacted when the fixture was saved.
+func sameBody(recorded string, actual string) bool {
+	actual = sensitiveFields.ReplaceAllString(actual, `$1"`+Redacted+`"`)
+	if recorded == actual {
+		return true
+	}
+
+	var a, b any
+	if json.Unmarshal([]byte(recorded), &a) != nil || json.Unmarshal([]byte(actual), &b) != nil {
+		return false
+	}
+	ra, _ := json.Marshal(a)
+	rb, _ := json.Marshal(b)
+	return bytes.Equal(ra, rb)
+}
@@ -0,0 +1,92 @@
+package fakedns
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rackerlabs/goclouddns/domains"
+	"github.com/rackerlabs/goclouddns/records"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	s := NewServer()
+	s.PendingPolls = 1
+	domID := s.AddDomain(domains.CreateOpts{Name: "example.com", Email: "admin@example.com"})
+
+	ctx := context.Background()
+	recorder := NewRecorder(nil)
+	client := s.ServiceClient()
+	client.HTTPClient = http.Client{Transport: recorder}
+
+	opts := records.CreateOpts{Name: "www.example.com", Type: "A", Data: "10.5.19.11"}
+	created, err := records.Create(ctx, client, domID, opts).Extract()
+	if err != nil {
+		t.Fatalf("Create() returned error: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "create.json")
+	if err := recorder.Save(path); err != nil {
+		t.Fatalf("Save() returned error: %v", err)
+	}
+	s.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() returned error: %v", err)
+	}
+	if strings.Contains(string(data), Token) {
+		t.Fatalf("expected auth token to be redacted, got %s", data)
+	}
+
+	fixture, err := LoadFixture(path)
+	if err != nil {
+		t.Fatalf("LoadFixture() returned error: %v", err)
+	}
+	// the POST plus a RUNNING and a COMPLETED poll
+	if len(fixture.Exchanges) != 3 {
+		t.Fatalf("expected 3 exchanges, got %d", len(fixture.Exchanges))
+	}
+
+	replayer := NewReplayer(fixture)
+	client = s.ServiceClient()
+	client.HTTPClient = http.Client{Transport: replayer}
+
+	replayed, err := records.Create(ctx, client, domID, opts).Extract()
+	if err != nil {
+		t.Fatalf("replayed Create() returned error: %v", err)
+	}
+	if replayed.ID != created.ID {
+		t.Errorf("expected replayed ID %q, got %q", created.ID, replayed.ID)
+	}
+	if n := replayer.Remaining(); n != 0 {
+		t.Errorf("expected all exchanges replayed, %d left", n)
+	}
+}
+
+func TestReplayerRejectsUnexpectedRequest(t *testing.T) {
+	replayer := NewReplayer(&Fixture{Exchanges: []Exchange{
+		{Method: "GET", URL: "https://dns.example.com/v1.0/123456/domains", StatusCode: 200, ResponseBody: `{"domains":[]}`},
+	}})
+
+	req, _ := http.NewRequest("DELETE", "https://dns.example.com/v1.0/123456/domains/1", nil)
+	if _, err := replayer.RoundTrip(req); err == nil {
+		t.Fatal("expected mismatch error")
+	}
+}
+
+func TestRecorderRedactsCredentials(t *testing.T) {
+	recorder := &Recorder{exchanges: []Exchange{{
+		Method:      "POST",
+		URL:         "https://identity.example.com/v2.0/tokens",
+		RequestBody: `{"auth":{"RAX-KSKEY:apiKeyCredentials":{"username":"bob","apiKey":"s3cr3t"}}}`,
+	}}}
+
+	body := recorder.Fixture().Exchanges[0].RequestBody
+	if strings.Contains(body, "s3cr3t") {
+		t.Fatalf("expected api key to be redacted, got %s", body)
+	}
+}
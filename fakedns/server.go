@@ -0,0 +1,764 @@
+// Package fakedns provides an in-memory Cloud DNS API served over httptest,
+// along with a transport that records real API sessions as fixtures and
+// replays them, so code built on goclouddns can be exercised end-to-end
+// without network access.
+package fakedns
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gophercloud/gophercloud/v2"
+
+	"github.com/rackerlabs/goclouddns"
+	"github.com/rackerlabs/goclouddns/domains"
+	"github.com/rackerlabs/goclouddns/records"
+)
+
+const (
+	// TenantID is the account the fake server pretends to serve.
+	TenantID = "123456"
+
+	// Token is the auth token the fake server accepts.
+	Token = "fake-token"
+
+	// DefaultPageSize is the page size used when a List call sets no limit,
+	// matching the real API.
+	DefaultPageSize = 100
+)
+
+// Server is a fake Cloud DNS API. Mutating requests are queued as async jobs
+// and only applied once the job's callback URL is polled to completion.
+type Server struct {
+	*httptest.Server
+
+	// PageSize limits how many domains or records are returned per page.
+	PageSize int
+
+	// PendingPolls is how many times a job's callback URL reports RUNNING
+	// before the job is applied.
+	PendingPolls int
+
+	// Nameservers are reported for every domain.
+	Nameservers []string
+
+	mu       sync.Mutex
+	nextID   int
+	domains  []*domain
+	jobs     map[string]*job
+	failNext *string
+}
+
+type domain struct {
+	show    domains.DomainShow
+	records []*records.RecordShow
+}
+
+type job struct {
+	message goclouddns.AsyncMessage
+	polls   int
+	apply   func() (map[string]any, error)
+}
+
+// NewServer starts a fake Cloud DNS API. Callers should Close it when done.
+func NewServer() *Server {
+	s := &Server{
+		PageSize:    DefaultPageSize,
+		Nameservers: []string{"dns1.stabletransit.com", "dns2.stabletransit.com"},
+		jobs:        map[string]*job{},
+	}
+
+	mux := http.NewServeMux()
+	prefix := "/v1.0/" + TenantID
+	mux.HandleFunc("GET "+prefix+"/domains", s.listDomains)
+	mux.HandleFunc("POST "+prefix+"/domains", s.createDomain)
+	mux.HandleFunc("GET "+prefix+"/domains/{domID}", s.getDomain)
+	mux.HandleFunc("PUT "+prefix+"/domains/{domID}", s.updateDomain)
+	mux.HandleFunc("DELETE "+prefix+"/domains/{domID}", s.deleteDomain)
+	mux.HandleFunc("GET "+prefix+"/domains/{domID}/records", s.listRecords)
+	mux.HandleFunc("POST "+prefix+"/domains/{domID}/records", s.createRecord)
+	mux.HandleFunc("GET "+prefix+"/domains/{domID}/records/{id}", s.getRecord)
+	mux.HandleFunc("PUT "+prefix+"/domains/{domID}/records/{id}", s.updateRecord)
+	mux.HandleFunc("DELETE "+prefix+"/domains/{domID}/records/{id}", s.deleteRecord)
+	mux.HandleFunc("GET "+prefix+"/status/{jobID}", s.getStatus)
+
+	s.Server = httptest.NewServer(s.authenticate(mux))
+	return s
+}
+
+// Endpoint returns the service endpoint a client should be pointed at.
+func (s *Server) Endpoint() string {
+	return s.URL + "/v1.0/" + TenantID + "/"
+}
+
+// ServiceClient returns a client already authenticated against the server.
+func (s *Server) ServiceClient() *gophercloud.ServiceClient {
+	provider := &gophercloud.ProviderClient{
+		TokenID: Token,
+		EndpointLocator: func(gophercloud.EndpointOpts) (string, error) {
+			return s.Endpoint(), nil
+		},
+	}
+
+	// the locator above cannot fail
+	service, _ := goclouddns.NewCloudDNS(provider, gophercloud.EndpointOpts{})
+	return service
+}
+
+// FailNextJob makes the next async job end in ERROR with the given details.
+func (s *Server) FailNextJob(details string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failNext = &details
+}
+
+// AddDomain seeds a domain directly, bypassing the async job queue, and
+// returns its ID.
+func (s *Server) AddDomain(opts domains.CreateOpts) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.addDomain(opts).show.ID
+}
+
+// AddRecord seeds a record directly, bypassing the async job queue, and
+// returns its ID.
+func (s *Server) AddRecord(domID string, opts records.CreateOpts) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d := s.findDomain(domID)
+	if d == nil {
+		return "", fmt.Errorf("domain %s not found", domID)
+	}
+	return s.addRecord(d, opts).ID, nil
+}
+
+// Domain returns a copy of the stored domain, without its records list.
+func (s *Server) Domain(id string) (domains.DomainShow, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if d := s.findDomain(id); d != nil {
+		return d.show, true
+	}
+	return domains.DomainShow{}, false
+}
+
+// Record returns a copy of the stored record.
+func (s *Server) Record(domID string, id string) (records.RecordShow, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if d := s.findDomain(domID); d != nil {
+		if r := findRecord(d, id); r != nil {
+			return *r, true
+		}
+	}
+	return records.RecordShow{}, false
+}
+
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Auth-Token") != Token {
+			writeError(w, http.StatusUnauthorized, "Unauthorized", "No valid token provided")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) listDomains(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	name := strings.ToLower(r.URL.Query().Get("name"))
+	var matched []domains.DomainList
+	for _, d := range s.domains {
+		if name != "" && !strings.Contains(strings.ToLower(d.show.Name), name) {
+			continue
+		}
+		matched = append(matched, domains.DomainList{
+			ID:        d.show.ID,
+			Created:   d.show.Created,
+			Updated:   d.show.Updated,
+			Email:     d.show.EmailAddress,
+			AccountID: d.show.AccountID,
+			Name:      d.show.Name,
+		})
+	}
+
+	page, links, err := s.paginate(r, len(matched))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Bad Request", err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"domains":      matched[page.start:page.end],
+		"totalEntries": len(matched),
+		"links":        links,
+	})
+}
+
+func (s *Server) getDomain(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d := s.findDomain(r.PathValue("domID"))
+	if d == nil {
+		writeNotFound(w, "domain")
+		return
+	}
+
+	show := d.show
+	show.RecordsList.TotalEntries = len(d.records)
+	for _, rec := range d.records {
+		show.RecordsList.Records = append(show.RecordsList.Records, struct {
+			ID      string `json:"id"`
+			Name    string `json:"name"`
+			Type    string `json:"type"`
+			Data    string `json:"data"`
+			TTL     uint   `json:"ttl"`
+			Updated string `json:"updated"`
+			Created string `json:"created"`
+		}{rec.ID, rec.Name, rec.Type, rec.Data, rec.TTL, rec.Updated, rec.Created})
+	}
+
+	writeJSON(w, http.StatusOK, show)
+}
+
+func (s *Server) createDomain(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Domains []domains.CreateOpts `json:"domains"`
+	}
+	if !readJSON(w, r, &body) {
+		return
+	}
+	if len(body.Domains) == 0 {
+		writeError(w, http.StatusBadRequest, "Bad Request", "No domains were provided")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.queueJob(w, r, func() (map[string]any, error) {
+		created := make([]domains.DomainList, 0, len(body.Domains))
+		for _, opts := range body.Domains {
+			if opts.Name == "" || opts.Email == "" {
+				return nil, fmt.Errorf("domain name and emailAddress are required")
+			}
+			if s.findDomainByName(opts.Name) != nil {
+				return nil, fmt.Errorf("domain %s already exists", opts.Name)
+			}
+		}
+		for _, opts := range body.Domains {
+			d := s.addDomain(opts)
+			created = append(created, domains.DomainList{
+				ID:        d.show.ID,
+				Created:   d.show.Created,
+				Updated:   d.show.Updated,
+				Email:     d.show.EmailAddress,
+				AccountID: d.show.AccountID,
+				Name:      d.show.Name,
+			})
+		}
+		return map[string]any{"domains": created}, nil
+	})
+}
+
+func (s *Server) updateDomain(w http.ResponseWriter, r *http.Request) {
+	var opts domains.UpdateOpts
+	if !readJSON(w, r, &opts) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := r.PathValue("domID")
+	if s.findDomain(id) == nil {
+		writeNotFound(w, "domain")
+		return
+	}
+
+	s.queueJob(w, r, func() (map[string]any, error) {
+		d := s.findDomain(id)
+		if d == nil {
+			return nil, errNotFound
+		}
+		if opts.Email != "" {
+			d.show.EmailAddress = opts.Email
+		}
+		if opts.TTL != 0 {
+			d.show.TTL = uint64(opts.TTL)
+		}
+		if opts.Comment != "" {
+			d.show.Comment = opts.Comment
+		}
+		d.show.Updated = timestamp()
+		return nil, nil
+	})
+}
+
+func (s *Server) deleteDomain(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := r.PathValue("domID")
+	if s.findDomain(id) == nil {
+		writeNotFound(w, "domain")
+		return
+	}
+
+	s.queueJob(w, r, func() (map[string]any, error) {
+		s.domains = slices.DeleteFunc(s.domains, func(d *domain) bool {
+			return d.show.ID == id
+		})
+		return nil, nil
+	})
+}
+
+func (s *Server) listRecords(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d := s.findDomain(r.PathValue("domID"))
+	if d == nil {
+		writeNotFound(w, "domain")
+		return
+	}
+
+	query := r.URL.Query()
+	var matched []records.RecordList
+	for _, rec := range d.records {
+		if name := query.Get("name"); name != "" && !strings.EqualFold(rec.Name, name) {
+			continue
+		}
+		if typ := query.Get("type"); typ != "" && !strings.EqualFold(rec.Type, typ) {
+			continue
+		}
+		if data := query.Get("data"); data != "" && rec.Data != data {
+			continue
+		}
+		matched = append(matched, records.RecordList{
+			ID:       rec.ID,
+			Name:     rec.Name,
+			Type:     rec.Type,
+			Data:     rec.Data,
+			TTL:      rec.TTL,
+			Priority: rec.Priority,
+			Comment:  rec.Comment,
+		})
+	}
+
+	page, links, err := s.paginate(r, len(matched))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Bad Request", err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"records":      matched[page.start:page.end],
+		"totalEntries": len(matched),
+		"links":        links,
+	})
+}
+
+func (s *Server) getRecord(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d := s.findDomain(r.PathValue("domID"))
+	if d == nil {
+		writeNotFound(w, "domain")
+		return
+	}
+	rec := findRecord(d, r.PathValue("id"))
+	if rec == nil {
+		writeNotFound(w, "record")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, rec)
+}
+
+func (s *Server) createRecord(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Records []records.CreateOpts `json:"records"`
+	}
+	if !readJSON(w, r, &body) {
+		return
+	}
+	if len(body.Records) == 0 {
+		writeError(w, http.StatusBadRequest, "Bad Request", "No records were provided")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	domID := r.PathValue("domID")
+	if s.findDomain(domID) == nil {
+		writeNotFound(w, "domain")
+		return
+	}
+
+	s.queueJob(w, r, func() (map[string]any, error) {
+		d := s.findDomain(domID)
+		if d == nil {
+			return nil, errNotFound
+		}
+		for _, opts := range body.Records {
+			if err := validateRecord(d, opts.Name, opts.Type, opts.Data); err != nil {
+				return nil, err
+			}
+		}
+
+		created := make([]records.RecordList, 0, len(body.Records))
+		for _, opts := range body.Records {
+			rec := s.addRecord(d, opts)
+			created = append(created, records.RecordList{
+				ID:       rec.ID,
+				Name:     rec.Name,
+				Type:     rec.Type,
+				Data:     rec.Data,
+				TTL:      rec.TTL,
+				Priority: rec.Priority,
+				Comment:  rec.Comment,
+			})
+		}
+		return map[string]any{"records": created}, nil
+	})
+}
+
+func (s *Server) updateRecord(w http.ResponseWriter, r *http.Request) {
+	// a pointer tells an empty comment, which clears it, from none
+	var opts struct {
+		records.UpdateOpts
+		Comment *string `json:"comment"`
+	}
+	if !readJSON(w, r, &opts) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	domID, id := r.PathValue("domID"), r.PathValue("id")
+	d := s.findDomain(domID)
+	if d == nil {
+		writeNotFound(w, "domain")
+		return
+	}
+	if findRecord(d, id) == nil {
+		writeNotFound(w, "record")
+		return
+	}
+
+	s.queueJob(w, r, func() (map[string]any, error) {
+		d := s.findDomain(domID)
+		if d == nil {
+			return nil, errNotFound
+		}
+		rec := findRecord(d, id)
+		if rec == nil {
+			return nil, errNotFound
+		}
+		if opts.Name != "" {
+			if err := validateRecord(d, opts.Name, rec.Type, rec.Data); err != nil {
+				return nil, err
+			}
+			rec.Name = opts.Name
+		}
+		if opts.Data != "" {
+			rec.Data = opts.Data
+		}
+		if opts.TTL != 0 {
+			rec.TTL = opts.TTL
+		}
+		if opts.Priority != 0 {
+			rec.Priority = opts.Priority
+		}
+		if opts.Comment != nil {
+			rec.Comment = *opts.Comment
+		}
+		rec.Updated = timestamp()
+		return nil, nil
+	})
+}
+
+func (s *Server) deleteRecord(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	domID, id := r.PathValue("domID"), r.PathValue("id")
+	d := s.findDomain(domID)
+	if d == nil {
+		writeNotFound(w, "domain")
+		return
+	}
+	if findRecord(d, id) == nil {
+		writeNotFound(w, "record")
+		return
+	}
+
+	s.queueJob(w, r, func() (map[string]any, error) {
+		if d := s.findDomain(domID); d != nil {
+			d.records = slices.DeleteFunc(d.records, func(rec *records.RecordShow) bool {
+				return rec.ID == id
+			})
+		}
+		return nil, nil
+	})
+}
+
+func (s *Server) getStatus(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	j, ok := s.jobs[r.PathValue("jobID")]
+	if !ok {
+		writeNotFound(w, "job")
+		return
+	}
+
+	if j.message.Status == "RUNNING" {
+		if j.polls < s.PendingPolls {
+			j.polls++
+		} else if j.apply != nil {
+			response, err := j.apply()
+			j.apply = nil
+			if err != nil {
+				j.message.Status = "ERROR"
+				j.message.Error = map[string]any{
+					"code":    400,
+					"message": "Bad Request",
+					"details": err.Error(),
+				}
+			} else {
+				j.message.Status = "COMPLETED"
+				j.message.Response = response
+			}
+		}
+	}
+
+	message := j.message
+	if r.URL.Query().Get("showDetails") != "true" {
+		message.Response = nil
+		message.Error = nil
+	}
+	writeJSON(w, http.StatusOK, message)
+}
+
+// queueJob records an async job for the request and writes the 202 response
+// pointing at its callback URL. The caller must hold s.mu.
+func (s *Server) queueJob(w http.ResponseWriter, r *http.Request, apply func() (map[string]any, error)) {
+	s.nextID++
+	jobID := fmt.Sprintf("job-%d", s.nextID)
+
+	if s.failNext != nil {
+		details := *s.failNext
+		apply = func() (map[string]any, error) {
+			return nil, fmt.Errorf("%s", details)
+		}
+		s.failNext = nil
+	}
+
+	j := &job{
+		message: goclouddns.AsyncMessage{
+			CallbackURL: s.Endpoint() + "status/" + jobID,
+			JobID:       jobID,
+			RequestURL:  s.URL + r.URL.RequestURI(),
+			Verb:        r.Method,
+			Status:      "RUNNING",
+		},
+		apply: apply,
+	}
+	s.jobs[jobID] = j
+
+	writeJSON(w, http.StatusAccepted, j.message)
+}
+
+type pageBounds struct {
+	start int
+	end   int
+}
+
+// paginate works out the slice of results for the request's limit and offset
+// and the links the real API would return alongside it.
+func (s *Server) paginate(r *http.Request, total int) (pageBounds, []gophercloud.Link, error) {
+	query := r.URL.Query()
+
+	limit := s.PageSize
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return pageBounds{}, nil, fmt.Errorf("invalid limit %q", v)
+		}
+		limit = min(n, s.PageSize)
+	}
+
+	offset := 0
+	if v := query.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return pageBounds{}, nil, fmt.Errorf("invalid offset %q", v)
+		}
+		offset = n
+	}
+
+	page := pageBounds{start: min(offset, total), end: min(offset+limit, total)}
+
+	var links []gophercloud.Link
+	linkTo := func(rel string, offset int) {
+		q := url.Values{}
+		for k, v := range query {
+			q[k] = v
+		}
+		q.Set("limit", strconv.Itoa(limit))
+		q.Set("offset", strconv.Itoa(offset))
+		links = append(links, gophercloud.Link{Href: s.URL + r.URL.Path + "?" + q.Encode(), Rel: rel})
+	}
+	if offset > 0 {
+		linkTo("previous", max(offset-limit, 0))
+	}
+	if page.end < total {
+		linkTo("next", page.end)
+	}
+
+	return page, links, nil
+}
+
+func (s *Server) addDomain(opts domains.CreateOpts) *domain {
+	s.nextID++
+	now := timestamp()
+	ttl := opts.TTL
+	if ttl == 0 {
+		ttl = 3600
+	}
+
+	d := &domain{}
+	d.show.ID = strconv.Itoa(1000000 + s.nextID)
+	d.show.AccountID = TenantID
+	d.show.Name = strings.ToLower(opts.Name)
+	d.show.EmailAddress = opts.Email
+	d.show.TTL = uint64(ttl)
+	d.show.Comment = opts.Comment
+	d.show.Created = now
+	d.show.Updated = now
+	for _, ns := range s.Nameservers {
+		d.show.Nameservers = append(d.show.Nameservers, struct {
+			Name string `json:"name"`
+		}{ns})
+	}
+
+	s.domains = append(s.domains, d)
+	return d
+}
+
+func (s *Server) addRecord(d *domain, opts records.CreateOpts) *records.RecordShow {
+	s.nextID++
+	now := timestamp()
+	ttl := opts.TTL
+	if ttl == 0 {
+		ttl = 3600
+	}
+
+	rec := &records.RecordShow{
+		ID:       fmt.Sprintf("%s-%d", strings.ToUpper(opts.Type), 2000000+s.nextID),
+		Name:     strings.ToLower(opts.Name),
+		Type:     strings.ToUpper(opts.Type),
+		Data:     opts.Data,
+		TTL:      ttl,
+		Priority: opts.Priority,
+		Comment:  opts.Comment,
+		Created:  now,
+		Updated:  now,
+	}
+
+	d.records = append(d.records, rec)
+	return rec
+}
+
+func (s *Server) findDomain(id string) *domain {
+	for _, d := range s.domains {
+		if d.show.ID == id {
+			return d
+		}
+	}
+	return nil
+}
+
+func (s *Server) findDomainByName(name string) *domain {
+	for _, d := range s.domains {
+		if strings.EqualFold(d.show.Name, name) {
+			return d
+		}
+	}
+	return nil
+}
+
+func findRecord(d *domain, id string) *records.RecordShow {
+	for _, rec := range d.records {
+		if rec.ID == id {
+			return rec
+		}
+	}
+	return nil
+}
+
+// validateRecord applies the checks the real API rejects records for most
+// often: names outside the zone and missing required fields.
+func validateRecord(d *domain, name string, typ string, data string) error {
+	if name == "" || typ == "" || data == "" {
+		return fmt.Errorf("record name, type and data are required")
+	}
+
+	name = strings.ToLower(name)
+	if name != d.show.Name && !strings.HasSuffix(name, "."+d.show.Name) {
+		return fmt.Errorf("record name %s is not within domain %s", name, d.show.Name)
+	}
+	return nil
+}
+
+var errNotFound = errors.New("object not found")
+
+func timestamp() string {
+	return time.Now().UTC().Format("2006-01-02T15:04:05.000Z07:00")
+}
+
+func readJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, "Bad Request", err.Error())
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, message string, details string) {
+	writeJSON(w, code, map[string]any{
+		"code":    code,
+		"message": message,
+		"details": details,
+	})
+}
+
+func writeNotFound(w http.ResponseWriter, kind string) {
+	writeError(w, http.StatusNotFound, "Not Found", fmt.Sprintf("Object not Found. No %s with that ID", kind))
+}
@@ -0,0 +1,215 @@
+package fakedns
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/gophercloud/gophercloud/v2/pagination"
+
+	"github.com/rackerlabs/goclouddns/domains"
+	"github.com/rackerlabs/goclouddns/records"
+)
+
+func TestDomainLifecycle(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+
+	ctx := context.Background()
+	client := s.ServiceClient()
+
+	created, err := domains.Create(ctx, client, domains.CreateOpts{
+		Name:  "example.com",
+		Email: "admin@example.com",
+	}).Extract()
+	if err != nil {
+		t.Fatalf("Create() returned error: %v", err)
+	}
+	if created.Name != "example.com" || created.ID == "" {
+		t.Fatalf("unexpected created domain: %+v", created)
+	}
+
+	domain, err := domains.Get(ctx, client, created.ID).Extract()
+	if err != nil {
+		t.Fatalf("Get() returned error: %v", err)
+	}
+	if domain.TTL != 3600 {
+		t.Errorf("expected default TTL 3600, got %d", domain.TTL)
+	}
+	if len(domain.Nameservers) != 2 {
+		t.Errorf("expected 2 nameservers, got %d", len(domain.Nameservers))
+	}
+
+	if err := domains.Update(ctx, client, domain, domains.UpdateOpts{TTL: 300}).ExtractErr(); err != nil {
+		t.Fatalf("Update() returned error: %v", err)
+	}
+	if stored, _ := s.Domain(created.ID); stored.TTL != 300 {
+		t.Errorf("expected updated TTL 300, got %d", stored.TTL)
+	}
+
+	if err := domains.Delete(ctx, client, created.ID).ExtractErr(); err != nil {
+		t.Fatalf("Delete() returned error: %v", err)
+	}
+	if _, ok := s.Domain(created.ID); ok {
+		t.Errorf("expected domain to be deleted")
+	}
+}
+
+func TestRecordLifecycle(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+
+	ctx := context.Background()
+	client := s.ServiceClient()
+	domID := s.AddDomain(domains.CreateOpts{Name: "example.com", Email: "admin@example.com"})
+
+	created, err := records.Create(ctx, client, domID, records.CreateOpts{
+		Name: "www.example.com",
+		Type: "A",
+		Data: "10.5.19.11",
+		TTL:  300,
+	}).Extract()
+	if err != nil {
+		t.Fatalf("Create() returned error: %v", err)
+	}
+	if !strings.HasPrefix(created.ID, "A-") {
+		t.Fatalf("expected A- record ID, got %q", created.ID)
+	}
+
+	record, err := records.Get(ctx, client, domID, created.ID).Extract()
+	if err != nil {
+		t.Fatalf("Get() returned error: %v", err)
+	}
+
+	opts := records.UpdateOpts{Name: record.Name, Data: "10.5.19.12"}
+	if err := records.Update(ctx, client, domID, record, opts).ExtractErr(); err != nil {
+		t.Fatalf("Update() returned error: %v", err)
+	}
+	if stored, _ := s.Record(domID, created.ID); stored.Data != "10.5.19.12" {
+		t.Errorf("expected updated data, got %q", stored.Data)
+	}
+
+	if err := records.Delete(ctx, client, domID, created.ID).ExtractErr(); err != nil {
+		t.Fatalf("Delete() returned error: %v", err)
+	}
+	if _, ok := s.Record(domID, created.ID); ok {
+		t.Errorf("expected record to be deleted")
+	}
+}
+
+func TestListPagination(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	s.PageSize = 2
+
+	domID := s.AddDomain(domains.CreateOpts{Name: "example.com", Email: "admin@example.com"})
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		if _, err := s.AddRecord(domID, records.CreateOpts{Name: name + ".example.com", Type: "A", Data: "10.0.0.1"}); err != nil {
+			t.Fatalf("AddRecord() returned error: %v", err)
+		}
+	}
+
+	ctx := context.Background()
+	pages := 0
+	var names []string
+	err := records.List(ctx, s.ServiceClient(), domID, nil).EachPage(ctx, func(ctx context.Context, page pagination.Page) (bool, error) {
+		pages++
+		recordList, err := records.ExtractRecords(page)
+		for _, record := range recordList {
+			names = append(names, record.Name)
+		}
+		return true, err
+	})
+	if err != nil {
+		t.Fatalf("EachPage() returned error: %v", err)
+	}
+
+	if pages != 3 {
+		t.Errorf("expected 3 pages, got %d", pages)
+	}
+	if len(names) != 5 {
+		t.Errorf("expected 5 records, got %v", names)
+	}
+}
+
+func TestListDomainsFiltersByName(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+
+	s.AddDomain(domains.CreateOpts{Name: "example.com", Email: "admin@example.com"})
+	s.AddDomain(domains.CreateOpts{Name: "prod.example.com", Email: "admin@example.com"})
+	s.AddDomain(domains.CreateOpts{Name: "example.org", Email: "admin@example.com"})
+
+	ctx := context.Background()
+	page, err := domains.List(ctx, s.ServiceClient(), domains.ListOpts{Name: "example.com"}).AllPages(ctx)
+	if err != nil {
+		t.Fatalf("AllPages() returned error: %v", err)
+	}
+	domainList, err := domains.ExtractDomains(page)
+	if err != nil {
+		t.Fatalf("ExtractDomains() returned error: %v", err)
+	}
+	if len(domainList) != 2 {
+		t.Errorf("expected 2 matching domains, got %+v", domainList)
+	}
+}
+
+func TestPendingJobCompletesAfterPolling(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	s.PendingPolls = 1
+
+	ctx := context.Background()
+	created, err := domains.Create(ctx, s.ServiceClient(), domains.CreateOpts{
+		Name:  "example.com",
+		Email: "admin@example.com",
+	}).Extract()
+	if err != nil {
+		t.Fatalf("Create() returned error: %v", err)
+	}
+	if _, ok := s.Domain(created.ID); !ok {
+		t.Errorf("expected domain to exist once the job completed")
+	}
+}
+
+func TestFailedJobReturnsDetails(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	s.FailNextJob("Domain is restricted")
+
+	ctx := context.Background()
+	_, err := domains.Create(ctx, s.ServiceClient(), domains.CreateOpts{
+		Name:  "example.com",
+		Email: "admin@example.com",
+	}).Extract()
+	if err == nil || err.Error() != "Domain is restricted" {
+		t.Fatalf("expected job error, got %v", err)
+	}
+}
+
+func TestRecordOutsideDomainFails(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+
+	domID := s.AddDomain(domains.CreateOpts{Name: "example.com", Email: "admin@example.com"})
+
+	ctx := context.Background()
+	_, err := records.Create(ctx, s.ServiceClient(), domID, records.CreateOpts{
+		Name: "www.example.org",
+		Type: "A",
+		Data: "10.5.19.11",
+	}).Extract()
+	if err == nil || !strings.Contains(err.Error(), "not within domain") {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+}
+
+func TestMissingDomainReturnsNotFound(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+
+	_, err := domains.Get(context.Background(), s.ServiceClient(), "999").Extract()
+	if err == nil {
+		t.Fatal("expected not found error")
+	}
+}
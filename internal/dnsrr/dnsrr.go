@@ -0,0 +1,115 @@
+// Package dnsrr converts between Cloud DNS records and their DNS wire form.
+// Cloud DNS keeps the priority of MX and SRV records apart from their data
+// and stores host names without the trailing dot.
+package dnsrr
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/miekg/dns"
+
+	"github.com/rackerlabs/goclouddns/records"
+)
+
+// MinTTL is the lowest TTL Cloud DNS accepts.
+const MinTTL = 300
+
+// FromRecord renders a Cloud DNS record in wire form. It reports false for
+// types it does not know and data it cannot parse.
+func FromRecord(record records.RecordList) (dns.RR, bool) {
+	hdr := dns.RR_Header{
+		Name:  dns.CanonicalName(record.Name),
+		Class: dns.ClassINET,
+		Ttl:   uint32(record.TTL),
+	}
+
+	switch strings.ToUpper(record.Type) {
+	case "A":
+		ip := net.ParseIP(record.Data).To4()
+		if ip == nil {
+			return nil, false
+		}
+		hdr.Rrtype = dns.TypeA
+		return &dns.A{Hdr: hdr, A: ip}, true
+	case "AAAA":
+		ip := net.ParseIP(record.Data)
+		if ip == nil {
+			return nil, false
+		}
+		hdr.Rrtype = dns.TypeAAAA
+		return &dns.AAAA{Hdr: hdr, AAAA: ip}, true
+	case "CNAME":
+		hdr.Rrtype = dns.TypeCNAME
+		return &dns.CNAME{Hdr: hdr, Target: dns.Fqdn(record.Data)}, true
+	case "NS":
+		hdr.Rrtype = dns.TypeNS
+		return &dns.NS{Hdr: hdr, Ns: dns.Fqdn(record.Data)}, true
+	case "PTR":
+		hdr.Rrtype = dns.TypePTR
+		return &dns.PTR{Hdr: hdr, Ptr: dns.Fqdn(record.Data)}, true
+	case "MX":
+		hdr.Rrtype = dns.TypeMX
+		return &dns.MX{Hdr: hdr, Preference: uint16(record.Priority), Mx: dns.Fqdn(record.Data)}, true
+	case "TXT":
+		hdr.Rrtype = dns.TypeTXT
+		return &dns.TXT{Hdr: hdr, Txt: splitTXT(record.Data)}, true
+	case "SRV":
+		var weight, port uint16
+		var target string
+		if _, err := fmt.Sscanf(record.Data, "%d %d %s", &weight, &port, &target); err != nil {
+			return nil, false
+		}
+		hdr.Rrtype = dns.TypeSRV
+		return &dns.SRV{Hdr: hdr, Priority: uint16(record.Priority), Weight: weight, Port: port, Target: dns.Fqdn(target)}, true
+	default:
+		return nil, false
+	}
+}
+
+// ToCreateOpts converts rr into the options to create it in Cloud DNS,
+// raising its TTL to MinTTL.
+func ToCreateOpts(rr dns.RR) (records.CreateOpts, error) {
+	hdr := rr.Header()
+	opts := records.CreateOpts{
+		Name: strings.TrimSuffix(dns.CanonicalName(hdr.Name), "."),
+		Type: dns.TypeToString[hdr.Rrtype],
+		TTL:  uint(max(hdr.Ttl, MinTTL)),
+	}
+
+	switch v := rr.(type) {
+	case *dns.A:
+		opts.Data = v.A.String()
+	case *dns.AAAA:
+		opts.Data = v.AAAA.String()
+	case *dns.CNAME:
+		opts.Data = strings.TrimSuffix(v.Target, ".")
+	case *dns.NS:
+		opts.Data = strings.TrimSuffix(v.Ns, ".")
+	case *dns.PTR:
+		opts.Data = strings.TrimSuffix(v.Ptr, ".")
+	case *dns.MX:
+		opts.Data = strings.TrimSuffix(v.Mx, ".")
+		opts.Priority = uint(v.Preference)
+	case *dns.TXT:
+		opts.Data = strings.Join(v.Txt, "")
+	case *dns.SRV:
+		opts.Data = fmt.Sprintf("%d %d %s", v.Weight, v.Port, strings.TrimSuffix(v.Target, "."))
+		opts.Priority = uint(v.Priority)
+	default:
+		return opts, fmt.Errorf("unsupported record type %s", opts.Type)
+	}
+	return opts, nil
+}
+
+// splitTXT breaks long TXT data into the 255 byte strings the wire format
+// requires.
+func splitTXT(s string) []string {
+	var parts []string
+	for len(s) > 255 {
+		parts = append(parts, s[:255])
+		s = s[255:]
+	}
+	return append(parts, s)
+}
@@ -0,0 +1,340 @@
+// Package libdns adapts Cloud DNS to the libdns interfaces used by Caddy and
+// other ACME and dynamic DNS tools. Provider implements RecordGetter,
+// RecordAppender, RecordSetter, RecordDeleter and ZoneLister.
+//
+// Zones are fully qualified ("example.com.") and record names are relative
+// to them ("www", or "@" for the apex), as libdns expects. Cloud DNS does not
+// accept TTLs under five minutes, so shorter TTLs are raised to MinTTL.
+// Changes are not atomic: each record is its own Cloud DNS job.
+package libdns
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gophercloud/gophercloud/v2"
+	ldns "github.com/libdns/libdns"
+
+	"github.com/rackerlabs/goclouddns/domains"
+	"github.com/rackerlabs/goclouddns/records"
+)
+
+// MinTTL is the lowest TTL Cloud DNS accepts.
+const MinTTL = 300 * time.Second
+
+// Provider manages records in Cloud DNS domains through the libdns
+// interfaces. It is safe for concurrent use.
+type Provider struct {
+	client *gophercloud.ServiceClient
+
+	mu      sync.Mutex
+	zoneIDs map[string]string
+}
+
+// NewProvider returns a Provider using client.
+func NewProvider(client *gophercloud.ServiceClient) *Provider {
+	return &Provider{client: client, zoneIDs: map[string]string{}}
+}
+
+// GetRecords returns every record in zone.
+func (p *Provider) GetRecords(ctx context.Context, zone string) ([]ldns.Record, error) {
+	domID, err := p.zoneID(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := p.listRecords(ctx, domID)
+	if err != nil {
+		return nil, err
+	}
+
+	recs := make([]ldns.Record, 0, len(existing))
+	for _, record := range existing {
+		recs = append(recs, toLibdns(record, zone))
+	}
+	return recs, nil
+}
+
+// AppendRecords creates recs in zone and returns them as stored.
+func (p *Provider) AppendRecords(ctx context.Context, zone string, recs []ldns.Record) ([]ldns.Record, error) {
+	domID, err := p.zoneID(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	created := make([]ldns.Record, 0, len(recs))
+	for _, rec := range recs {
+		record, err := p.create(ctx, domID, zone, rec)
+		if err != nil {
+			return created, err
+		}
+		created = append(created, record)
+	}
+	return created, nil
+}
+
+// SetRecords makes recs the only records for each name and type they
+// cover, updating TTLs in place where the data is unchanged.
+func (p *Provider) SetRecords(ctx context.Context, zone string, recs []ldns.Record) ([]ldns.Record, error) {
+	domID, err := p.zoneID(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := p.listRecords(ctx, domID)
+	if err != nil {
+		return nil, err
+	}
+
+	desired := make([]records.CreateOpts, 0, len(recs))
+	rrsets := map[string]bool{}
+	for _, rec := range recs {
+		opts, err := fromLibdns(rec, zone)
+		if err != nil {
+			return nil, err
+		}
+		desired = append(desired, opts)
+		rrsets[rrsetKey(opts.Name, opts.Type)] = true
+	}
+
+	var set []ldns.Record
+	matched := make([]bool, len(desired))
+	for _, record := range existing {
+		if !rrsets[rrsetKey(record.Name, record.Type)] {
+			continue
+		}
+
+		i := indexOf(desired, matched, record)
+		if i < 0 {
+			if err := records.Delete(ctx, p.client, domID, record.ID).ExtractErr(); err != nil {
+				return set, fmt.Errorf("deleting %s %s: %w", record.Type, record.Name, err)
+			}
+			continue
+		}
+		matched[i] = true
+
+		if ttl := desired[i].TTL; ttl != 0 && ttl != record.TTL {
+			opts := records.UpdateOpts{Name: record.Name, Data: record.Data, TTL: ttl, Priority: record.Priority}
+			if err := records.Update(ctx, p.client, domID, &records.RecordShow{ID: record.ID}, opts).ExtractErr(); err != nil {
+				return set, fmt.Errorf("updating %s %s: %w", record.Type, record.Name, err)
+			}
+			record.TTL = ttl
+		}
+		set = append(set, toLibdns(record, zone))
+	}
+
+	for i, opts := range desired {
+		if matched[i] {
+			continue
+		}
+		record, err := records.Create(ctx, p.client, domID, opts).Extract()
+		if err != nil {
+			return set, fmt.Errorf("creating %s %s: %w", opts.Type, opts.Name, err)
+		}
+		set = append(set, toLibdns(*record, zone))
+	}
+
+	return set, nil
+}
+
+// DeleteRecords removes the records in zone matching recs. An empty type,
+// TTL or data in an input record matches any value.
+func (p *Provider) DeleteRecords(ctx context.Context, zone string, recs []ldns.Record) ([]ldns.Record, error) {
+	domID, err := p.zoneID(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := p.listRecords(ctx, domID)
+	if err != nil {
+		return nil, err
+	}
+
+	var deleted []ldns.Record
+	removed := map[string]bool{}
+	for _, rec := range recs {
+		want := rec.RR()
+		for _, record := range existing {
+			if removed[record.ID] {
+				continue
+			}
+
+			have := toLibdns(record, zone).RR()
+			if have.Name != ldns.RelativeName(ldns.AbsoluteName(want.Name, zone), zone) {
+				continue
+			}
+			if want.Type != "" && !strings.EqualFold(have.Type, want.Type) {
+				continue
+			}
+			if want.TTL != 0 && have.TTL != roundTTL(want.TTL) {
+				continue
+			}
+			if want.Data != "" && normalizeData(have.Type, have.Data) != normalizeData(have.Type, want.Data) {
+				continue
+			}
+
+			if err := records.Delete(ctx, p.client, domID, record.ID).ExtractErr(); err != nil {
+				return deleted, fmt.Errorf("deleting %s %s: %w", record.Type, record.Name, err)
+			}
+			removed[record.ID] = true
+			deleted = append(deleted, toLibdns(record, zone))
+		}
+	}
+
+	return deleted, nil
+}
+
+// ListZones returns every domain on the account as a libdns zone.
+func (p *Provider) ListZones(ctx context.Context) ([]ldns.Zone, error) {
+	page, err := domains.List(ctx, p.client, nil).AllPages(ctx)
+	if err != nil {
+		return nil, err
+	}
+	domainList, err := domains.ExtractDomains(page)
+	if err != nil {
+		return nil, err
+	}
+
+	zones := make([]ldns.Zone, 0, len(domainList))
+	for _, domain := range domainList {
+		zones = append(zones, ldns.Zone{Name: domain.Name + "."})
+	}
+	return zones, nil
+}
+
+func (p *Provider) create(ctx context.Context, domID string, zone string, rec ldns.Record) (ldns.Record, error) {
+	opts, err := fromLibdns(rec, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	record, err := records.Create(ctx, p.client, domID, opts).Extract()
+	if err != nil {
+		return nil, fmt.Errorf("creating %s %s: %w", opts.Type, opts.Name, err)
+	}
+	return toLibdns(*record, zone), nil
+}
+
+// zoneID looks up the Cloud DNS domain ID for zone, remembering it for
+// later calls.
+func (p *Provider) zoneID(ctx context.Context, zone string) (string, error) {
+	name := strings.ToLower(strings.TrimSuffix(zone, "."))
+
+	p.mu.Lock()
+	id, ok := p.zoneIDs[name]
+	p.mu.Unlock()
+	if ok {
+		return id, nil
+	}
+
+	id, err := domains.IDFromName(ctx, p.client, name)
+	if err != nil {
+		return "", err
+	}
+
+	p.mu.Lock()
+	p.zoneIDs[name] = id
+	p.mu.Unlock()
+	return id, nil
+}
+
+func (p *Provider) listRecords(ctx context.Context, domID string) ([]records.RecordList, error) {
+	page, err := records.List(ctx, p.client, domID, nil).AllPages(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return records.ExtractRecords(page)
+}
+
+// toLibdns converts a Cloud DNS record into the typed libdns record for its
+// type, falling back to a plain RR when it cannot be parsed.
+func toLibdns(record records.RecordList, zone string) ldns.Record {
+	data := record.Data
+	switch strings.ToUpper(record.Type) {
+	case "MX", "SRV":
+		data = fmt.Sprintf("%d %s", record.Priority, record.Data)
+	}
+
+	rr := ldns.RR{
+		Name: ldns.RelativeName(strings.ToLower(record.Name), zone),
+		TTL:  time.Duration(record.TTL) * time.Second,
+		Type: strings.ToUpper(record.Type),
+		Data: data,
+	}
+
+	parsed, err := rr.Parse()
+	if err != nil {
+		return rr
+	}
+	return parsed
+}
+
+// fromLibdns converts a libdns record into the options to create it in
+// Cloud DNS, splitting the priority out of MX and SRV data.
+func fromLibdns(rec ldns.Record, zone string) (records.CreateOpts, error) {
+	rr := rec.RR()
+	opts := records.CreateOpts{
+		Name: strings.ToLower(strings.TrimSuffix(ldns.AbsoluteName(rr.Name, zone), ".")),
+		Type: strings.ToUpper(rr.Type),
+		Data: normalizeData(rr.Type, rr.Data),
+	}
+	if rr.TTL > 0 {
+		opts.TTL = uint(roundTTL(rr.TTL) / time.Second)
+	}
+
+	switch opts.Type {
+	case "MX", "SRV":
+		priority, data, ok := strings.Cut(opts.Data, " ")
+		if !ok {
+			return opts, fmt.Errorf("invalid %s data %q: expected a priority", opts.Type, rr.Data)
+		}
+		n, err := strconv.ParseUint(priority, 10, 16)
+		if err != nil {
+			return opts, fmt.Errorf("invalid %s data %q: %w", opts.Type, rr.Data, err)
+		}
+		opts.Priority = uint(n)
+		opts.Data = data
+	}
+
+	return opts, nil
+}
+
+// normalizeData strips the trailing dots libdns callers may put on host
+// names, since Cloud DNS stores them without.
+func normalizeData(recordType string, data string) string {
+	switch strings.ToUpper(recordType) {
+	case "CNAME", "NS", "MX", "SRV":
+		return strings.TrimSuffix(data, ".")
+	default:
+		return data
+	}
+}
+
+// roundTTL raises ttl to Cloud DNS's minimum and truncates it to whole
+// seconds.
+func roundTTL(ttl time.Duration) time.Duration {
+	return max(ttl, MinTTL).Truncate(time.Second)
+}
+
+func rrsetKey(name string, recordType string) string {
+	return strings.ToLower(name) + " " + strings.ToUpper(recordType)
+}
+
+// indexOf returns the first unmatched desired record with the same name,
+// type and data as record, or -1.
+func indexOf(desired []records.CreateOpts, matched []bool, record records.RecordList) int {
+	for i, opts := range desired {
+		if matched[i] {
+			continue
+		}
+		if rrsetKey(opts.Name, opts.Type) == rrsetKey(record.Name, record.Type) &&
+			opts.Data == record.Data && opts.Priority == record.Priority {
+			return i
+		}
+	}
+	return -1
+}
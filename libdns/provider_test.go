@@ -0,0 +1,158 @@
+package libdns
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+	"time"
+
+	ldns "github.com/libdns/libdns"
+
+	"github.com/rackerlabs/goclouddns/domains"
+	"github.com/rackerlabs/goclouddns/fakedns"
+	"github.com/rackerlabs/goclouddns/records"
+)
+
+const zone = "example.com."
+
+func TestGetRecordsReturnsTypedRelativeRecords(t *testing.T) {
+	server, domID := newTestServer(t)
+	addRecord(t, server, domID, records.CreateOpts{Name: "www.example.com", Type: "A", Data: "10.0.0.1", TTL: 300})
+	addRecord(t, server, domID, records.CreateOpts{Name: "example.com", Type: "MX", Data: "mail.example.com", TTL: 600, Priority: 10})
+
+	recs, err := NewProvider(server.ServiceClient()).GetRecords(context.Background(), zone)
+	if err != nil {
+		t.Fatalf("GetRecords() returned error: %v", err)
+	}
+	if len(recs) != 2 {
+		t.Fatalf("expected 2 records, got %+v", recs)
+	}
+
+	addr, ok := recs[0].(ldns.Address)
+	if !ok || addr.Name != "www" || addr.IP != netip.MustParseAddr("10.0.0.1") || addr.TTL != 5*time.Minute {
+		t.Errorf("unexpected A record %#v", recs[0])
+	}
+	mx, ok := recs[1].(ldns.MX)
+	if !ok || mx.Name != "@" || mx.Preference != 10 || mx.Target != "mail.example.com" {
+		t.Errorf("unexpected MX record %#v", recs[1])
+	}
+}
+
+func TestAppendRecordsRaisesShortTTL(t *testing.T) {
+	server, domID := newTestServer(t)
+
+	created, err := NewProvider(server.ServiceClient()).AppendRecords(context.Background(), zone, []ldns.Record{
+		ldns.TXT{Name: "_acme-challenge", TTL: time.Minute, Text: "token"},
+	})
+	if err != nil {
+		t.Fatalf("AppendRecords() returned error: %v", err)
+	}
+	if len(created) != 1 || created[0].RR().TTL != MinTTL {
+		t.Fatalf("expected one record with TTL raised to %s, got %+v", MinTTL, created)
+	}
+
+	txt := listRecords(t, server, domID)
+	if len(txt) != 1 || txt[0].Name != "_acme-challenge.example.com" {
+		t.Errorf("expected fully qualified record in Cloud DNS, got %+v", txt)
+	}
+}
+
+func TestSetRecordsReplacesRRset(t *testing.T) {
+	server, domID := newTestServer(t)
+	keepID := addRecord(t, server, domID, records.CreateOpts{Name: "www.example.com", Type: "A", Data: "10.0.0.1", TTL: 300})
+	addRecord(t, server, domID, records.CreateOpts{Name: "www.example.com", Type: "A", Data: "10.0.0.2", TTL: 300})
+	otherID := addRecord(t, server, domID, records.CreateOpts{Name: "www.example.com", Type: "TXT", Data: "hello", TTL: 300})
+
+	set, err := NewProvider(server.ServiceClient()).SetRecords(context.Background(), zone, []ldns.Record{
+		ldns.Address{Name: "www", TTL: time.Hour, IP: netip.MustParseAddr("10.0.0.1")},
+		ldns.Address{Name: "www", TTL: time.Hour, IP: netip.MustParseAddr("10.0.0.3")},
+	})
+	if err != nil {
+		t.Fatalf("SetRecords() returned error: %v", err)
+	}
+	if len(set) != 2 {
+		t.Fatalf("expected 2 records set, got %+v", set)
+	}
+
+	if kept, _ := server.Record(domID, keepID); kept.TTL != 3600 {
+		t.Errorf("expected unchanged record's TTL to be updated in place, got %d", kept.TTL)
+	}
+	if _, ok := server.Record(domID, otherID); !ok {
+		t.Errorf("expected records of other types to be left alone")
+	}
+
+	var data []string
+	for _, record := range listRecords(t, server, domID) {
+		if record.Type == "A" {
+			data = append(data, record.Data)
+		}
+	}
+	if len(data) != 2 || data[0] != "10.0.0.1" || data[1] != "10.0.0.3" {
+		t.Errorf("unexpected A records after set: %v", data)
+	}
+}
+
+func TestDeleteRecordsMatchesEmptyFieldsAsWildcards(t *testing.T) {
+	server, domID := newTestServer(t)
+	addRecord(t, server, domID, records.CreateOpts{Name: "_acme-challenge.example.com", Type: "TXT", Data: "one"})
+	addRecord(t, server, domID, records.CreateOpts{Name: "_acme-challenge.example.com", Type: "TXT", Data: "two"})
+	keepID := addRecord(t, server, domID, records.CreateOpts{Name: "www.example.com", Type: "TXT", Data: "one"})
+
+	deleted, err := NewProvider(server.ServiceClient()).DeleteRecords(context.Background(), zone, []ldns.Record{
+		ldns.RR{Name: "_acme-challenge", Type: "TXT"},
+	})
+	if err != nil {
+		t.Fatalf("DeleteRecords() returned error: %v", err)
+	}
+	if len(deleted) != 2 {
+		t.Errorf("expected 2 records deleted, got %+v", deleted)
+	}
+	if _, ok := server.Record(domID, keepID); !ok {
+		t.Errorf("expected other names to be left alone")
+	}
+}
+
+func TestListZones(t *testing.T) {
+	server, _ := newTestServer(t)
+
+	zones, err := NewProvider(server.ServiceClient()).ListZones(context.Background())
+	if err != nil {
+		t.Fatalf("ListZones() returned error: %v", err)
+	}
+	if len(zones) != 1 || zones[0].Name != zone {
+		t.Errorf("unexpected zones %+v", zones)
+	}
+}
+
+func newTestServer(t *testing.T) (*fakedns.Server, string) {
+	t.Helper()
+
+	server := fakedns.NewServer()
+	t.Cleanup(server.Close)
+	return server, server.AddDomain(domains.CreateOpts{Name: "example.com", Email: "admin@example.com"})
+}
+
+func addRecord(t *testing.T, server *fakedns.Server, domID string, opts records.CreateOpts) string {
+	t.Helper()
+
+	id, err := server.AddRecord(domID, opts)
+	if err != nil {
+		t.Fatalf("AddRecord() returned error: %v", err)
+	}
+	return id
+}
+
+func listRecords(t *testing.T, server *fakedns.Server, domID string) []records.RecordList {
+	t.Helper()
+
+	ctx := context.Background()
+	page, err := records.List(ctx, server.ServiceClient(), domID, nil).AllPages(ctx)
+	if err != nil {
+		t.Fatalf("List() returned error: %v", err)
+	}
+	recordList, err := records.ExtractRecords(page)
+	if err != nil {
+		t.Fatalf("ExtractRecords() returned error: %v", err)
+	}
+	return recordList
+}
@@ -0,0 +1,45 @@
+package goclouddns
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/gophercloud/gophercloud/v2"
+)
+
+// discardLogger is used by clients without a logger of their own, so that
+// nothing is ever written to the process-wide logger.
+var discardLogger = slog.New(slog.DiscardHandler)
+
+// SetLogger makes the calls made through client log to logger. Requests
+// and job polls are logged at debug level. A nil logger turns logging off,
+// which is the default.
+func SetLogger(client *gophercloud.ServiceClient, logger *slog.Logger) {
+	setOption(client, func(o *clientOptions) { o.logger = logger })
+}
+
+// Logger returns the logger set on client with SetLogger, or a logger that
+// discards everything.
+func Logger(client *gophercloud.ServiceClient) *slog.Logger {
+	if client != nil {
+		if logger := optionsFor(client).logger; logger != nil {
+			return logger
+		}
+	}
+	return discardLogger
+}
+
+// LogRequest logs a request made through client that started at start.
+// The domains and records packages call it after each request.
+func LogRequest(ctx context.Context, client *gophercloud.ServiceClient, method string, url string, start time.Time, err error) {
+	attrs := []slog.Attr{
+		slog.String("method", method),
+		slog.String("url", url),
+		slog.Duration("duration", time.Since(start)),
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	Logger(client).LogAttrs(ctx, slog.LevelDebug, "request", attrs...)
+}
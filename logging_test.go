@@ -0,0 +1,85 @@
+package goclouddns_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/rackerlabs/goclouddns"
+	"github.com/rackerlabs/goclouddns/domains"
+	"github.com/rackerlabs/goclouddns/fakedns"
+	"github.com/rackerlabs/goclouddns/records"
+)
+
+func TestLoggerRecordsCreate(t *testing.T) {
+	server := fakedns.NewServer()
+	defer server.Close()
+	server.PendingPolls = 1
+	domID := server.AddDomain(domains.CreateOpts{Name: "example.com", Email: "admin@example.com"})
+
+	var global bytes.Buffer
+	log.SetOutput(&global)
+	defer log.SetOutput(os.Stderr)
+
+	var buf bytes.Buffer
+	client := server.ServiceClient()
+	goclouddns.SetLogger(client, slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+
+	opts := records.CreateOpts{Name: "www.example.com", Type: "A", Data: "192.0.2.10"}
+	if _, err := records.Create(t.Context(), client, domID, opts).Extract(); err != nil {
+		t.Fatalf("Create() returned error: %v", err)
+	}
+
+	var entries []map[string]any
+	for _, line := range bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n")) {
+		var entry map[string]any
+		if err := json.Unmarshal(line, &entry); err != nil {
+			t.Fatalf("invalid log line %q: %v", line, err)
+		}
+		entries = append(entries, entry)
+	}
+
+	byMsg := map[string][]map[string]any{}
+	for _, entry := range entries {
+		if entry["level"] != "DEBUG" {
+			t.Errorf("expected debug level, got %v", entry)
+		}
+		byMsg[entry["msg"].(string)] = append(byMsg[entry["msg"].(string)], entry)
+	}
+
+	if len(byMsg["request"]) != 1 {
+		t.Fatalf("expected one request entry, got %v", entries)
+	}
+	request := byMsg["request"][0]
+	if request["method"] != "POST" || request["url"] != client.ServiceURL("domains", domID, "records") || request["duration"] == nil {
+		t.Errorf("unexpected request entry %v", request)
+	}
+
+	if n := len(byMsg["job poll"]); n != 2 {
+		t.Errorf("expected 2 job poll entries, got %d", n)
+	}
+
+	if len(byMsg["job done"]) != 1 {
+		t.Fatalf("expected one job done entry, got %v", entries)
+	}
+	done := byMsg["job done"][0]
+	if done["job_id"] == "" || done["status"] != "COMPLETED" || done["polls"] != float64(2) || done["duration"] == nil {
+		t.Errorf("unexpected job done entry %v", done)
+	}
+
+	if global.Len() != 0 {
+		t.Errorf("expected nothing on the standard logger, got %q", global.String())
+	}
+}
+
+func TestLoggerDefaultDiscards(t *testing.T) {
+	server := fakedns.NewServer()
+	defer server.Close()
+
+	if goclouddns.Logger(server.ServiceClient()).Enabled(t.Context(), slog.LevelError) {
+		t.Errorf("expected the default logger to discard everything")
+	}
+}
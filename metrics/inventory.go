@@ -0,0 +1,189 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gophercloud/gophercloud/v2"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/rackerlabs/goclouddns"
+	"github.com/rackerlabs/goclouddns/domains"
+	"github.com/rackerlabs/goclouddns/records"
+)
+
+// TTLBuckets are the upper bounds, in seconds, of the record TTL histogram.
+var TTLBuckets = []float64{300, 600, 900, 1800, 3600, 7200, 14400, 43200, 86400}
+
+var (
+	domainsDesc = prometheus.NewDesc(
+		"clouddns_domains", "Domains on the account.", nil, nil)
+	recordsDesc = prometheus.NewDesc(
+		"clouddns_records", "Records by domain and type.", []string{"domain", "type"}, nil)
+	ttlDesc = prometheus.NewDesc(
+		"clouddns_record_ttl_seconds", "Distribution of record TTLs by type.", []string{"type"}, nil)
+	lastSuccessDesc = prometheus.NewDesc(
+		"clouddns_inventory_last_success_timestamp_seconds", "When the inventory was last refreshed successfully.", nil, nil)
+	refreshDurationDesc = prometheus.NewDesc(
+		"clouddns_inventory_refresh_duration_seconds", "How long the last successful refresh took.", nil, nil)
+	refreshErrorsDesc = prometheus.NewDesc(
+		"clouddns_inventory_refresh_errors_total", "Refreshes that failed.", nil, nil)
+)
+
+type recordKey struct {
+	domain     string
+	recordType string
+}
+
+// Inventory is a prometheus.Collector reporting the domains and records
+// found by the last successful Refresh. Scrapes never call the API.
+type Inventory struct {
+	client *gophercloud.ServiceClient
+
+	mu            sync.Mutex
+	domains       int
+	records       map[recordKey]int
+	ttls          map[string][]uint
+	lastSuccess   time.Time
+	lastDuration  time.Duration
+	refreshErrors int
+}
+
+// NewInventory returns an Inventory walking the account behind client.
+func NewInventory(client *gophercloud.ServiceClient) *Inventory {
+	return &Inventory{client: client}
+}
+
+// Refresh lists every domain and record and replaces the reported counts.
+// On error the previous counts are kept.
+func (inv *Inventory) Refresh(ctx context.Context) error {
+	start := time.Now()
+	counts, ttls, domainCount, err := inv.walk(ctx)
+
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	if err != nil {
+		inv.refreshErrors++
+		return err
+	}
+
+	inv.domains = domainCount
+	inv.records = counts
+	inv.ttls = ttls
+	inv.lastSuccess = time.Now()
+	inv.lastDuration = time.Since(start)
+	return nil
+}
+
+// Run refreshes the inventory now and then every interval until ctx is
+// cancelled. Each refresh is bounded by the interval, and failures are
+// logged and retried on the next tick. It returns an error straight away
+// if interval is not positive, and nil once ctx is cancelled.
+func (inv *Inventory) Run(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		return fmt.Errorf("metrics: refresh interval must be positive, got %s", interval)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		refreshCtx, cancel := context.WithTimeout(ctx, interval)
+		if err := inv.Refresh(refreshCtx); err != nil && ctx.Err() == nil {
+			goclouddns.Logger(inv.client).Warn("metrics: refreshing inventory", "error", err)
+		}
+		cancel()
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (inv *Inventory) walk(ctx context.Context) (map[recordKey]int, map[string][]uint, int, error) {
+	page, err := domains.List(ctx, inv.client, nil).AllPages(ctx)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	domainList, err := domains.ExtractDomains(page)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	counts := map[recordKey]int{}
+	ttls := map[string][]uint{}
+	for _, domain := range domainList {
+		page, err := records.List(ctx, inv.client, domain.ID, nil).AllPages(ctx)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		recordList, err := records.ExtractRecords(page)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+
+		for _, record := range recordList {
+			recordType := strings.ToUpper(record.Type)
+			counts[recordKey{domain.Name, recordType}]++
+			ttls[recordType] = append(ttls[recordType], record.TTL)
+		}
+	}
+	return counts, ttls, len(domainList), nil
+}
+
+// Describe implements prometheus.Collector.
+func (inv *Inventory) Describe(ch chan<- *prometheus.Desc) {
+	ch <- domainsDesc
+	ch <- recordsDesc
+	ch <- ttlDesc
+	ch <- lastSuccessDesc
+	ch <- refreshDurationDesc
+	ch <- refreshErrorsDesc
+}
+
+// Collect implements prometheus.Collector.
+func (inv *Inventory) Collect(ch chan<- prometheus.Metric) {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+
+	ch <- prometheus.MustNewConstMetric(refreshErrorsDesc, prometheus.CounterValue, float64(inv.refreshErrors))
+	if inv.lastSuccess.IsZero() {
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(domainsDesc, prometheus.GaugeValue, float64(inv.domains))
+	ch <- prometheus.MustNewConstMetric(lastSuccessDesc, prometheus.GaugeValue, float64(inv.lastSuccess.Unix()))
+	ch <- prometheus.MustNewConstMetric(refreshDurationDesc, prometheus.GaugeValue, inv.lastDuration.Seconds())
+
+	for key, n := range inv.records {
+		ch <- prometheus.MustNewConstMetric(recordsDesc, prometheus.GaugeValue, float64(n), key.domain, key.recordType)
+	}
+	for recordType, values := range inv.ttls {
+		ch <- ttlHistogram(recordType, values)
+	}
+}
+
+func ttlHistogram(recordType string, values []uint) prometheus.Metric {
+	sorted := slices.Sorted(slices.Values(values))
+
+	var sum float64
+	for _, v := range sorted {
+		sum += float64(v)
+	}
+
+	buckets := make(map[float64]uint64, len(TTLBuckets))
+	i := 0
+	for _, bound := range TTLBuckets {
+		for i < len(sorted) && float64(sorted[i]) <= bound {
+			i++
+		}
+		buckets[bound] = uint64(i)
+	}
+	return prometheus.MustNewConstHistogram(ttlDesc, uint64(len(sorted)), sum, buckets, recordType)
+}
@@ -0,0 +1,61 @@
+// Package metrics exposes Cloud DNS inventory and API job latency as
+// Prometheus metrics.
+//
+// JobMetrics records how long each async job takes once it is set as a
+// client's job observer:
+//
+//	jobs := metrics.NewJobMetrics()
+//	prometheus.MustRegister(jobs)
+//	goclouddns.SetJobObserver(client, jobs)
+//
+// Inventory counts the domains and records on an account each time it is
+// refreshed.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/rackerlabs/goclouddns"
+)
+
+// JobMetrics is a goclouddns.JobObserver keeping a histogram of job
+// durations by operation and final status.
+type JobMetrics struct {
+	duration *prometheus.HistogramVec
+	polls    *prometheus.HistogramVec
+}
+
+// NewJobMetrics returns an empty JobMetrics. Register it with a Prometheus
+// registry to expose it.
+func NewJobMetrics() *JobMetrics {
+	return &JobMetrics{
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "clouddns_job_duration_seconds",
+			Help:    "Time spent waiting for Cloud DNS async jobs.",
+			Buckets: []float64{0.5, 1, 2, 5, 10, 20, 30, 60, 120, 300},
+		}, []string{"operation", "status"}),
+		polls: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "clouddns_job_polls",
+			Help:    "Callback URL fetches per Cloud DNS async job.",
+			Buckets: []float64{1, 2, 3, 5, 10, 20, 50, 100},
+		}, []string{"operation"}),
+	}
+}
+
+// ObserveJob implements goclouddns.JobObserver.
+func (m *JobMetrics) ObserveJob(job goclouddns.JobResult) {
+	m.duration.WithLabelValues(job.Operation, job.Status).Observe(job.Duration.Seconds())
+	m.polls.WithLabelValues(job.Operation).Observe(float64(job.Polls))
+}
+
+// Describe implements prometheus.Collector.
+func (m *JobMetrics) Describe(ch chan<- *prometheus.Desc) {
+	m.duration.Describe(ch)
+	m.polls.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (m *JobMetrics) Collect(ch chan<- prometheus.Metric) {
+	m.duration.Collect(ch)
+	m.polls.Collect(ch)
+}
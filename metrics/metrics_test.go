@@ -0,0 +1,134 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/rackerlabs/goclouddns"
+	"github.com/rackerlabs/goclouddns/domains"
+	"github.com/rackerlabs/goclouddns/fakedns"
+	"github.com/rackerlabs/goclouddns/records"
+)
+
+func TestInventory(t *testing.T) {
+	server := fakedns.NewServer()
+	defer server.Close()
+
+	domID := server.AddDomain(domains.CreateOpts{Name: "example.com", Email: "admin@example.com"})
+	for _, opts := range []records.CreateOpts{
+		{Name: "www.example.com", Type: "A", Data: "192.0.2.10", TTL: 300},
+		{Name: "api.example.com", Type: "A", Data: "192.0.2.11", TTL: 3600},
+		{Name: "example.com", Type: "MX", Data: "mail.example.com", Priority: 10, TTL: 86400},
+	} {
+		if _, err := server.AddRecord(domID, opts); err != nil {
+			t.Fatalf("AddRecord() returned error: %v", err)
+		}
+	}
+	server.AddDomain(domains.CreateOpts{Name: "example.net", Email: "admin@example.net"})
+
+	inventory := NewInventory(server.ServiceClient())
+	if err := inventory.Refresh(t.Context()); err != nil {
+		t.Fatalf("Refresh() returned error: %v", err)
+	}
+
+	expected := `
+# HELP clouddns_domains Domains on the account.
+# TYPE clouddns_domains gauge
+clouddns_domains 2
+# HELP clouddns_records Records by domain and type.
+# TYPE clouddns_records gauge
+clouddns_records{domain="example.com",type="A"} 2
+clouddns_records{domain="example.com",type="MX"} 1
+# HELP clouddns_record_ttl_seconds Distribution of record TTLs by type.
+# TYPE clouddns_record_ttl_seconds histogram
+clouddns_record_ttl_seconds_bucket{type="A",le="300"} 1
+clouddns_record_ttl_seconds_bucket{type="A",le="600"} 1
+clouddns_record_ttl_seconds_bucket{type="A",le="900"} 1
+clouddns_record_ttl_seconds_bucket{type="A",le="1800"} 1
+clouddns_record_ttl_seconds_bucket{type="A",le="3600"} 2
+clouddns_record_ttl_seconds_bucket{type="A",le="7200"} 2
+clouddns_record_ttl_seconds_bucket{type="A",le="14400"} 2
+clouddns_record_ttl_seconds_bucket{type="A",le="43200"} 2
+clouddns_record_ttl_seconds_bucket{type="A",le="86400"} 2
+clouddns_record_ttl_seconds_bucket{type="A",le="+Inf"} 2
+clouddns_record_ttl_seconds_sum{type="A"} 3900
+clouddns_record_ttl_seconds_count{type="A"} 2
+clouddns_record_ttl_seconds_bucket{type="MX",le="300"} 0
+clouddns_record_ttl_seconds_bucket{type="MX",le="600"} 0
+clouddns_record_ttl_seconds_bucket{type="MX",le="900"} 0
+clouddns_record_ttl_seconds_bucket{type="MX",le="1800"} 0
+clouddns_record_ttl_seconds_bucket{type="MX",le="3600"} 0
+clouddns_record_ttl_seconds_bucket{type="MX",le="7200"} 0
+clouddns_record_ttl_seconds_bucket{type="MX",le="14400"} 0
+clouddns_record_ttl_seconds_bucket{type="MX",le="43200"} 0
+clouddns_record_ttl_seconds_bucket{type="MX",le="86400"} 1
+clouddns_record_ttl_seconds_bucket{type="MX",le="+Inf"} 1
+clouddns_record_ttl_seconds_sum{type="MX"} 86400
+clouddns_record_ttl_seconds_count{type="MX"} 1
+# HELP clouddns_inventory_refresh_errors_total Refreshes that failed.
+# TYPE clouddns_inventory_refresh_errors_total counter
+clouddns_inventory_refresh_errors_total 0
+`
+	err := testutil.CollectAndCompare(inventory, strings.NewReader(expected),
+		"clouddns_domains", "clouddns_records", "clouddns_record_ttl_seconds", "clouddns_inventory_refresh_errors_total")
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestJobMetrics(t *testing.T) {
+	server := fakedns.NewServer()
+	defer server.Close()
+	domID := server.AddDomain(domains.CreateOpts{Name: "example.com", Email: "admin@example.com"})
+
+	client := server.ServiceClient()
+	jobs := NewJobMetrics()
+	goclouddns.SetJobObserver(client, jobs)
+
+	opts := records.CreateOpts{Name: "www.example.com", Type: "A", Data: "192.0.2.10"}
+	if _, err := records.Create(t.Context(), client, domID, opts).Extract(); err != nil {
+		t.Fatalf("Create() returned error: %v", err)
+	}
+	server.FailNextJob("quota exceeded")
+	if _, err := records.Create(t.Context(), client, domID, opts).Extract(); err == nil {
+		t.Fatal("expected the failed job to return an error")
+	}
+
+	if n := testutil.CollectAndCount(jobs, "clouddns_job_duration_seconds"); n != 2 {
+		t.Errorf("expected COMPLETED and ERROR series, got %d", n)
+	}
+
+	expected := `
+# HELP clouddns_job_polls Callback URL fetches per Cloud DNS async job.
+# TYPE clouddns_job_polls histogram
+clouddns_job_polls_bucket{operation="records.Create",le="1"} 2
+clouddns_job_polls_bucket{operation="records.Create",le="2"} 2
+clouddns_job_polls_bucket{operation="records.Create",le="3"} 2
+clouddns_job_polls_bucket{operation="records.Create",le="5"} 2
+clouddns_job_polls_bucket{operation="records.Create",le="10"} 2
+clouddns_job_polls_bucket{operation="records.Create",le="20"} 2
+clouddns_job_polls_bucket{operation="records.Create",le="50"} 2
+clouddns_job_polls_bucket{operation="records.Create",le="100"} 2
+clouddns_job_polls_bucket{operation="records.Create",le="+Inf"} 2
+clouddns_job_polls_sum{operation="records.Create"} 2
+clouddns_job_polls_count{operation="records.Create"} 2
+`
+	if err := testutil.CollectAndCompare(jobs, strings.NewReader(expected), "clouddns_job_polls"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestInventoryRunRejectsNonPositiveInterval(t *testing.T) {
+	server := fakedns.NewServer()
+	defer server.Close()
+
+	inventory := NewInventory(server.ServiceClient())
+	for _, interval := range []time.Duration{0, -time.Minute} {
+		if err := inventory.Run(t.Context(), interval); err == nil {
+			t.Errorf("Run(%s) returned no error", interval)
+		}
+	}
+}
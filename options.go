@@ -0,0 +1,81 @@
+package goclouddns
+
+import (
+	"log/slog"
+	"runtime"
+	"sync"
+	"weak"
+
+	"github.com/gophercloud/gophercloud/v2"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// clientOptions holds the settings made with SetJobObserver, SetLogger,
+// SetTracerProvider and SetDryRun.
+type clientOptions struct {
+	jobObserver    JobObserver
+	tracerProvider trace.TracerProvider
+	logger         *slog.Logger
+	dryRun         DryRunRecorder
+}
+
+// The gophercloud ServiceClient has no room for the settings above, so
+// they are kept here, keyed by the ProviderClient of the client they were
+// set on. Copies of a ServiceClient share its ProviderClient and so keep
+// the settings, which apply to every service client on that
+// ProviderClient, whatever becomes of its HTTPClient. The keys are weak
+// and an entry goes once its ProviderClient is garbage collected.
+var (
+	optionsMu sync.Mutex
+	options   = map[weak.Pointer[gophercloud.ProviderClient]]clientOptions{}
+)
+
+func optionsFor(client *gophercloud.ServiceClient) clientOptions {
+	if client == nil {
+		return clientOptions{}
+	}
+	return providerOptions(client.ProviderClient)
+}
+
+func providerOptions(provider *gophercloud.ProviderClient) clientOptions {
+	if provider == nil {
+		return clientOptions{}
+	}
+
+	optionsMu.Lock()
+	defer optionsMu.Unlock()
+	return options[weak.Make(provider)]
+}
+
+func setOption(client *gophercloud.ServiceClient, set func(*clientOptions)) {
+	key := weak.Make(client.ProviderClient)
+
+	optionsMu.Lock()
+	defer optionsMu.Unlock()
+
+	o, ok := options[key]
+	if !ok {
+		runtime.AddCleanup(client.ProviderClient, forgetOptions, key)
+	}
+	set(&o)
+	options[key] = o
+}
+
+func forgetOptions(key weak.Pointer[gophercloud.ProviderClient]) {
+	optionsMu.Lock()
+	defer optionsMu.Unlock()
+	delete(options, key)
+}
+
+// SetJobObserver makes WaitForStatus report every job it waits on through
+// client to observer. A nil observer stops the reports.
+func SetJobObserver(client *gophercloud.ServiceClient, observer JobObserver) {
+	setOption(client, func(o *clientOptions) { o.jobObserver = observer })
+}
+
+// ClearOptions undoes every SetJobObserver, SetLogger, SetTracerProvider
+// and SetDryRun call made on client, or on any other service client
+// sharing its ProviderClient.
+func ClearOptions(client *gophercloud.ServiceClient) {
+	setOption(client, func(o *clientOptions) { *o = clientOptions{} })
+}
@@ -0,0 +1,68 @@
+package goclouddns_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/rackerlabs/goclouddns"
+	"github.com/rackerlabs/goclouddns/domains"
+	"github.com/rackerlabs/goclouddns/fakedns"
+	"github.com/rackerlabs/goclouddns/records"
+)
+
+func TestOptionsFollowCopiesAndClear(t *testing.T) {
+	server := fakedns.NewServer()
+	defer server.Close()
+
+	client := server.ServiceClient()
+	var plan goclouddns.DryRunLog
+	goclouddns.SetDryRun(client, &plan)
+
+	copied := *client
+	if !goclouddns.IsDryRun(&copied) {
+		t.Fatal("expected a copy of the client to keep dry-run mode")
+	}
+	if goclouddns.IsDryRun(server.ServiceClient()) {
+		t.Fatal("expected a client on another ProviderClient to be unaffected")
+	}
+
+	goclouddns.ClearOptions(&copied)
+	if goclouddns.IsDryRun(client) {
+		t.Fatal("expected ClearOptions to turn dry-run mode off")
+	}
+}
+
+func TestDryRunSurvivesTransportSwap(t *testing.T) {
+	server := fakedns.NewServer()
+	defer server.Close()
+
+	domID := server.AddDomain(domains.CreateOpts{Name: "example.com", Email: "admin@example.com"})
+	recID, err := server.AddRecord(domID, records.CreateOpts{Name: "www.example.com", Type: "A", Data: "192.0.2.10"})
+	if err != nil {
+		t.Fatalf("AddRecord() returned error: %v", err)
+	}
+
+	client := server.ServiceClient()
+	var plan goclouddns.DryRunLog
+	goclouddns.SetDryRun(client, &plan)
+
+	recorder := fakedns.NewRecorder(nil)
+	client.HTTPClient = http.Client{Transport: recorder}
+
+	if _, err := records.Create(t.Context(), client, domID, records.CreateOpts{Name: "app.example.com", Type: "A", Data: "192.0.2.11"}).Extract(); err != nil {
+		t.Fatalf("Create() returned error: %v", err)
+	}
+	if err := records.Delete(t.Context(), client, domID, recID).ExtractErr(); err != nil {
+		t.Fatalf("Delete() returned error: %v", err)
+	}
+
+	if n := len(recorder.Fixture().Exchanges); n != 0 {
+		t.Fatalf("expected no requests to reach the server, got %d", n)
+	}
+	if _, ok := server.Record(domID, recID); !ok {
+		t.Fatal("expected the record to survive a dry-run delete")
+	}
+	if !goclouddns.IsDryRun(client) {
+		t.Fatal("expected the client to stay in dry-run mode")
+	}
+}
@@ -0,0 +1,248 @@
+// Package propagation checks that a change made through Cloud DNS is being
+// served. A job reporting COMPLETED only means the change was accepted; the
+// authoritative nameservers pick it up some time later. A Verifier queries
+// each nameserver directly until it serves the expected data.
+package propagation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+
+	"github.com/rackerlabs/goclouddns/domains"
+	"github.com/rackerlabs/goclouddns/internal/dnsrr"
+	"github.com/rackerlabs/goclouddns/records"
+)
+
+// ErrNotPropagated is returned when a nameserver is still not serving the
+// expected data when the context ends.
+var ErrNotPropagated = errors.New("change has not propagated to every nameserver")
+
+// ErrNoNameservers is returned when there are no nameservers to check, as
+// nothing can then be said about the change.
+var ErrNoNameservers = errors.New("no nameservers to check")
+
+// Status is the outcome of checking one nameserver.
+type Status struct {
+	// Nameserver is the name the server was given as.
+	Nameserver string
+
+	// Address is the host:port that was queried.
+	Address string
+
+	// Propagated reports whether the server served the expected data.
+	Propagated bool
+
+	// Attempts is how many times the server was queried.
+	Attempts int
+
+	// Elapsed is how long it took to see the expected data, or how long
+	// the server was polled before giving up.
+	Elapsed time.Duration
+
+	// Err is the last error seen, if the server never served the data.
+	Err error
+}
+
+// Verifier polls nameservers for a record.
+type Verifier struct {
+	// Resolver is the host:port of the DNS server used to find the
+	// addresses of nameservers given by name. Empty uses the system
+	// resolver.
+	Resolver string
+
+	// Port is the port nameservers are queried on.
+	Port string
+
+	// Interval is how long to wait between queries to one nameserver.
+	Interval time.Duration
+
+	// QueryTimeout bounds each query.
+	QueryTimeout time.Duration
+}
+
+// NewVerifier returns a Verifier with the usual defaults.
+func NewVerifier() *Verifier {
+	return &Verifier{
+		Port:         "53",
+		Interval:     2 * time.Second,
+		QueryTimeout: 2 * time.Second,
+	}
+}
+
+// Nameservers returns the nameserver names of domain.
+func Nameservers(domain *domains.DomainShow) []string {
+	names := make([]string, 0, len(domain.Nameservers))
+	for _, ns := range domain.Nameservers {
+		names = append(names, ns.Name)
+	}
+	return names
+}
+
+// WaitForRecord polls each nameserver until it serves record, or ctx ends.
+// It returns the status of every nameserver, and ErrNotPropagated if any
+// of them never served it, or ErrNoNameservers if nameservers is empty.
+func (v *Verifier) WaitForRecord(ctx context.Context, nameservers []string, record records.RecordList) ([]Status, error) {
+	want, ok := dnsrr.FromRecord(record)
+	if !ok {
+		return nil, fmt.Errorf("cannot verify %s record %s", record.Type, record.Name)
+	}
+
+	return v.wait(ctx, nameservers, want, func(answer []dns.RR) error {
+		for _, rr := range answer {
+			if dns.IsDuplicate(rr, want) {
+				return nil
+			}
+		}
+		return fmt.Errorf("not serving %s yet", rdata(want))
+	})
+}
+
+// WaitForAbsent polls each nameserver until it stops serving record, or
+// ctx ends.
+func (v *Verifier) WaitForAbsent(ctx context.Context, nameservers []string, record records.RecordList) ([]Status, error) {
+	want, ok := dnsrr.FromRecord(record)
+	if !ok {
+		return nil, fmt.Errorf("cannot verify %s record %s", record.Type, record.Name)
+	}
+
+	return v.wait(ctx, nameservers, want, func(answer []dns.RR) error {
+		for _, rr := range answer {
+			if dns.IsDuplicate(rr, want) {
+				return fmt.Errorf("still serving %s", rdata(want))
+			}
+		}
+		return nil
+	})
+}
+
+func (v *Verifier) wait(ctx context.Context, nameservers []string, want dns.RR, check func([]dns.RR) error) ([]Status, error) {
+	if len(nameservers) == 0 {
+		return nil, ErrNoNameservers
+	}
+
+	statuses := make([]Status, len(nameservers))
+
+	var wg sync.WaitGroup
+	for i, ns := range nameservers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			statuses[i] = v.poll(ctx, ns, want, check)
+		}()
+	}
+	wg.Wait()
+
+	for _, s := range statuses {
+		if !s.Propagated {
+			return statuses, ErrNotPropagated
+		}
+	}
+	return statuses, nil
+}
+
+// poll queries one nameserver until check passes or ctx ends.
+func (v *Verifier) poll(ctx context.Context, nameserver string, want dns.RR, check func([]dns.RR) error) Status {
+	start := time.Now()
+	status := Status{Nameserver: nameserver}
+
+	for {
+		if status.Address == "" {
+			status.Address, status.Err = v.address(ctx, nameserver)
+		}
+		if status.Address != "" {
+			status.Attempts++
+			answer, err := v.query(ctx, status.Address, want.Header().Name, want.Header().Rrtype)
+			if err == nil {
+				err = check(answer)
+			}
+			status.Err = err
+		}
+
+		if status.Err == nil {
+			status.Propagated = true
+			status.Elapsed = time.Since(start)
+			return status
+		}
+
+		select {
+		case <-ctx.Done():
+			status.Elapsed = time.Since(start)
+			return status
+		case <-time.After(v.Interval):
+		}
+	}
+}
+
+// query asks addr for name and qtype without recursion, retrying over TCP
+// if the answer was truncated.
+func (v *Verifier) query(ctx context.Context, addr string, name string, qtype uint16) ([]dns.RR, error) {
+	m := new(dns.Msg)
+	m.SetQuestion(dns.Fqdn(name), qtype)
+	m.RecursionDesired = false
+
+	client := &dns.Client{Timeout: v.QueryTimeout}
+	reply, _, err := client.ExchangeContext(ctx, m, addr)
+	if err == nil && reply.Truncated {
+		client.Net = "tcp"
+		reply, _, err = client.ExchangeContext(ctx, m, addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch reply.Rcode {
+	case dns.RcodeSuccess, dns.RcodeNameError:
+		return reply.Answer, nil
+	default:
+		return nil, fmt.Errorf("answered %s", dns.RcodeToString[reply.Rcode])
+	}
+}
+
+// address returns the host:port to query nameserver on, looking its name
+// up through the Resolver.
+func (v *Verifier) address(ctx context.Context, nameserver string) (string, error) {
+	host := strings.TrimSuffix(nameserver, ".")
+	if ip := net.ParseIP(host); ip != nil {
+		return net.JoinHostPort(host, v.Port), nil
+	}
+
+	if v.Resolver == "" {
+		addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+		if err != nil {
+			return "", err
+		}
+		return net.JoinHostPort(addrs[0], v.Port), nil
+	}
+
+	for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
+		m := new(dns.Msg)
+		m.SetQuestion(dns.Fqdn(host), qtype)
+
+		client := &dns.Client{Timeout: v.QueryTimeout}
+		reply, _, err := client.ExchangeContext(ctx, m, v.Resolver)
+		if err != nil {
+			return "", err
+		}
+		for _, rr := range reply.Answer {
+			switch rr := rr.(type) {
+			case *dns.A:
+				return net.JoinHostPort(rr.A.String(), v.Port), nil
+			case *dns.AAAA:
+				return net.JoinHostPort(rr.AAAA.String(), v.Port), nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no address found for %s", host)
+}
+
+// rdata returns the data part of rr as it appears in zone files.
+func rdata(rr dns.RR) string {
+	return strings.TrimPrefix(rr.String(), rr.Header().String())
+}
@@ -0,0 +1,144 @@
+package propagation
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+
+	"github.com/rackerlabs/goclouddns/records"
+)
+
+// standIn answers for the nameservers' own addresses and, once serving is
+// set, for www.example.com.
+type standIn struct {
+	serving atomic.Bool
+	queries atomic.Int32
+}
+
+func (s *standIn) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
+	m := new(dns.Msg)
+	m.SetReply(r)
+	m.Authoritative = true
+
+	q := r.Question[0]
+	switch {
+	case q.Name == "ns1.example.net." && q.Qtype == dns.TypeA:
+		m.Answer = append(m.Answer, mustRR("ns1.example.net. 300 IN A 127.0.0.1"))
+	case q.Name == "www.example.com." && q.Qtype == dns.TypeA:
+		if s.queries.Add(1) > 1 && s.serving.Load() {
+			m.Answer = append(m.Answer, mustRR("www.example.com. 300 IN A 192.0.2.10"))
+		}
+	default:
+		m.Rcode = dns.RcodeNameError
+	}
+	_ = w.WriteMsg(m)
+}
+
+func mustRR(s string) dns.RR {
+	rr, err := dns.NewRR(s)
+	if err != nil {
+		panic(err)
+	}
+	return rr
+}
+
+func startStandIn(t *testing.T, handler dns.Handler) (string, string) {
+	t.Helper()
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket() returned error: %v", err)
+	}
+
+	started := make(chan struct{})
+	server := &dns.Server{PacketConn: pc, Handler: handler, NotifyStartedFunc: func() { close(started) }}
+	go func() { _ = server.ActivateAndServe() }()
+	t.Cleanup(func() { _ = server.Shutdown() })
+	<-started
+
+	addr := pc.LocalAddr().String()
+	_, port, _ := net.SplitHostPort(addr)
+	return addr, port
+}
+
+func testVerifier(t *testing.T, handler dns.Handler) *Verifier {
+	addr, port := startStandIn(t, handler)
+
+	v := NewVerifier()
+	v.Resolver = addr
+	v.Port = port
+	v.Interval = 10 * time.Millisecond
+	return v
+}
+
+var record = records.RecordList{Name: "www.example.com", Type: "A", Data: "192.0.2.10", TTL: 300}
+
+func TestWaitForRecord(t *testing.T) {
+	handler := &standIn{}
+	handler.serving.Store(true)
+	v := testVerifier(t, handler)
+
+	statuses, err := v.WaitForRecord(t.Context(), []string{"ns1.example.net", "127.0.0.1"}, record)
+	if err != nil {
+		t.Fatalf("WaitForRecord() returned error: %v", err)
+	}
+
+	if len(statuses) != 2 {
+		t.Fatalf("expected 2 statuses, got %+v", statuses)
+	}
+	for _, s := range statuses {
+		if !s.Propagated || s.Address != "127.0.0.1:"+v.Port {
+			t.Errorf("unexpected status %+v", s)
+		}
+	}
+	if attempts := statuses[0].Attempts + statuses[1].Attempts; attempts < 3 {
+		t.Errorf("expected the first empty answer to be retried, got %d attempts", attempts)
+	}
+}
+
+func TestWaitForRecordTimesOut(t *testing.T) {
+	v := testVerifier(t, &standIn{})
+
+	ctx, cancel := context.WithTimeout(t.Context(), 100*time.Millisecond)
+	defer cancel()
+
+	statuses, err := v.WaitForRecord(ctx, []string{"ns1.example.net", "ns2.example.net"}, record)
+	if !errors.Is(err, ErrNotPropagated) {
+		t.Fatalf("expected ErrNotPropagated, got %v", err)
+	}
+
+	if s := statuses[0]; s.Propagated || s.Attempts == 0 || s.Err == nil {
+		t.Errorf("expected ns1 to be polled without success, got %+v", s)
+	}
+	if s := statuses[1]; s.Address != "" || s.Err == nil {
+		t.Errorf("expected ns2 to fail to resolve, got %+v", s)
+	}
+}
+
+func TestWaitWithoutNameservers(t *testing.T) {
+	v := testVerifier(t, &standIn{})
+
+	if _, err := v.WaitForRecord(t.Context(), nil, record); !errors.Is(err, ErrNoNameservers) {
+		t.Fatalf("expected ErrNoNameservers from WaitForRecord, got %v", err)
+	}
+	if _, err := v.WaitForAbsent(t.Context(), nil, record); !errors.Is(err, ErrNoNameservers) {
+		t.Fatalf("expected ErrNoNameservers from WaitForAbsent, got %v", err)
+	}
+}
+
+func TestWaitForAbsent(t *testing.T) {
+	v := testVerifier(t, &standIn{})
+
+	statuses, err := v.WaitForAbsent(t.Context(), []string{"ns1.example.net"}, record)
+	if err != nil {
+		t.Fatalf("WaitForAbsent() returned error: %v", err)
+	}
+	if !statuses[0].Propagated {
+		t.Errorf("unexpected status %+v", statuses[0])
+	}
+}
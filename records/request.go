@@ -2,7 +2,10 @@ package records
 
 import (
 	"context"
-	"log"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/gophercloud/gophercloud/v2"
 	"github.com/gophercloud/gophercloud/v2/pagination"
@@ -30,7 +33,7 @@ func (opts ListOpts) ToRecordListQuery() (string, error) {
 	return q.String(), err
 }
 
-func List(_ctx context.Context, client *gophercloud.ServiceClient, domID string, opts ListOptsBuilder) pagination.Pager {
+func List(ctx context.Context, client *gophercloud.ServiceClient, domID string, opts ListOptsBuilder) pagination.Pager {
 	url := client.ServiceURL("domains", domID, "records")
 	if opts != nil {
 		query, err := opts.ToRecordListQuery()
@@ -40,30 +43,44 @@ func List(_ctx context.Context, client *gophercloud.ServiceClient, domID string,
 		url += query
 	}
 
-	log.Printf("GET %s", url)
+	goclouddns.Logger(client).DebugContext(ctx, "request", "method", "GET", "url", url)
 
-	return pagination.NewPager(client, url, func(r pagination.PageResult) pagination.Page {
+	pager := pagination.NewPager(client, url, func(r pagination.PageResult) pagination.Page {
 		return RecordPage{pagination.LinkedPageBase{PageResult: r}}
 	})
+	return goclouddns.TracePages(client, pager, "records.List")
 }
 
 // Get returns data about a specific record by its ID.
 func Get(ctx context.Context, client *gophercloud.ServiceClient, domID string, id string) (r GetResult) {
+	ctx, span := goclouddns.StartSpan(ctx, client, "records.Get",
+		goclouddns.AttrDomainID.String(domID), goclouddns.AttrRecordID.String(id))
+	defer func() { goclouddns.EndSpan(span, r.Err) }()
+
 	url := client.ServiceURL("domains", domID, "records", id)
-	log.Printf("GET %s", url)
+	start := time.Now()
 	_, r.Err = client.Get(ctx, url, &r.Body, nil)
+	goclouddns.LogRequest(ctx, client, "GET", url, start, r.Err)
 	return
 }
 
 // Delete deletes the specified record ID.
 func Delete(ctx context.Context, client *gophercloud.ServiceClient, domID string, id string) (r DeleteResult) {
+	ctx, span := goclouddns.StartSpan(ctx, client, "records.Delete",
+		goclouddns.AttrDomainID.String(domID), goclouddns.AttrRecordID.String(id))
+	defer func() { goclouddns.EndSpan(span, r.Err) }()
+
 	url := client.ServiceURL("domains", domID, "records", id)
-	log.Printf("DELETE %s", url)
+	if goclouddns.PlanRequest(ctx, client, "DELETE", url, nil) {
+		return
+	}
 
+	start := time.Now()
 	var resp goclouddns.AsyncResult
 	_, resp.Err = client.Delete(ctx, url, &gophercloud.RequestOpts{
 		JSONResponse: &resp.Body,
 	})
+	goclouddns.LogRequest(ctx, client, "DELETE", url, start, resp.Err)
 
 	if resp.Err != nil {
 		r.Err = resp.Err
@@ -78,6 +95,10 @@ func Delete(ctx context.Context, client *gophercloud.ServiceClient, domID string
 	return
 }
 
+// MaxCreateMany is the most records CreateMany takes in one call, keeping
+// each request and job a reasonable size.
+const MaxCreateMany = 100
+
 // CreateOpts contain the values necessary to create a record
 type CreateOpts struct {
 	// Name is the name of the Record.
@@ -91,9 +112,10 @@ type CreateOpts struct {
 
 // Create creates a requested record
 func Create(ctx context.Context, client *gophercloud.ServiceClient, domID string, opts CreateOpts) (r CreateResult) {
-	url := client.ServiceURL("domains", domID, "records")
+	ctx, span := goclouddns.StartSpan(ctx, client, "records.Create", goclouddns.AttrDomainID.String(domID))
+	defer func() { goclouddns.EndSpan(span, r.Err) }()
 
-	log.Printf("POST %s", url)
+	url := client.ServiceURL("domains", domID, "records")
 
 	var body = struct {
 		Records []CreateOpts `json:"records"`
@@ -101,8 +123,57 @@ func Create(ctx context.Context, client *gophercloud.ServiceClient, domID string
 		[]CreateOpts{opts},
 	}
 
+	if goclouddns.PlanRequest(ctx, client, "POST", url, body) {
+		r.Body = dryRunCreated(opts)
+		return
+	}
+
+	start := time.Now()
+	var resp goclouddns.AsyncResult
+	_, resp.Err = client.Post(ctx, url, body, &resp.Body, nil)
+	goclouddns.LogRequest(ctx, client, "POST", url, start, resp.Err)
+	if resp.Err != nil {
+		r.Err = resp.Err
+		return
+	}
+
+	if err := goclouddns.WaitForStatus(ctx, client, &resp, "COMPLETED"); err != nil {
+		r.Err = err
+		return
+	}
+	r.Body = resp.Body
+	return
+}
+
+// CreateMany creates several records of a domain in one request and one
+// job. Callers with more than MaxCreateMany records split them into
+// batches.
+func CreateMany(ctx context.Context, client *gophercloud.ServiceClient, domID string, opts []CreateOpts) (r CreateManyResult) {
+	ctx, span := goclouddns.StartSpan(ctx, client, "records.CreateMany", goclouddns.AttrDomainID.String(domID))
+	defer func() { goclouddns.EndSpan(span, r.Err) }()
+
+	if len(opts) > MaxCreateMany {
+		r.Err = fmt.Errorf("cannot create %d records in one request, the limit is %d", len(opts), MaxCreateMany)
+		return
+	}
+
+	url := client.ServiceURL("domains", domID, "records")
+
+	var body = struct {
+		Records []CreateOpts `json:"records"`
+	}{
+		opts,
+	}
+
+	if goclouddns.PlanRequest(ctx, client, "POST", url, body) {
+		r.Body = dryRunCreated(opts...)
+		return
+	}
+
+	start := time.Now()
 	var resp goclouddns.AsyncResult
 	_, resp.Err = client.Post(ctx, url, body, &resp.Body, nil)
+	goclouddns.LogRequest(ctx, client, "POST", url, start, resp.Err)
 	if resp.Err != nil {
 		r.Err = resp.Err
 		return
@@ -116,6 +187,27 @@ func Create(ctx context.Context, client *gophercloud.ServiceClient, domID string
 	return
 }
 
+// dryRunCreated is the body of a completed job that created opts, for
+// Create and CreateMany to return in a dry run.
+func dryRunCreated(opts ...CreateOpts) map[string]any {
+	created := make([]RecordList, 0, len(opts))
+	for _, o := range opts {
+		created = append(created, RecordList{
+			ID:       goclouddns.DryRunID,
+			Name:     o.Name,
+			Type:     o.Type,
+			Data:     o.Data,
+			TTL:      o.TTL,
+			Priority: o.Priority,
+			Comment:  o.Comment,
+		})
+	}
+	return map[string]any{
+		"status":   "COMPLETED",
+		"response": map[string]any{"records": created},
+	}
+}
+
 // UpdateOpts contain the values necessary to create a record
 type UpdateOpts struct {
 	Name     string `json:"name"`
@@ -123,16 +215,39 @@ type UpdateOpts struct {
 	TTL      uint   `json:"ttl,omitempty"`
 	Comment  string `json:"comment,omitempty"`
 	Priority uint   `json:"priority,omitempty"`
+
+	// ClearComment removes the record's comment, which an empty Comment
+	// leaves alone.
+	ClearComment bool `json:"-"`
+}
+
+// MarshalJSON sends an empty comment when ClearComment is set.
+func (opts UpdateOpts) MarshalJSON() ([]byte, error) {
+	type plain UpdateOpts
+	if !opts.ClearComment {
+		return json.Marshal(plain(opts))
+	}
+	return json.Marshal(struct {
+		plain
+		Comment string `json:"comment"`
+	}{plain: plain(opts)})
 }
 
 // Update updates a requested record
 func Update(ctx context.Context, client *gophercloud.ServiceClient, domID string, record *RecordShow, opts UpdateOpts) (r UpdateResult) {
-	url := client.ServiceURL("domains", domID, "records", record.ID)
+	ctx, span := goclouddns.StartSpan(ctx, client, "records.Update",
+		goclouddns.AttrDomainID.String(domID), goclouddns.AttrRecordID.String(record.ID))
+	defer func() { goclouddns.EndSpan(span, r.Err) }()
 
-	log.Printf("PUT %s", url)
+	url := client.ServiceURL("domains", domID, "records", record.ID)
+	if goclouddns.PlanRequest(ctx, client, "PUT", url, opts) {
+		return
+	}
 
+	start := time.Now()
 	var resp goclouddns.AsyncResult
 	_, resp.Err = client.Put(ctx, url, opts, &resp.Body, nil)
+	goclouddns.LogRequest(ctx, client, "PUT", url, start, resp.Err)
 	if resp.Err != nil {
 		r.Err = resp.Err
 		return
@@ -145,3 +260,105 @@ func Update(ctx context.Context, client *gophercloud.ServiceClient, domID string
 	r.Body = resp.Body
 	return
 }
+
+// Find returns the records of a domain named name, and of type
+// recordType unless it is empty, both compared without regard to case.
+// The API's name filter also matches partial names, so results are
+// checked again here. FindByOpts also matches on data.
+func Find(ctx context.Context, client *gophercloud.ServiceClient, domID string, name string, recordType string) ([]RecordList, error) {
+	return FindByOpts(ctx, client, domID, ListOpts{Name: name, Type: recordType})
+}
+
+// FindByOpts returns the records of a domain matching every field set in
+// opts: the name and type compared without regard to case, and the data
+// exactly. The API filters are used to narrow the listing, and results are
+// checked again here.
+func FindByOpts(ctx context.Context, client *gophercloud.ServiceClient, domID string, opts ListOpts) ([]RecordList, error) {
+	ctx, span := goclouddns.StartSpan(ctx, client, "records.Find", goclouddns.AttrDomainID.String(domID))
+	matches, err := find(ctx, client, domID, opts)
+	goclouddns.EndSpan(span, err)
+	return matches, err
+}
+
+func find(ctx context.Context, client *gophercloud.ServiceClient, domID string, opts ListOpts) ([]RecordList, error) {
+	page, err := List(ctx, client, domID, opts).AllPages(ctx)
+	if err != nil {
+		return nil, err
+	}
+	recordList, err := ExtractRecords(page)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []RecordList
+	for _, record := range recordList {
+		if (opts.Name == "" || strings.EqualFold(record.Name, opts.Name)) &&
+			(opts.Type == "" || strings.EqualFold(record.Type, opts.Type)) &&
+			(opts.Data == "" || record.Data == opts.Data) {
+			matches = append(matches, record)
+		}
+	}
+	return matches, nil
+}
+
+// Ensure makes sure the record named by opts.Name and opts.Type has the
+// data, TTL and priority in opts, creating it if it does not exist and
+// updating it only when something differs. A zero TTL or priority in opts
+// leaves the existing value alone. It returns the record as it now stands
+// and whether any change was made. Ensure refuses to pick between several
+// records with the same name and type.
+func Ensure(ctx context.Context, client *gophercloud.ServiceClient, domID string, opts CreateOpts) (*RecordList, bool, error) {
+	ctx, span := goclouddns.StartSpan(ctx, client, "records.Ensure", goclouddns.AttrDomainID.String(domID))
+	record, changed, err := ensure(ctx, client, domID, opts)
+	goclouddns.EndSpan(span, err)
+	return record, changed, err
+}
+
+func ensure(ctx context.Context, client *gophercloud.ServiceClient, domID string, opts CreateOpts) (*RecordList, bool, error) {
+	matches, err := Find(ctx, client, domID, opts.Name, opts.Type)
+	if err != nil {
+		return nil, false, err
+	}
+
+	switch len(matches) {
+	case 0:
+		record, err := Create(ctx, client, domID, opts).Extract()
+		if err != nil {
+			return nil, false, err
+		}
+		return record, true, nil
+	case 1:
+	default:
+		return nil, false, fmt.Errorf("found %d %s records named %s, expected at most one", len(matches), opts.Type, opts.Name)
+	}
+
+	record := matches[0]
+	if record.Data == opts.Data &&
+		(opts.TTL == 0 || record.TTL == opts.TTL) &&
+		(opts.Priority == 0 || record.Priority == opts.Priority) {
+		return &record, false, nil
+	}
+
+	updateOpts := UpdateOpts{
+		Name:     record.Name,
+		Data:     opts.Data,
+		TTL:      opts.TTL,
+		Comment:  opts.Comment,
+		Priority: opts.Priority,
+	}
+	if err := Update(ctx, client, domID, &RecordShow{ID: record.ID}, updateOpts).ExtractErr(); err != nil {
+		return nil, false, err
+	}
+
+	record.Data = opts.Data
+	if opts.TTL != 0 {
+		record.TTL = opts.TTL
+	}
+	if opts.Priority != 0 {
+		record.Priority = opts.Priority
+	}
+	if opts.Comment != "" {
+		record.Comment = opts.Comment
+	}
+	return &record, true, nil
+}
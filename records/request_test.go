@@ -0,0 +1,134 @@
+package records_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rackerlabs/goclouddns/domains"
+	"github.com/rackerlabs/goclouddns/fakedns"
+	"github.com/rackerlabs/goclouddns/records"
+)
+
+func TestEnsureCreatesUpdatesAndSkips(t *testing.T) {
+	server := fakedns.NewServer()
+	defer server.Close()
+	domID := server.AddDomain(domains.CreateOpts{Name: "example.com", Email: "admin@example.com"})
+
+	ctx := context.Background()
+	client := server.ServiceClient()
+	opts := records.CreateOpts{Name: "office1.example.com", Type: "A", Data: "203.0.113.10", TTL: 300}
+
+	created, changed, err := records.Ensure(ctx, client, domID, opts)
+	if err != nil {
+		t.Fatalf("Ensure() returned error: %v", err)
+	}
+	if !changed {
+		t.Errorf("expected missing record to be created")
+	}
+
+	_, changed, err = records.Ensure(ctx, client, domID, opts)
+	if err != nil {
+		t.Fatalf("Ensure() returned error: %v", err)
+	}
+	if changed {
+		t.Errorf("expected matching record to be left alone")
+	}
+
+	opts.Data = "203.0.113.11"
+	updated, changed, err := records.Ensure(ctx, client, domID, opts)
+	if err != nil {
+		t.Fatalf("Ensure() returned error: %v", err)
+	}
+	if !changed || updated.ID != created.ID {
+		t.Errorf("expected record %s to be updated in place, got %+v", created.ID, updated)
+	}
+	if stored, _ := server.Record(domID, created.ID); stored.Data != "203.0.113.11" {
+		t.Errorf("expected stored data to change, got %q", stored.Data)
+	}
+}
+
+func TestEnsureRejectsAmbiguousRecords(t *testing.T) {
+	server := fakedns.NewServer()
+	defer server.Close()
+	domID := server.AddDomain(domains.CreateOpts{Name: "example.com", Email: "admin@example.com"})
+	for _, data := range []string{"203.0.113.10", "203.0.113.11"} {
+		if _, err := server.AddRecord(domID, records.CreateOpts{Name: "office1.example.com", Type: "A", Data: data}); err != nil {
+			t.Fatalf("AddRecord() returned error: %v", err)
+		}
+	}
+
+	opts := records.CreateOpts{Name: "office1.example.com", Type: "A", Data: "203.0.113.12"}
+	if _, _, err := records.Ensure(context.Background(), server.ServiceClient(), domID, opts); err == nil {
+		t.Fatal("expected an error for duplicate records")
+	}
+}
+
+func TestFind(t *testing.T) {
+	server := fakedns.NewServer()
+	defer server.Close()
+	domID := server.AddDomain(domains.CreateOpts{Name: "example.com", Email: "admin@example.com"})
+	for _, opts := range []records.CreateOpts{
+		{Name: "www.example.com", Type: "A", Data: "203.0.113.10"},
+		{Name: "www.example.com", Type: "A", Data: "203.0.113.11"},
+		{Name: "www.example.com", Type: "AAAA", Data: "2001:db8::10"},
+		{Name: "mail.example.com", Type: "A", Data: "203.0.113.10"},
+	} {
+		if _, err := server.AddRecord(domID, opts); err != nil {
+			t.Fatalf("AddRecord() returned error: %v", err)
+		}
+	}
+
+	ctx := context.Background()
+	client := server.ServiceClient()
+	tests := []struct {
+		opts records.ListOpts
+		want int
+	}{
+		{records.ListOpts{Name: "WWW.example.com"}, 3},
+		{records.ListOpts{Name: "www.example.com", Type: "a"}, 2},
+		{records.ListOpts{Name: "www.example.com", Type: "A", Data: "203.0.113.11"}, 1},
+		{records.ListOpts{Type: "A", Data: "203.0.113.10"}, 2},
+		{records.ListOpts{Name: "ftp.example.com"}, 0},
+	}
+	for _, tt := range tests {
+		found, err := records.FindByOpts(ctx, client, domID, tt.opts)
+		if err != nil {
+			t.Fatalf("FindByOpts(%+v) returned error: %v", tt.opts, err)
+		}
+		if len(found) != tt.want {
+			t.Errorf("FindByOpts(%+v) found %d records, want %d", tt.opts, len(found), tt.want)
+		}
+	}
+
+	found, err := records.Find(ctx, client, domID, "WWW.example.com", "a")
+	if err != nil || len(found) != 2 {
+		t.Errorf("Find() found %+v, %v, want 2 records", found, err)
+	}
+}
+
+func TestCreateMany(t *testing.T) {
+	server := fakedns.NewServer()
+	defer server.Close()
+	domID := server.AddDomain(domains.CreateOpts{Name: "example.com", Email: "admin@example.com"})
+
+	ctx := context.Background()
+	client := server.ServiceClient()
+	created, err := records.CreateMany(ctx, client, domID, []records.CreateOpts{
+		{Name: "www.example.com", Type: "A", Data: "203.0.113.10"},
+		{Name: "example.com", Type: "MX", Data: "mail.example.com", Priority: 10},
+	}).Extract()
+	if err != nil {
+		t.Fatalf("CreateMany() returned error: %v", err)
+	}
+	if len(created) != 2 || created[0].Name != "www.example.com" || created[1].Priority != 10 {
+		t.Fatalf("unexpected records %+v", created)
+	}
+	if stored, ok := server.Record(domID, created[1].ID); !ok || stored.Data != "mail.example.com" {
+		t.Errorf("expected the MX record to be stored, got %+v", stored)
+	}
+
+	err = records.CreateMany(ctx, client, domID, make([]records.CreateOpts, records.MaxCreateMany+1)).Err
+	if err == nil {
+		t.Fatal("expected too many records to be refused")
+	}
+}
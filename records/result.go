@@ -24,6 +24,23 @@ func (r CreateResult) Extract() (*RecordList, error) {
 	return s.Response.Records[0], err
 }
 
+// CreateManyResult is the result of a CreateMany operation
+type CreateManyResult struct {
+	gophercloud.Result
+}
+
+// Extract interprets a CreateManyResult as the created Records, in the
+// order they were given.
+func (r CreateManyResult) Extract() ([]RecordList, error) {
+	var s struct {
+		Response struct {
+			Records []RecordList `json:"records"`
+		} `json:"response"`
+	}
+	err := r.ExtractInto(&s)
+	return s.Response.Records, err
+}
+
 // method to determine if the call succeeded or failed.
 type DeleteResult struct {
 	gophercloud.ErrResult
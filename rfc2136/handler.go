@@ -0,0 +1,418 @@
+// Package rfc2136 accepts RFC 2136 DNS UPDATE messages and applies them to
+// Cloud DNS, so tools such as nsupdate and DHCP servers can manage records
+// without speaking the Cloud DNS API.
+package rfc2136
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gophercloud/gophercloud/v2"
+	"github.com/miekg/dns"
+
+	"github.com/rackerlabs/goclouddns"
+	"github.com/rackerlabs/goclouddns/domains"
+	"github.com/rackerlabs/goclouddns/internal/dnsrr"
+	"github.com/rackerlabs/goclouddns/records"
+)
+
+// DefaultTimeout bounds the Cloud DNS calls made for one UPDATE message.
+const DefaultTimeout = 2 * time.Minute
+
+// Handler is a dns.Handler applying UPDATE messages to Cloud DNS. Updates
+// are applied one message at a time, so the prerequisites of one message
+// are checked against the results of the previous one.
+type Handler struct {
+	client *gophercloud.ServiceClient
+
+	// Keys maps each accepted TSIG key name (fully qualified) to its
+	// algorithm. The secrets themselves are checked by the dns.Server.
+	Keys map[string]string
+
+	// AllowUnsigned accepts updates without a valid TSIG signature.
+	AllowUnsigned bool
+
+	// Timeout bounds the Cloud DNS calls made for one message.
+	Timeout time.Duration
+
+	mu sync.Mutex
+}
+
+// NewHandler returns a Handler applying updates through client.
+func NewHandler(client *gophercloud.ServiceClient) *Handler {
+	return &Handler{client: client, Keys: map[string]string{}, Timeout: DefaultTimeout}
+}
+
+// AcceptUpdate is a dns.MsgAcceptFunc admitting UPDATE messages, which the
+// dns package's default rejects. Servers running a Handler must use it.
+func AcceptUpdate(dh dns.Header) dns.MsgAcceptAction {
+	const qr = 1 << 15
+	if dh.Bits&qr != 0 {
+		return dns.MsgIgnore
+	}
+	if opcode := int(dh.Bits>>11) & 0xF; opcode != dns.OpcodeUpdate {
+		return dns.MsgRejectNotImplemented
+	}
+	if dh.Qdcount != 1 {
+		return dns.MsgReject
+	}
+	return dns.MsgAccept
+}
+
+// ServeDNS implements dns.Handler.
+func (h *Handler) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
+	rcode := h.rcode(w, r)
+	m := new(dns.Msg)
+	m.SetRcode(r, rcode)
+
+	// a signed NOTAUTH reads as a TSIG failure to most clients, so it goes
+	// out unsigned
+	if tsig := r.IsTsig(); tsig != nil && w.TsigStatus() == nil && rcode != dns.RcodeNotAuth {
+		m.SetTsig(tsig.Hdr.Name, tsig.Algorithm, 300, time.Now().Unix())
+	}
+	if err := w.WriteMsg(m); err != nil {
+		goclouddns.Logger(h.client).Warn("rfc2136: writing reply", "error", err)
+	}
+}
+
+func (h *Handler) rcode(w dns.ResponseWriter, r *dns.Msg) int {
+	if r.Opcode != dns.OpcodeUpdate {
+		return dns.RcodeNotImplemented
+	}
+	if !h.authorized(w, r) {
+		return dns.RcodeNotAuth
+	}
+	if len(r.Question) != 1 || r.Question[0].Qtype != dns.TypeSOA {
+		return dns.RcodeFormatError
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.Timeout)
+	defer cancel()
+
+	u := &update{client: h.client, zone: dns.CanonicalName(r.Question[0].Name)}
+	return u.apply(ctx, r.Answer, r.Ns)
+}
+
+func (h *Handler) authorized(w dns.ResponseWriter, r *dns.Msg) bool {
+	tsig := r.IsTsig()
+	if tsig == nil {
+		return h.AllowUnsigned
+	}
+	if w.TsigStatus() != nil {
+		return false
+	}
+
+	algorithm, ok := h.Keys[dns.CanonicalName(tsig.Hdr.Name)]
+	return ok && strings.EqualFold(dns.CanonicalName(algorithm), dns.CanonicalName(tsig.Algorithm))
+}
+
+// update holds the state of one UPDATE message while it is applied.
+type update struct {
+	client   *gophercloud.ServiceClient
+	zone     string
+	domainID string
+	existing []records.RecordList
+}
+
+// apply checks the prerequisites, validates every update and then makes
+// the changes, following the order in RFC 2136 section 3.
+func (u *update) apply(ctx context.Context, prereqs []dns.RR, updates []dns.RR) int {
+	domainID, err := u.findDomain(ctx)
+	if err != nil {
+		goclouddns.Logger(u.client).Error("rfc2136: update failed", "zone", u.zone, "error", err)
+		return dns.RcodeServerFailure
+	}
+	if domainID == "" {
+		return dns.RcodeNotAuth
+	}
+	u.domainID = domainID
+
+	page, err := records.List(ctx, u.client, domainID, nil).AllPages(ctx)
+	if err != nil {
+		goclouddns.Logger(u.client).Error("rfc2136: update failed", "zone", u.zone, "error", err)
+		return dns.RcodeServerFailure
+	}
+	if u.existing, err = records.ExtractRecords(page); err != nil {
+		goclouddns.Logger(u.client).Error("rfc2136: update failed", "zone", u.zone, "error", err)
+		return dns.RcodeServerFailure
+	}
+
+	if rcode := u.checkPrereqs(prereqs); rcode != dns.RcodeSuccess {
+		return rcode
+	}
+	if rcode := u.checkUpdates(updates); rcode != dns.RcodeSuccess {
+		return rcode
+	}
+
+	for _, rr := range updates {
+		if err := u.applyOne(ctx, rr); err != nil {
+			goclouddns.Logger(u.client).Error("rfc2136: update failed", "zone", u.zone, "error", err)
+			return dns.RcodeServerFailure
+		}
+	}
+	return dns.RcodeSuccess
+}
+
+// findDomain returns the ID of the Cloud DNS domain for the zone, or ""
+// when there is none.
+func (u *update) findDomain(ctx context.Context) (string, error) {
+	id, err := domains.IDFromName(ctx, u.client, u.zone)
+	if errors.As(err, &gophercloud.ErrResourceNotFound{}) {
+		return "", nil
+	}
+	return id, err
+}
+
+// checkPrereqs evaluates the prerequisite section (RFC 2136 section 3.2).
+func (u *update) checkPrereqs(prereqs []dns.RR) int {
+	valueSets := map[string][]dns.RR{}
+
+	for _, rr := range prereqs {
+		hdr := rr.Header()
+		if hdr.Ttl != 0 {
+			return dns.RcodeFormatError
+		}
+		if !dns.IsSubDomain(u.zone, hdr.Name) {
+			return dns.RcodeNotZone
+		}
+
+		switch hdr.Class {
+		case dns.ClassANY:
+			if !isEmpty(rr) {
+				return dns.RcodeFormatError
+			}
+			if hdr.Rrtype == dns.TypeANY {
+				if len(u.rrset(hdr.Name, dns.TypeANY)) == 0 {
+					return dns.RcodeNameError
+				}
+			} else if len(u.rrset(hdr.Name, hdr.Rrtype)) == 0 {
+				return dns.RcodeNXRrset
+			}
+		case dns.ClassNONE:
+			if !isEmpty(rr) {
+				return dns.RcodeFormatError
+			}
+			if hdr.Rrtype == dns.TypeANY {
+				if len(u.rrset(hdr.Name, dns.TypeANY)) != 0 {
+					return dns.RcodeYXDomain
+				}
+			} else if len(u.rrset(hdr.Name, hdr.Rrtype)) != 0 {
+				return dns.RcodeYXRrset
+			}
+		case dns.ClassINET:
+			key := dns.CanonicalName(hdr.Name) + " " + dns.TypeToString[hdr.Rrtype]
+			valueSets[key] = append(valueSets[key], rr)
+		default:
+			return dns.RcodeFormatError
+		}
+	}
+
+	for _, want := range valueSets {
+		hdr := want[0].Header()
+		have := u.rrset(hdr.Name, hdr.Rrtype)
+		if len(have) != len(want) {
+			return dns.RcodeNXRrset
+		}
+		for _, rr := range want {
+			if indexOf(have, rr) < 0 {
+				return dns.RcodeNXRrset
+			}
+		}
+	}
+	return dns.RcodeSuccess
+}
+
+// checkUpdates prescans the update section (RFC 2136 section 3.4.1) so a
+// malformed message changes nothing.
+func (u *update) checkUpdates(updates []dns.RR) int {
+	for _, rr := range updates {
+		hdr := rr.Header()
+		if !dns.IsSubDomain(u.zone, hdr.Name) {
+			return dns.RcodeNotZone
+		}
+
+		switch hdr.Class {
+		case dns.ClassINET:
+			if hdr.Rrtype == dns.TypeANY {
+				return dns.RcodeFormatError
+			}
+			if _, err := dnsrr.ToCreateOpts(rr); err != nil {
+				goclouddns.Logger(u.client).Info("rfc2136: unsupported record", "zone", u.zone, "error", err)
+				return dns.RcodeNotImplemented
+			}
+		case dns.ClassANY:
+			if hdr.Ttl != 0 || !isEmpty(rr) {
+				return dns.RcodeFormatError
+			}
+		case dns.ClassNONE:
+			if hdr.Ttl != 0 || hdr.Rrtype == dns.TypeANY {
+				return dns.RcodeFormatError
+			}
+		default:
+			return dns.RcodeFormatError
+		}
+	}
+	return dns.RcodeSuccess
+}
+
+func (u *update) applyOne(ctx context.Context, rr dns.RR) error {
+	hdr := rr.Header()
+	apex := dns.CanonicalName(hdr.Name) == u.zone
+
+	switch hdr.Class {
+	case dns.ClassINET:
+		// Cloud DNS owns the SOA, and a CNAME cannot share a name
+		if hdr.Rrtype == dns.TypeSOA {
+			return nil
+		}
+		if hdr.Rrtype != dns.TypeCNAME && len(u.rrset(hdr.Name, dns.TypeCNAME)) != 0 {
+			return nil
+		}
+		return u.add(ctx, rr)
+
+	case dns.ClassANY:
+		for _, record := range u.matching(hdr.Name, hdr.Rrtype) {
+			if apex && (record.Type == "SOA" || record.Type == "NS") {
+				continue
+			}
+			if err := u.delete(ctx, record); err != nil {
+				return err
+			}
+		}
+
+	case dns.ClassNONE:
+		if hdr.Rrtype == dns.TypeSOA || (apex && hdr.Rrtype == dns.TypeNS && len(u.rrset(hdr.Name, dns.TypeNS)) <= 1) {
+			return nil
+		}
+		for _, record := range u.matching(hdr.Name, hdr.Rrtype) {
+			if existing, ok := dnsrr.FromRecord(record); ok && dns.IsDuplicate(existing, withClass(rr, dns.ClassINET)) {
+				if err := u.delete(ctx, record); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// add creates rr, or refreshes the TTL of an identical record. Adding a
+// CNAME replaces the existing one, since there can only be one.
+func (u *update) add(ctx context.Context, rr dns.RR) error {
+	opts, err := dnsrr.ToCreateOpts(rr)
+	if err != nil {
+		return err
+	}
+
+	for _, record := range u.matching(rr.Header().Name, rr.Header().Rrtype) {
+		existing, ok := dnsrr.FromRecord(record)
+		if !ok {
+			continue
+		}
+
+		if dns.IsDuplicate(existing, rr) || rr.Header().Rrtype == dns.TypeCNAME {
+			if record.Data == opts.Data && record.Priority == opts.Priority && record.TTL == opts.TTL {
+				return nil
+			}
+
+			updateOpts := records.UpdateOpts{Name: record.Name, Data: opts.Data, TTL: opts.TTL, Priority: opts.Priority}
+			if err := records.Update(ctx, u.client, u.domainID, &records.RecordShow{ID: record.ID}, updateOpts).ExtractErr(); err != nil {
+				return fmt.Errorf("updating %s %s: %w", record.Type, record.Name, err)
+			}
+			u.replace(record.ID, records.RecordList{
+				ID: record.ID, Name: record.Name, Type: record.Type,
+				Data: opts.Data, TTL: opts.TTL, Priority: opts.Priority,
+			})
+			return nil
+		}
+	}
+
+	created, err := records.Create(ctx, u.client, u.domainID, opts).Extract()
+	if err != nil {
+		return fmt.Errorf("creating %s %s: %w", opts.Type, opts.Name, err)
+	}
+	u.existing = append(u.existing, *created)
+	return nil
+}
+
+func (u *update) delete(ctx context.Context, record records.RecordList) error {
+	if err := records.Delete(ctx, u.client, u.domainID, record.ID).ExtractErr(); err != nil {
+		return fmt.Errorf("deleting %s %s: %w", record.Type, record.Name, err)
+	}
+	u.replace(record.ID, records.RecordList{})
+	return nil
+}
+
+// replace swaps the cached record with the given ID, dropping it when
+// record has no ID.
+func (u *update) replace(id string, record records.RecordList) {
+	for i := range u.existing {
+		if u.existing[i].ID != id {
+			continue
+		}
+		if record.ID == "" {
+			u.existing = append(u.existing[:i], u.existing[i+1:]...)
+		} else {
+			u.existing[i] = record
+		}
+		return
+	}
+}
+
+// matching returns the cached records at name with type t, or of any type
+// for dns.TypeANY.
+func (u *update) matching(name string, t uint16) []records.RecordList {
+	name = strings.TrimSuffix(dns.CanonicalName(name), ".")
+
+	var matches []records.RecordList
+	for _, record := range u.existing {
+		if !strings.EqualFold(record.Name, name) {
+			continue
+		}
+		if t == dns.TypeANY || strings.EqualFold(record.Type, dns.TypeToString[t]) {
+			matches = append(matches, record)
+		}
+	}
+	return matches
+}
+
+// rrset returns the RRs at name with type t in wire form.
+func (u *update) rrset(name string, t uint16) []dns.RR {
+	var rrs []dns.RR
+	for _, record := range u.matching(name, t) {
+		if rr, ok := dnsrr.FromRecord(record); ok {
+			rrs = append(rrs, rr)
+		}
+	}
+	return rrs
+}
+
+func indexOf(rrs []dns.RR, rr dns.RR) int {
+	for i, have := range rrs {
+		if dns.IsDuplicate(have, rr) {
+			return i
+		}
+	}
+	return -1
+}
+
+// isEmpty reports whether rr carries no RDATA, as prerequisites and
+// deletions of class ANY must.
+func isEmpty(rr dns.RR) bool {
+	if _, ok := rr.(*dns.ANY); ok {
+		return true
+	}
+	return rr.Header().Rdlength == 0
+}
+
+func withClass(rr dns.RR, class uint16) dns.RR {
+	rr = dns.Copy(rr)
+	rr.Header().Class = class
+	return rr
+}
@@ -0,0 +1,177 @@
+package rfc2136
+
+import (
+	"net"
+	"testing"
+
+	"github.com/miekg/dns"
+
+	"github.com/rackerlabs/goclouddns/domains"
+	"github.com/rackerlabs/goclouddns/fakedns"
+	"github.com/rackerlabs/goclouddns/records"
+)
+
+const (
+	testKey    = "dhcp-key."
+	testSecret = "c2VjcmV0c2VjcmV0c2VjcmV0"
+)
+
+// startServer serves handler over UDP on a loopback port and returns its
+// address.
+func startServer(t *testing.T, handler *Handler) string {
+	t.Helper()
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket() returned error: %v", err)
+	}
+
+	started := make(chan struct{})
+	server := &dns.Server{
+		PacketConn:        pc,
+		Handler:           handler,
+		TsigSecret:        map[string]string{testKey: testSecret},
+		MsgAcceptFunc:     AcceptUpdate,
+		NotifyStartedFunc: func() { close(started) },
+	}
+	go func() { _ = server.ActivateAndServe() }()
+	t.Cleanup(func() { _ = server.Shutdown() })
+	<-started
+
+	return pc.LocalAddr().String()
+}
+
+func newTestHandler(t *testing.T) (*fakedns.Server, string, string) {
+	t.Helper()
+
+	fake := fakedns.NewServer()
+	t.Cleanup(fake.Close)
+	domID := fake.AddDomain(domains.CreateOpts{Name: "example.com", Email: "admin@example.com"})
+
+	handler := NewHandler(fake.ServiceClient())
+	handler.Keys[testKey] = dns.HmacSHA256
+	return fake, domID, startServer(t, handler)
+}
+
+func exchange(t *testing.T, addr string, m *dns.Msg, sign bool) int {
+	t.Helper()
+
+	client := &dns.Client{TsigSecret: map[string]string{testKey: testSecret}}
+	if sign {
+		m.SetTsig(testKey, dns.HmacSHA256, 300, 0)
+	}
+	reply, _, err := client.Exchange(m, addr)
+	if err != nil {
+		t.Fatalf("Exchange() returned error: %v", err)
+	}
+	return reply.Rcode
+}
+
+func mustRR(t *testing.T, s string) dns.RR {
+	t.Helper()
+
+	rr, err := dns.NewRR(s)
+	if err != nil {
+		t.Fatalf("NewRR(%q) returned error: %v", s, err)
+	}
+	return rr
+}
+
+func zoneRecords(t *testing.T, fake *fakedns.Server, domID string) []records.RecordList {
+	t.Helper()
+
+	page, err := records.List(t.Context(), fake.ServiceClient(), domID, nil).AllPages(t.Context())
+	if err != nil {
+		t.Fatalf("List() returned error: %v", err)
+	}
+	recs, err := records.ExtractRecords(page)
+	if err != nil {
+		t.Fatalf("ExtractRecords() returned error: %v", err)
+	}
+	return recs
+}
+
+func TestUpdateAddsAndDeletes(t *testing.T) {
+	fake, domID, addr := newTestHandler(t)
+
+	m := new(dns.Msg)
+	m.SetUpdate("example.com.")
+	m.RRsetNotUsed([]dns.RR{mustRR(t, "host1.example.com. 0 IN A 0.0.0.0")})
+	m.Insert([]dns.RR{
+		mustRR(t, "host1.example.com. 60 IN A 192.0.2.10"),
+		mustRR(t, "example.com. 3600 IN MX 10 mail.example.com."),
+	})
+	if rcode := exchange(t, addr, m, true); rcode != dns.RcodeSuccess {
+		t.Fatalf("expected NOERROR, got %s", dns.RcodeToString[rcode])
+	}
+
+	recs := zoneRecords(t, fake, domID)
+	if len(recs) != 2 {
+		t.Fatalf("expected 2 records, got %+v", recs)
+	}
+	for _, record := range recs {
+		switch record.Type {
+		case "A":
+			if record.Data != "192.0.2.10" || record.TTL != 300 {
+				t.Errorf("unexpected A record %+v", record)
+			}
+		case "MX":
+			if record.Data != "mail.example.com" || record.Priority != 10 {
+				t.Errorf("unexpected MX record %+v", record)
+			}
+		}
+	}
+
+	// the same prerequisite now fails, and nothing changes
+	m = new(dns.Msg)
+	m.SetUpdate("example.com.")
+	m.RRsetNotUsed([]dns.RR{mustRR(t, "host1.example.com. 0 IN A 0.0.0.0")})
+	m.Insert([]dns.RR{mustRR(t, "host1.example.com. 300 IN A 192.0.2.11")})
+	if rcode := exchange(t, addr, m, true); rcode != dns.RcodeYXRrset {
+		t.Fatalf("expected YXRRSET, got %s", dns.RcodeToString[rcode])
+	}
+
+	m = new(dns.Msg)
+	m.SetUpdate("example.com.")
+	m.Used([]dns.RR{mustRR(t, "host1.example.com. 0 IN A 192.0.2.10")})
+	m.RemoveRRset([]dns.RR{mustRR(t, "host1.example.com. 0 IN A 0.0.0.0")})
+	if rcode := exchange(t, addr, m, true); rcode != dns.RcodeSuccess {
+		t.Fatalf("expected NOERROR, got %s", dns.RcodeToString[rcode])
+	}
+	if recs := zoneRecords(t, fake, domID); len(recs) != 1 || recs[0].Type != "MX" {
+		t.Errorf("expected only the MX record to remain, got %+v", recs)
+	}
+}
+
+func TestUpdateRejectsBadRequests(t *testing.T) {
+	fake, _, addr := newTestHandler(t)
+
+	insert := func(zone string, rr string) *dns.Msg {
+		m := new(dns.Msg)
+		m.SetUpdate(zone)
+		m.Insert([]dns.RR{mustRR(t, rr)})
+		return m
+	}
+
+	if rcode := exchange(t, addr, insert("example.com.", "host1.example.com. 300 IN A 192.0.2.10"), false); rcode != dns.RcodeNotAuth {
+		t.Errorf("expected NOTAUTH for an unsigned update, got %s", dns.RcodeToString[rcode])
+	}
+	if rcode := exchange(t, addr, insert("example.net.", "host1.example.net. 300 IN A 192.0.2.10"), true); rcode != dns.RcodeNotAuth {
+		t.Errorf("expected NOTAUTH for an unknown zone, got %s", dns.RcodeToString[rcode])
+	}
+	if rcode := exchange(t, addr, insert("example.com.", "host1.example.net. 300 IN A 192.0.2.10"), true); rcode != dns.RcodeNotZone {
+		t.Errorf("expected NOTZONE for a name outside the zone, got %s", dns.RcodeToString[rcode])
+	}
+
+	m := new(dns.Msg)
+	m.SetUpdate("example.com.")
+	m.NameUsed([]dns.RR{mustRR(t, "host1.example.com. 0 IN A 0.0.0.0")})
+	if rcode := exchange(t, addr, m, true); rcode != dns.RcodeNameError {
+		t.Errorf("expected NXDOMAIN for an unused name, got %s", dns.RcodeToString[rcode])
+	}
+
+	fake.FailNextJob("quota exceeded")
+	if rcode := exchange(t, addr, insert("example.com.", "host1.example.com. 300 IN A 192.0.2.10"), true); rcode != dns.RcodeServerFailure {
+		t.Errorf("expected SERVFAIL for a failed job, got %s", dns.RcodeToString[rcode])
+	}
+}
@@ -0,0 +1,348 @@
+package snapshot
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+
+	"github.com/rackerlabs/goclouddns"
+	"github.com/rackerlabs/goclouddns/internal/dnsrr"
+)
+
+// maxCNAMEChain bounds how many CNAMEs are followed within the served zones.
+const maxCNAMEChain = 8
+
+// Handler is a dns.Handler answering authoritatively for the zones in a set
+// of snapshots. CNAMEs are followed while their targets stay within those
+// zones, wildcards are expanded and delegations are answered with
+// referrals.
+type Handler struct {
+	// Logger receives the errors of writing replies. When nil, the logger
+	// of client-less calls is used, goclouddns.Logger(nil), which discards
+	// everything. Programs that fetched the snapshots usually pass
+	// goclouddns.Logger(client).
+	Logger *slog.Logger
+
+	zones map[string]*zone
+}
+
+// NewHandler returns a Handler serving snapshots. It fails if a record
+// cannot be served, since the answers would then differ from Cloud DNS.
+func NewHandler(snapshots ...*Snapshot) (*Handler, error) {
+	h := &Handler{zones: map[string]*zone{}}
+	for _, s := range snapshots {
+		z, err := newZone(s)
+		if err != nil {
+			return nil, err
+		}
+		h.zones[z.origin] = z
+	}
+	return h, nil
+}
+
+// ServeDNS implements dns.Handler.
+func (h *Handler) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
+	m := new(dns.Msg)
+	m.SetReply(r)
+
+	switch {
+	case r.Opcode != dns.OpcodeQuery:
+		m.Rcode = dns.RcodeNotImplemented
+	case len(r.Question) != 1:
+		m.Rcode = dns.RcodeFormatError
+	default:
+		q := r.Question[0]
+		z := h.zoneFor(q.Name)
+		if z == nil || (q.Qclass != dns.ClassINET && q.Qclass != dns.ClassANY) {
+			m.Rcode = dns.RcodeRefused
+			break
+		}
+		m.Authoritative = true
+		h.answer(m, z, dns.CanonicalName(q.Name), q.Qtype)
+	}
+
+	size := dns.MaxMsgSize
+	if w.LocalAddr().Network() == "udp" {
+		size = dns.MinMsgSize
+	}
+	if opt := r.IsEdns0(); opt != nil {
+		m.SetEdns0(opt.UDPSize(), false)
+		if w.LocalAddr().Network() == "udp" {
+			size = max(int(opt.UDPSize()), dns.MinMsgSize)
+		}
+	}
+	m.Truncate(size)
+
+	if err := w.WriteMsg(m); err != nil {
+		h.logger().Warn("serve-snapshot: writing reply", "error", err)
+	}
+}
+
+func (h *Handler) logger() *slog.Logger {
+	if h.Logger != nil {
+		return h.Logger
+	}
+	return goclouddns.Logger(nil)
+}
+
+// answer fills in m for a query of name and qtype, starting in z.
+func (h *Handler) answer(m *dns.Msg, z *zone, name string, qtype uint16) {
+	seen := map[string]bool{}
+	for range maxCNAMEChain {
+		res := z.lookup(name, qtype)
+
+		if res.delegation != nil {
+			// a referral is only given for the name asked about
+			if len(m.Answer) == 0 {
+				m.Authoritative = false
+				m.Ns = res.delegation
+				m.Extra = append(m.Extra, z.glue(res.delegation)...)
+			}
+			return
+		}
+
+		if res.cname != nil {
+			m.Answer = append(m.Answer, res.cname)
+			seen[name] = true
+
+			target := dns.CanonicalName(res.cname.Target)
+			next := h.zoneFor(target)
+			if next == nil || seen[target] {
+				return
+			}
+			z, name = next, target
+			continue
+		}
+
+		m.Answer = append(m.Answer, res.answer...)
+		if len(res.answer) == 0 {
+			m.Ns = []dns.RR{z.negativeSOA()}
+			if !res.exists {
+				m.Rcode = dns.RcodeNameError
+			}
+		}
+		return
+	}
+}
+
+// zoneFor returns the most specific zone containing name, or nil.
+func (h *Handler) zoneFor(name string) *zone {
+	name = dns.CanonicalName(name)
+	for {
+		if z, ok := h.zones[name]; ok {
+			return z
+		}
+		i, end := dns.NextLabel(name, 0)
+		if end {
+			return nil
+		}
+		name = name[i:]
+	}
+}
+
+// zone is one snapshot in wire form, keyed by canonical owner name.
+type zone struct {
+	origin string
+	soa    *dns.SOA
+	names  map[string][]dns.RR
+}
+
+func newZone(s *Snapshot) (*zone, error) {
+	z := &zone{
+		origin: dns.CanonicalName(s.Domain.Name),
+		names:  map[string][]dns.RR{},
+	}
+
+	for _, record := range s.Records {
+		rr, ok := dnsrr.FromRecord(record)
+		if !ok {
+			return nil, fmt.Errorf("%s: cannot serve %s record %s with data %q", s.Domain.Name, record.Type, record.Name, record.Data)
+		}
+		if !dns.IsSubDomain(z.origin, rr.Header().Name) {
+			return nil, fmt.Errorf("%s: record %s is outside the zone", s.Domain.Name, record.Name)
+		}
+		z.names[rr.Header().Name] = append(z.names[rr.Header().Name], rr)
+	}
+
+	z.soa = newSOA(s, z.origin)
+	apex := z.names[z.origin]
+	if !hasType(apex, dns.TypeNS) {
+		for _, ns := range s.Domain.Nameservers {
+			apex = append(apex, &dns.NS{
+				Hdr: dns.RR_Header{Name: z.origin, Rrtype: dns.TypeNS, Class: dns.ClassINET, Ttl: z.soa.Hdr.Ttl},
+				Ns:  dns.Fqdn(strings.ToLower(ns.Name)),
+			})
+		}
+	}
+	z.names[z.origin] = append([]dns.RR{z.soa}, apex...)
+	return z, nil
+}
+
+// newSOA builds the SOA Cloud DNS would serve, which is not part of the
+// record list.
+func newSOA(s *Snapshot, origin string) *dns.SOA {
+	mname := origin
+	if len(s.Domain.Nameservers) > 0 {
+		mname = dns.Fqdn(strings.ToLower(s.Domain.Nameservers[0].Name))
+	}
+
+	ttl := uint32(s.Domain.TTL)
+	if ttl == 0 {
+		ttl = dnsrr.MinTTL
+	}
+
+	return &dns.SOA{
+		Hdr:     dns.RR_Header{Name: origin, Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: ttl},
+		Ns:      mname,
+		Mbox:    dns.Fqdn(strings.Replace(s.Domain.EmailAddress, "@", ".", 1)),
+		Serial:  serial(s.Domain.Updated),
+		Refresh: 3600,
+		Retry:   300,
+		Expire:  1814400,
+		Minttl:  dnsrr.MinTTL,
+	}
+}
+
+// serial derives a zone serial from the domain's last update time.
+func serial(updated string) uint32 {
+	for _, layout := range []string{"2006-01-02T15:04:05.000-0700", time.RFC3339} {
+		if t, err := time.Parse(layout, updated); err == nil {
+			return uint32(t.Unix())
+		}
+	}
+	return 1
+}
+
+// negativeSOA is the SOA for the authority section of negative answers,
+// with the TTL lowered as RFC 2308 requires.
+func (z *zone) negativeSOA() dns.RR {
+	soa := dns.Copy(z.soa)
+	soa.Header().Ttl = min(z.soa.Hdr.Ttl, z.soa.Minttl)
+	return soa
+}
+
+type lookupResult struct {
+	answer     []dns.RR
+	cname      *dns.CNAME
+	delegation []dns.RR
+	exists     bool
+}
+
+func (z *zone) lookup(name string, qtype uint16) lookupResult {
+	if ns := z.delegation(name, qtype); ns != nil {
+		return lookupResult{delegation: ns}
+	}
+
+	rrs, ok := z.names[name]
+	if !ok {
+		if z.hasDescendants(name) {
+			return lookupResult{exists: true}
+		}
+		if rrs, ok = z.wildcard(name); !ok {
+			return lookupResult{}
+		}
+	}
+
+	res := lookupResult{exists: true}
+	for _, rr := range rrs {
+		if qtype == dns.TypeANY || rr.Header().Rrtype == qtype {
+			res.answer = append(res.answer, rr)
+		}
+	}
+	if len(res.answer) == 0 {
+		for _, rr := range rrs {
+			if cname, ok := rr.(*dns.CNAME); ok {
+				res.cname = cname
+			}
+		}
+	}
+	return res
+}
+
+// delegation returns the NS records of the topmost zone cut between the
+// origin and name, or nil. DS records live on the parent side of a cut.
+func (z *zone) delegation(name string, qtype uint16) []dns.RR {
+	var cut []dns.RR
+	for n := name; n != z.origin; {
+		if qtype != dns.TypeDS || n != name {
+			if ns := rrsOfType(z.names[n], dns.TypeNS); ns != nil {
+				cut = ns
+			}
+		}
+
+		i, end := dns.NextLabel(n, 0)
+		if end {
+			break
+		}
+		n = n[i:]
+	}
+	return cut
+}
+
+// hasDescendants reports whether name is an empty non-terminal: it owns no
+// records but names below it do.
+func (z *zone) hasDescendants(name string) bool {
+	for owner := range z.names {
+		if strings.HasSuffix(owner, "."+name) {
+			return true
+		}
+	}
+	return false
+}
+
+// wildcard returns the records of the wildcard matching name, renamed to
+// name, per RFC 4592.
+func (z *zone) wildcard(name string) ([]dns.RR, bool) {
+	encloser := name
+	for encloser != z.origin {
+		i, _ := dns.NextLabel(encloser, 0)
+		encloser = encloser[i:]
+		if _, ok := z.names[encloser]; ok || z.hasDescendants(encloser) {
+			break
+		}
+	}
+
+	rrs, ok := z.names["*."+encloser]
+	if !ok {
+		return nil, false
+	}
+
+	synthesized := make([]dns.RR, 0, len(rrs))
+	for _, rr := range rrs {
+		rr = dns.Copy(rr)
+		rr.Header().Name = name
+		synthesized = append(synthesized, rr)
+	}
+	return synthesized, true
+}
+
+// glue returns the in-zone addresses of the name servers in ns.
+func (z *zone) glue(ns []dns.RR) []dns.RR {
+	var extra []dns.RR
+	for _, rr := range ns {
+		target := dns.CanonicalName(rr.(*dns.NS).Ns)
+		for _, addr := range z.names[target] {
+			if t := addr.Header().Rrtype; t == dns.TypeA || t == dns.TypeAAAA {
+				extra = append(extra, addr)
+			}
+		}
+	}
+	return extra
+}
+
+func rrsOfType(rrs []dns.RR, t uint16) []dns.RR {
+	var matches []dns.RR
+	for _, rr := range rrs {
+		if rr.Header().Rrtype == t {
+			matches = append(matches, rr)
+		}
+	}
+	return matches
+}
+
+func hasType(rrs []dns.RR, t uint16) bool {
+	return rrsOfType(rrs, t) != nil
+}
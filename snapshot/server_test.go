@@ -0,0 +1,207 @@
+package snapshot
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/miekg/dns"
+
+	"github.com/rackerlabs/goclouddns/domains"
+	"github.com/rackerlabs/goclouddns/fakedns"
+	"github.com/rackerlabs/goclouddns/records"
+)
+
+func testSnapshot(t *testing.T) *Snapshot {
+	t.Helper()
+
+	server := fakedns.NewServer()
+	defer server.Close()
+
+	domID := server.AddDomain(domains.CreateOpts{Name: "example.com", Email: "admin@example.com", TTL: 3600})
+	for _, opts := range []records.CreateOpts{
+		{Name: "www.example.com", Type: "A", Data: "192.0.2.10", TTL: 300},
+		{Name: "www.example.com", Type: "AAAA", Data: "2001:db8::10", TTL: 300},
+		{Name: "alias.example.com", Type: "CNAME", Data: "www.example.com", TTL: 300},
+		{Name: "external.example.com", Type: "CNAME", Data: "www.example.net", TTL: 300},
+		{Name: "example.com", Type: "MX", Data: "mail.example.com", Priority: 10, TTL: 300},
+		{Name: "example.com", Type: "TXT", Data: "v=spf1 -all", TTL: 300},
+		{Name: "_sip._tcp.example.com", Type: "SRV", Data: "5 5060 sip.example.com", Priority: 10, TTL: 300},
+		{Name: "*.apps.example.com", Type: "A", Data: "192.0.2.20", TTL: 300},
+		{Name: "lab.example.com", Type: "NS", Data: "ns1.lab.example.com", TTL: 300},
+		{Name: "ns1.lab.example.com", Type: "A", Data: "192.0.2.53", TTL: 300},
+	} {
+		if _, err := server.AddRecord(domID, opts); err != nil {
+			t.Fatalf("AddRecord() returned error: %v", err)
+		}
+	}
+
+	s, err := Fetch(t.Context(), server.ServiceClient(), domID)
+	if err != nil {
+		t.Fatalf("Fetch() returned error: %v", err)
+	}
+
+	// round trip through a saved file, as serve-snapshot --from does
+	path := filepath.Join(t.TempDir(), "example.com.json")
+	if err := s.Save(path); err != nil {
+		t.Fatalf("Save() returned error: %v", err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() returned error: %v", err)
+	}
+	return loaded
+}
+
+func startServer(t *testing.T, handler dns.Handler) string {
+	t.Helper()
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket() returned error: %v", err)
+	}
+
+	started := make(chan struct{})
+	server := &dns.Server{PacketConn: pc, Handler: handler, NotifyStartedFunc: func() { close(started) }}
+	go func() { _ = server.ActivateAndServe() }()
+	t.Cleanup(func() { _ = server.Shutdown() })
+	<-started
+
+	return pc.LocalAddr().String()
+}
+
+func TestHandlerAnswers(t *testing.T) {
+	handler, err := NewHandler(testSnapshot(t))
+	if err != nil {
+		t.Fatalf("NewHandler() returned error: %v", err)
+	}
+	addr := startServer(t, handler)
+
+	tests := []struct {
+		name      string
+		qtype     uint16
+		rcode     int
+		answer    []string
+		authority uint16
+	}{
+		{name: "www.example.com.", qtype: dns.TypeA, answer: []string{"www.example.com.\t300\tIN\tA\t192.0.2.10"}},
+		{name: "WWW.Example.com.", qtype: dns.TypeAAAA, answer: []string{"www.example.com.\t300\tIN\tAAAA\t2001:db8::10"}},
+		{name: "alias.example.com.", qtype: dns.TypeA, answer: []string{
+			"alias.example.com.\t300\tIN\tCNAME\twww.example.com.",
+			"www.example.com.\t300\tIN\tA\t192.0.2.10",
+		}},
+		{name: "external.example.com.", qtype: dns.TypeA, answer: []string{"external.example.com.\t300\tIN\tCNAME\twww.example.net."}},
+		{name: "example.com.", qtype: dns.TypeMX, answer: []string{"example.com.\t300\tIN\tMX\t10 mail.example.com."}},
+		{name: "example.com.", qtype: dns.TypeTXT, answer: []string{"example.com.\t300\tIN\tTXT\t\"v=spf1 -all\""}},
+		{name: "_sip._tcp.example.com.", qtype: dns.TypeSRV, answer: []string{"_sip._tcp.example.com.\t300\tIN\tSRV\t10 5 5060 sip.example.com."}},
+		{name: "foo.apps.example.com.", qtype: dns.TypeA, answer: []string{"foo.apps.example.com.\t300\tIN\tA\t192.0.2.20"}},
+		{name: "www.example.com.", qtype: dns.TypeMX, authority: dns.TypeSOA},
+		{name: "_tcp.example.com.", qtype: dns.TypeA, authority: dns.TypeSOA},
+		{name: "missing.example.com.", qtype: dns.TypeA, rcode: dns.RcodeNameError, authority: dns.TypeSOA},
+		{name: "host.lab.example.com.", qtype: dns.TypeA, authority: dns.TypeNS},
+		{name: "www.example.org.", qtype: dns.TypeA, rcode: dns.RcodeRefused},
+	}
+
+	client := new(dns.Client)
+	for _, tt := range tests {
+		m := new(dns.Msg)
+		m.SetQuestion(tt.name, tt.qtype)
+		reply, _, err := client.Exchange(m, addr)
+		if err != nil {
+			t.Fatalf("Exchange(%s %s) returned error: %v", tt.name, dns.TypeToString[tt.qtype], err)
+		}
+
+		if reply.Rcode != tt.rcode {
+			t.Errorf("%s %s: expected %s, got %s", tt.name, dns.TypeToString[tt.qtype], dns.RcodeToString[tt.rcode], dns.RcodeToString[reply.Rcode])
+		}
+		if len(reply.Answer) != len(tt.answer) {
+			t.Errorf("%s %s: expected %d answers, got %v", tt.name, dns.TypeToString[tt.qtype], len(tt.answer), reply.Answer)
+			continue
+		}
+		for i, rr := range reply.Answer {
+			if rr.String() != tt.answer[i] {
+				t.Errorf("%s %s: expected %q, got %q", tt.name, dns.TypeToString[tt.qtype], tt.answer[i], rr.String())
+			}
+		}
+		if tt.authority != 0 && (len(reply.Ns) == 0 || reply.Ns[0].Header().Rrtype != tt.authority) {
+			t.Errorf("%s %s: expected %s in authority, got %v", tt.name, dns.TypeToString[tt.qtype], dns.TypeToString[tt.authority], reply.Ns)
+		}
+	}
+}
+
+func TestHandlerSOA(t *testing.T) {
+	handler, err := NewHandler(testSnapshot(t))
+	if err != nil {
+		t.Fatalf("NewHandler() returned error: %v", err)
+	}
+	addr := startServer(t, handler)
+
+	m := new(dns.Msg)
+	m.SetQuestion("example.com.", dns.TypeSOA)
+	reply, _, err := new(dns.Client).Exchange(m, addr)
+	if err != nil {
+		t.Fatalf("Exchange() returned error: %v", err)
+	}
+	if !reply.Authoritative || len(reply.Answer) != 1 {
+		t.Fatalf("expected one authoritative SOA, got %v", reply)
+	}
+
+	soa := reply.Answer[0].(*dns.SOA)
+	if soa.Ns != "dns1.stabletransit.com." || soa.Mbox != "admin.example.com." || soa.Hdr.Ttl != 3600 {
+		t.Errorf("unexpected SOA %v", soa)
+	}
+}
+
+func TestNewHandlerRejectsUnservableRecords(t *testing.T) {
+	s := &Snapshot{
+		Domain:  domains.DomainShow{Name: "example.com"},
+		Records: []records.RecordList{{Name: "www.example.com", Type: "A", Data: "not-an-address"}},
+	}
+	if _, err := NewHandler(s); err == nil {
+		t.Fatal("expected an error for an unparsable record")
+	}
+}
+
+// failingWriter is a dns.ResponseWriter whose replies cannot be written.
+type failingWriter struct {
+	dns.ResponseWriter
+}
+
+func (failingWriter) LocalAddr() net.Addr {
+	return &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 53}
+}
+
+func (failingWriter) WriteMsg(*dns.Msg) error {
+	return errors.New("connection closed")
+}
+
+func TestHandlerLogsWriteErrors(t *testing.T) {
+	handler, err := NewHandler(testSnapshot(t))
+	if err != nil {
+		t.Fatalf("NewHandler() returned error: %v", err)
+	}
+
+	var global bytes.Buffer
+	log.SetOutput(&global)
+	defer log.SetOutput(os.Stderr)
+
+	var buf bytes.Buffer
+	handler.Logger = slog.New(slog.NewTextHandler(&buf, nil))
+
+	m := new(dns.Msg)
+	m.SetQuestion("www.example.com.", dns.TypeA)
+	handler.ServeDNS(failingWriter{}, m)
+
+	if !strings.Contains(buf.String(), "connection closed") {
+		t.Errorf("expected the write error to be logged, got %q", buf.String())
+	}
+	if global.Len() != 0 {
+		t.Errorf("expected nothing on the global logger, got %q", global.String())
+	}
+}
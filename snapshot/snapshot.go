@@ -0,0 +1,71 @@
+// Package snapshot captures a Cloud DNS domain and its records at a point in
+// time, and serves them from a local authoritative DNS server so the zone
+// can be checked with dig before a cutover.
+package snapshot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/gophercloud/gophercloud/v2"
+
+	"github.com/rackerlabs/goclouddns/domains"
+	"github.com/rackerlabs/goclouddns/records"
+)
+
+// Snapshot is a domain and every record in it.
+type Snapshot struct {
+	Domain  domains.DomainShow   `json:"domain"`
+	Records []records.RecordList `json:"records"`
+	Taken   time.Time            `json:"taken"`
+}
+
+// Fetch takes a snapshot of the domain with the given ID.
+func Fetch(ctx context.Context, client *gophercloud.ServiceClient, domID string) (*Snapshot, error) {
+	domain, err := domains.Get(ctx, client, domID).Extract()
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := records.List(ctx, client, domID, nil).AllPages(ctx)
+	if err != nil {
+		return nil, err
+	}
+	recordList, err := records.ExtractRecords(page)
+	if err != nil {
+		return nil, err
+	}
+
+	// the full record list replaces the partial one embedded in the domain
+	domain.RecordsList.Records = nil
+	return &Snapshot{Domain: *domain, Records: recordList, Taken: time.Now().UTC()}, nil
+}
+
+// Load reads a snapshot written by Save.
+func Load(path string) (*Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var s Snapshot
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("reading snapshot %s: %w", path, err)
+	}
+	if s.Domain.Name == "" {
+		return nil, fmt.Errorf("reading snapshot %s: no domain name", path)
+	}
+	return &s, nil
+}
+
+// Save writes the snapshot to path as JSON.
+func (s *Snapshot) Save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
@@ -0,0 +1,181 @@
+package goclouddns
+
+import (
+	"context"
+	"maps"
+	"net/http"
+	"sync"
+
+	"github.com/gophercloud/gophercloud/v2"
+	"github.com/gophercloud/gophercloud/v2/pagination"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName identifies the spans this module creates.
+const tracerName = "github.com/rackerlabs/goclouddns"
+
+// Span attributes set on operation and job spans.
+const (
+	AttrDomainID  = attribute.Key("clouddns.domain.id")
+	AttrRecordID  = attribute.Key("clouddns.record.id")
+	AttrJobID     = attribute.Key("clouddns.job.id")
+	AttrJobStatus = attribute.Key("clouddns.job.status")
+	AttrJobPoll   = attribute.Key("clouddns.job.poll")
+)
+
+// SetTracerProvider traces the calls made through client with provider
+// instead of the global OpenTelemetry provider. Each operation in domains
+// and records is a span, with a child span for every HTTP request and
+// every WaitForStatus poll.
+//
+// Operations are traced without it too, with the global provider. HTTP
+// requests are traced once the client's ProviderClient goes through
+// InstrumentHTTP, which SetTracerProvider calls. Like the other Set
+// functions, it applies to every service client sharing the client's
+// ProviderClient, and should be called before the client is in use.
+func SetTracerProvider(client *gophercloud.ServiceClient, provider trace.TracerProvider) {
+	setOption(client, func(o *clientOptions) { o.tracerProvider = provider })
+	InstrumentHTTP(client.ProviderClient)
+}
+
+// transportMu guards the check and swap of InstrumentHTTP.
+var transportMu sync.Mutex
+
+// InstrumentHTTP wraps the transport of provider so each request it makes
+// is a client span, using the tracer provider set on it with
+// SetTracerProvider, or else the one of the span in the request context,
+// or else the global one. It is safe to call more than once, but replacing
+// the HTTPClient or its Transport afterwards undoes it.
+func InstrumentHTTP(provider *gophercloud.ProviderClient) {
+	transportMu.Lock()
+	defer transportMu.Unlock()
+
+	if _, ok := provider.HTTPClient.Transport.(*tracingTransport); ok {
+		return
+	}
+	next := provider.HTTPClient.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	provider.HTTPClient.Transport = &tracingTransport{next: next, provider: provider}
+}
+
+// StartSpan starts the span for an operation such as "records.Create".
+// The domains and records packages call it for each public operation.
+func StartSpan(ctx context.Context, client *gophercloud.ServiceClient, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer(ctx, client).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// EndSpan records err, if any, on span and ends it.
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// tracer returns the tracer for client. Without a client-specific
+// provider, the span already in ctx decides, falling back to the global
+// provider.
+func tracer(ctx context.Context, client *gophercloud.ServiceClient) trace.Tracer {
+	if client != nil {
+		if provider := optionsFor(client).tracerProvider; provider != nil {
+			return provider.Tracer(tracerName)
+		}
+	}
+	if span := trace.SpanFromContext(ctx); span.SpanContext().IsValid() {
+		return span.TracerProvider().Tracer(tracerName)
+	}
+	return otel.GetTracerProvider().Tracer(tracerName)
+}
+
+// pageSpanHeader carries the span name set by TracePages from a pager to
+// tracingTransport, which removes it before the request is sent.
+const pageSpanHeader = "X-Goclouddns-Span"
+
+// TracePages makes every page fetched by pager a span called name, parent
+// of the span for its HTTP request. A pager does its requests after the
+// call that built it has returned, so this takes the place of StartSpan for
+// the List functions of domains and records. pager is returned unchanged if
+// client has not been through InstrumentHTTP, whose transport takes the
+// span name off again.
+func TracePages(client *gophercloud.ServiceClient, pager pagination.Pager, name string) pagination.Pager {
+	if pager.Err != nil {
+		return pager
+	}
+	if _, ok := client.ProviderClient.HTTPClient.Transport.(*tracingTransport); !ok {
+		return pager
+	}
+
+	headers := maps.Clone(pager.Headers)
+	if headers == nil {
+		headers = map[string]string{}
+	}
+	headers[pageSpanHeader] = name
+	pager.Headers = headers
+	return pager
+}
+
+// tracingTransport makes a client span for each HTTP request made through
+// provider.
+type tracingTransport struct {
+	next     http.RoundTripper
+	provider *gophercloud.ProviderClient
+}
+
+// RoundTrip implements http.RoundTripper. Page fetches marked by
+// TracePages get a span of their own around the request's.
+func (t *tracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	provider := providerOptions(t.provider).tracerProvider
+
+	var tr trace.Tracer
+	if provider != nil {
+		tr = provider.Tracer(tracerName)
+	} else {
+		tr = tracer(req.Context(), nil)
+	}
+
+	name := req.Header.Get(pageSpanHeader)
+	if name == "" {
+		return t.roundTrip(tr, req)
+	}
+
+	req = req.Clone(req.Context())
+	req.Header.Del(pageSpanHeader)
+	ctx, span := tr.Start(req.Context(), name)
+	resp, err := t.roundTrip(tr, req.WithContext(ctx))
+	if err == nil && resp.StatusCode >= 400 {
+		span.SetStatus(codes.Error, resp.Status)
+	}
+	EndSpan(span, err)
+	return resp, err
+}
+
+func (t *tracingTransport) roundTrip(tr trace.Tracer, req *http.Request) (*http.Response, error) {
+	ctx, span := tr.Start(req.Context(), "HTTP "+req.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(req.Method),
+			semconv.URLFull(req.URL.Redacted()),
+		),
+	)
+	defer span.End()
+
+	resp, err := t.next.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return resp, err
+	}
+
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	if resp.StatusCode >= 400 {
+		span.SetStatus(codes.Error, resp.Status)
+	}
+	return resp, nil
+}
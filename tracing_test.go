@@ -0,0 +1,114 @@
+package goclouddns_test
+
+import (
+	"testing"
+
+	"github.com/gophercloud/gophercloud/v2"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/rackerlabs/goclouddns"
+	"github.com/rackerlabs/goclouddns/domains"
+	"github.com/rackerlabs/goclouddns/fakedns"
+	"github.com/rackerlabs/goclouddns/records"
+)
+
+func TestTracingRecordsCreate(t *testing.T) {
+	server := fakedns.NewServer()
+	defer server.Close()
+	server.PendingPolls = 1
+	domID := server.AddDomain(domains.CreateOpts{Name: "example.com", Email: "admin@example.com"})
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	client := server.ServiceClient()
+	goclouddns.SetTracerProvider(client, provider)
+
+	opts := records.CreateOpts{Name: "www.example.com", Type: "A", Data: "192.0.2.10"}
+	if _, err := records.Create(t.Context(), client, domID, opts).Extract(); err != nil {
+		t.Fatalf("Create() returned error: %v", err)
+	}
+
+	spans := recorder.Ended()
+	byName := map[string][]sdktrace.ReadOnlySpan{}
+	for _, span := range spans {
+		byName[span.Name()] = append(byName[span.Name()], span)
+	}
+
+	if len(byName["records.Create"]) != 1 {
+		t.Fatalf("expected one records.Create span, got %d spans: %v", len(spans), byName)
+	}
+	op := byName["records.Create"][0]
+
+	attrs := map[string]string{}
+	for _, kv := range op.Attributes() {
+		attrs[string(kv.Key)] = kv.Value.Emit()
+	}
+	if attrs["clouddns.domain.id"] != domID || attrs["clouddns.job.id"] == "" || attrs["clouddns.job.status"] != "COMPLETED" {
+		t.Errorf("unexpected records.Create attributes %v", attrs)
+	}
+
+	if n := len(byName["WaitForStatus poll"]); n != 2 {
+		t.Errorf("expected 2 poll spans, got %d", n)
+	}
+	for _, poll := range byName["WaitForStatus poll"] {
+		if poll.Parent().SpanID() != op.SpanContext().SpanID() {
+			t.Errorf("expected poll span to be a child of records.Create")
+		}
+	}
+
+	if n := len(byName["HTTP POST"]); n != 1 {
+		t.Errorf("expected 1 HTTP POST span, got %d", n)
+	}
+	if n := len(byName["HTTP GET"]); n != 2 {
+		t.Errorf("expected an HTTP GET span per poll, got %d", n)
+	}
+}
+
+func TestTracingRecordsFind(t *testing.T) {
+	server := fakedns.NewServer()
+	defer server.Close()
+	domID := server.AddDomain(domains.CreateOpts{Name: "example.com", Email: "admin@example.com"})
+	if _, err := server.AddRecord(domID, records.CreateOpts{Name: "www.example.com", Type: "A", Data: "192.0.2.10"}); err != nil {
+		t.Fatalf("AddRecord() returned error: %v", err)
+	}
+
+	exchanges := fakedns.NewRecorder(nil)
+	provider := &gophercloud.ProviderClient{
+		TokenID: fakedns.Token,
+		EndpointLocator: func(gophercloud.EndpointOpts) (string, error) {
+			return server.Endpoint(), nil
+		},
+	}
+	provider.HTTPClient.Transport = exchanges
+	client, err := goclouddns.NewCloudDNS(provider, gophercloud.EndpointOpts{})
+	if err != nil {
+		t.Fatalf("NewCloudDNS() returned error: %v", err)
+	}
+
+	recorder := tracetest.NewSpanRecorder()
+	goclouddns.SetTracerProvider(client, sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	if _, err := records.Find(t.Context(), client, domID, "www.example.com", ""); err != nil {
+		t.Fatalf("Find() returned error: %v", err)
+	}
+
+	byName := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		byName[span.Name()] = span
+	}
+	find, list, get := byName["records.Find"], byName["records.List"], byName["HTTP GET"]
+	if find == nil || list == nil || get == nil {
+		t.Fatalf("expected records.Find, records.List and HTTP GET spans, got %v", byName)
+	}
+	if list.Parent().SpanID() != find.SpanContext().SpanID() || get.Parent().SpanID() != list.SpanContext().SpanID() {
+		t.Error("expected HTTP GET inside the records.List page span inside records.Find")
+	}
+
+	for _, exchange := range exchanges.Fixture().Exchanges {
+		if exchange.RequestHeaders.Get("X-Goclouddns-Span") != "" {
+			t.Errorf("span name header sent to the API on %s %s", exchange.Method, exchange.URL)
+		}
+	}
+}